@@ -0,0 +1,559 @@
+package fulltextsearch
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/kljensen/snowball/english"
+	"github.com/kljensen/snowball/french"
+	"github.com/kljensen/snowball/hungarian"
+	"github.com/kljensen/snowball/norwegian"
+	"github.com/kljensen/snowball/russian"
+	"github.com/kljensen/snowball/spanish"
+	"github.com/kljensen/snowball/swedish"
+	"golang.org/x/text/unicode/norm"
+)
+
+// isSeparator reports whether r splits tokens: any character that is not a
+// letter or a number.
+func isSeparator(r rune) bool {
+	return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+}
+
+func tokenize(text string) []string {
+	return strings.FieldsFunc(text, isSeparator)
+}
+
+func lowercaseFilter(tokens []string) []string {
+	r := make([]string, len(tokens))
+	for i, token := range tokens {
+		r[i] = strings.ToLower(token)
+	}
+	return r
+}
+
+var stopwords = map[string]struct{}{ // I wish Go had built-in sets.
+	"a": {}, "and": {}, "be": {}, "have": {}, "i": {},
+	"in": {}, "of": {}, "that": {}, "the": {}, "to": {},
+}
+
+func stopwordFilter(tokens []string, stopwords map[string]struct{}) []string {
+	r := make([]string, 0, len(tokens))
+	for _, token := range tokens {
+		if _, ok := stopwords[token]; !ok {
+			r = append(r, token)
+		}
+	}
+	return r
+}
+
+// foldedLetters are letters that don't decompose into a base letter and a
+// combining mark but are still commonly typed without their diacritic.
+var foldedLetters = strings.NewReplacer(
+	"ß", "ss", "æ", "ae", "œ", "oe", "ø", "o", "ł", "l", "đ", "d", "ð", "d", "þ", "th",
+)
+
+// foldAccent strips the diacritics from token, so "café" becomes "cafe".
+// Compatibility decomposition splits accented letters into base letters and
+// combining marks, and also flattens ligatures such as "ﬁ"; the marks are
+// then dropped.
+func foldAccent(token string) string {
+	var b strings.Builder
+	for _, r := range norm.NFKD.String(token) {
+		if !unicode.Is(unicode.Mn, r) {
+			b.WriteRune(r)
+		}
+	}
+	return foldedLetters.Replace(norm.NFC.String(b.String()))
+}
+
+func accentFoldFilter(tokens []string) []string {
+	r := make([]string, len(tokens))
+	for i, token := range tokens {
+		r[i] = foldAccent(token)
+	}
+	return r
+}
+
+// snowballStemmers are the Snowball stemmers by ISO 639-1 language code.
+var snowballStemmers = map[string]func(word string, stemStopWords bool) string{
+	"de": germanStem,
+	"en": english.Stem,
+	"es": spanish.Stem,
+	"fr": french.Stem,
+	"hu": hungarian.Stem,
+	"no": norwegian.Stem,
+	"ru": russian.Stem,
+	"sv": swedish.Stem,
+}
+
+func stemmerFilter(tokens []string, stem func(string, bool) string) []string {
+	r := make([]string, len(tokens))
+	for i, token := range tokens {
+		r[i] = stem(token, false)
+	}
+	return r
+}
+
+// lightStem is the "S-stemmer": it only undoes regular English plurals, so
+// unlike Snowball it never merges words such as "organization" and "organ".
+func lightStem(token string) string {
+	switch {
+	case strings.HasSuffix(token, "ies") && !strings.HasSuffix(token, "eies") && !strings.HasSuffix(token, "aies"):
+		return token[:len(token)-3] + "y"
+	case strings.HasSuffix(token, "es") && !strings.HasSuffix(token, "aes") && !strings.HasSuffix(token, "ees") && !strings.HasSuffix(token, "oes"):
+		return token[:len(token)-1]
+	case strings.HasSuffix(token, "s") && !strings.HasSuffix(token, "us") && !strings.HasSuffix(token, "ss"):
+		return token[:len(token)-1]
+	}
+	return token
+}
+
+func lightStemmerFilter(tokens []string) []string {
+	r := make([]string, len(tokens))
+	for i, token := range tokens {
+		r[i] = lightStem(token)
+	}
+	return r
+}
+
+// IrregularPlurals is a ready-made lemma table for common English plurals
+// that stemming can't normalize.
+var IrregularPlurals = map[string]string{
+	"children": "child", "feet": "foot", "geese": "goose", "lice": "louse",
+	"men": "man", "mice": "mouse", "oxen": "ox", "people": "person",
+	"teeth": "tooth", "women": "woman",
+}
+
+// lemmaFilter replaces tokens found in lemmas with their base form.
+func lemmaFilter(tokens []string, lemmas map[string]string) []string {
+	r := make([]string, len(tokens))
+	for i, token := range tokens {
+		if base, ok := lemmas[token]; ok {
+			token = base
+		}
+		r[i] = token
+	}
+	return r
+}
+
+// EnglishContractions is a ready-made table for the Contractions option.
+var EnglishContractions = map[string]string{
+	"aren't": "are not", "can't": "can not", "couldn't": "could not",
+	"didn't": "did not", "doesn't": "does not", "don't": "do not",
+	"hadn't": "had not", "hasn't": "has not", "haven't": "have not",
+	"he's": "he is", "i'm": "i am", "isn't": "is not", "it's": "it is",
+	"let's": "let us", "she's": "she is", "shouldn't": "should not",
+	"that's": "that is", "there's": "there is", "they're": "they are",
+	"wasn't": "was not", "we're": "we are", "weren't": "were not",
+	"won't": "will not", "wouldn't": "would not", "you're": "you are",
+	"i've": "i have", "we've": "we have", "you've": "you have",
+	"they've": "they have", "i'll": "i will", "you'll": "you will",
+	"we'll": "we will", "they'll": "they will",
+}
+
+var contractionRe = regexp.MustCompile(`\pL+['’]\pL+`)
+
+// expandContractions replaces the contractions in text found in table,
+// which is keyed by lowercase forms using a plain apostrophe.
+func expandContractions(text string, table map[string]string) string {
+	return contractionRe.ReplaceAllStringFunc(text, func(word string) string {
+		key := strings.ToLower(strings.ReplaceAll(word, "’", "'"))
+		if expanded, ok := table[key]; ok {
+			return expanded
+		}
+		return word
+	})
+}
+
+// StemStrength selects how aggressively an analyzer stems, trading recall
+// for precision.
+type StemStrength string
+
+const (
+	StemFull  StemStrength = "full"  // Snowball; the default
+	StemLight StemStrength = "light" // plurals only
+	StemNone  StemStrength = "none"
+)
+
+// vocabularyFilter drops any token not in vocab. It runs after stemming so
+// the allowlist is written in the same form the index stores.
+func vocabularyFilter(tokens []string, vocab map[string]struct{}) []string {
+	r := make([]string, 0, len(tokens))
+	for _, token := range tokens {
+		if _, ok := vocab[token]; ok {
+			r = append(r, token)
+		}
+	}
+	return r
+}
+
+// defaultMaxTokenLength is the longest token, in characters, kept when an
+// analyzer doesn't set its own limit.
+const defaultMaxTokenLength = 100
+
+// longTokenFilter drops, or if truncate is set cuts down, tokens longer than
+// max characters. Such tokens are almost always junk like base64 blobs and
+// would only bloat the index.
+func longTokenFilter(tokens []string, max int, truncate bool) []string {
+	r := make([]string, 0, len(tokens))
+	for _, token := range tokens {
+		if utf8.RuneCountInString(token) <= max {
+			r = append(r, token)
+		} else if truncate {
+			r = append(r, string([]rune(token)[:max]))
+		}
+	}
+	return r
+}
+
+// Analyzer holds the options that control how text becomes index terms.
+// The zero value runs the default pipeline.
+type Analyzer struct {
+	// Vocabulary, if non-nil, restricts terms to this set of stemmed forms.
+	Vocabulary map[string]struct{}
+	// Phonetic, if non-nil, indexes the Soundex codes of terms.
+	Phonetic *PhoneticFilter
+	// EdgeNGrams, if non-nil, indexes prefixes of terms for
+	// search-as-you-type.
+	EdgeNGrams *EdgeNGramFilter
+	// MaxTokenLength caps token length in characters, defaulting to
+	// defaultMaxTokenLength. Longer tokens are dropped unless
+	// TruncateLongTokens is set.
+	MaxTokenLength     int
+	TruncateLongTokens bool
+	// StemStrength defaults to StemFull.
+	StemStrength StemStrength
+	// Language is the ISO 639-1 code of the text, selecting the Snowball
+	// stemmer and, unless Stopwords is set, the stopword list. Stemmers
+	// exist for de, en, es, fr, hu, no, ru and sv; other languages aren't
+	// stemmed. Empty means English with the short built-in stopword list.
+	Language string
+	// Contractions, if non-nil, expands contractions such as "don't" before
+	// tokenizing, so they match their expanded forms.
+	Contractions map[string]string
+	// Lemmas maps lowercased word forms to a base form before stemming,
+	// for irregular forms such as "mice" that no stemmer normalizes.
+	Lemmas map[string]string
+	// Synonyms, if non-nil, expands each word to its set of synonyms before
+	// stopwords are dropped and terms stemmed, e.g. from LoadSynonyms.
+	Synonyms Synonyms
+	// FoldAccents strips diacritics from terms after stemming, so "café"
+	// matches "cafe".
+	FoldAccents bool
+	// Stopwords are the lowercased words dropped from text, such as one of
+	// StopwordLists or a list from LoadStopwords. Nil means a short
+	// built-in English list; an empty set keeps every word.
+	Stopwords map[string]struct{}
+
+	// Tokenizer splits text into tokens. Nil means WordTokenizer.
+	Tokenizer Tokenizer
+	// Filters, if non-nil, replaces the filter chain the options above
+	// build. Start from DefaultFilters to add, remove or reorder stages.
+	Filters []TokenFilter
+}
+
+// Tokenizer splits text into tokens.
+type Tokenizer interface {
+	Tokenize(text string) []string
+}
+
+// TokenizerFunc adapts a function to a Tokenizer.
+type TokenizerFunc func(text string) []string
+
+func (f TokenizerFunc) Tokenize(text string) []string { return f(text) }
+
+// TokenFilter is one stage of the analysis chain, transforming, adding or
+// dropping tokens.
+type TokenFilter interface {
+	Filter(tokens []string) []string
+}
+
+// TokenFilterFunc adapts a function to a TokenFilter.
+type TokenFilterFunc func(tokens []string) []string
+
+func (f TokenFilterFunc) Filter(tokens []string) []string { return f(tokens) }
+
+// The built-in analysis stages, for assembling custom chains.
+var (
+	// WordTokenizer splits text at anything that isn't a letter or number.
+	WordTokenizer Tokenizer = TokenizerFunc(tokenize)
+	// CJKTokenizer splits text like WordTokenizer but breaks Chinese,
+	// Japanese and Korean text into overlapping character bigrams, since
+	// those languages don't put spaces between words.
+	CJKTokenizer Tokenizer = TokenizerFunc(cjkTokenize)
+	// KeywordTokenizer emits the whole text as a single token, or none if
+	// it is empty.
+	KeywordTokenizer Tokenizer = TokenizerFunc(keywordTokenize)
+
+	LowercaseFilter    TokenFilter = TokenFilterFunc(lowercaseFilter)
+	StemmerFilter      TokenFilter = SnowballFilter("en")
+	LightStemmerFilter TokenFilter = TokenFilterFunc(lightStemmerFilter)
+	AccentFoldFilter   TokenFilter = TokenFilterFunc(accentFoldFilter)
+)
+
+// SnowballFilter stems tokens with the Snowball stemmer for language, an
+// ISO 639-1 code. Tokens pass through unchanged if there is no stemmer for
+// the language.
+func SnowballFilter(language string) TokenFilter {
+	stem, ok := snowballStemmers[language]
+	if !ok {
+		return TokenFilterFunc(func(tokens []string) []string { return tokens })
+	}
+	return TokenFilterFunc(func(tokens []string) []string {
+		return stemmerFilter(tokens, stem)
+	})
+}
+
+// NGramTokenizer splits text into words like WordTokenizer and emits every
+// run of min to max characters within each word, so a query for part of a
+// word, such as "synth", matches words containing it, like
+// "photosynthesis". Words shorter than min are emitted whole. Stemming
+// n-grams makes no sense, so pair it with Filters such as LowercaseFilter
+// alone.
+func NGramTokenizer(min, max int) Tokenizer {
+	return TokenizerFunc(func(text string) []string {
+		var r []string
+		for _, word := range tokenize(text) {
+			runes := []rune(word)
+			if len(runes) < min {
+				r = append(r, word)
+				continue
+			}
+			for i := range runes {
+				for n := min; n <= max && i+n <= len(runes); n++ {
+					r = append(r, string(runes[i:i+n]))
+				}
+			}
+		}
+		return r
+	})
+}
+
+// isCJK reports whether r is written without spaces between words: Han,
+// Hiragana, Katakana or Hangul.
+func isCJK(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul)
+}
+
+// cjkTokenize splits text like tokenize, except that runs of CJK
+// characters become overlapping bigrams, as in "東京都" to "東京" and
+// "京都"; a lone CJK character is kept as it is. Bigrams need no
+// dictionary and a query's bigrams are found wherever its text occurs,
+// though a one-character query only matches characters that stood alone.
+func cjkTokenize(text string) []string {
+	var r []string
+	for _, word := range tokenize(text) {
+		runes := []rune(word)
+		for i := 0; i < len(runes); {
+			j := i + 1
+			for j < len(runes) && isCJK(runes[j]) == isCJK(runes[i]) {
+				j++
+			}
+			switch {
+			case !isCJK(runes[i]):
+				r = append(r, string(runes[i:j]))
+			case j-i == 1:
+				r = append(r, string(runes[i]))
+			default:
+				for k := i; k+1 < j; k++ {
+					r = append(r, string(runes[k:k+2]))
+				}
+			}
+			i = j
+		}
+	}
+	return r
+}
+
+// EdgeNGramFilter adds the prefixes of each token, from Min to Max
+// characters long, so an incomplete word typed so far matches without a
+// wildcard scan: "donut" indexes "don", "donu" and "donut" for a Min of 3.
+// Tokens are kept whole too. It belongs on the indexing side only, which is
+// where Analyzer.EdgeNGrams puts it, and works on analyzed terms, so
+// prefixes are of the stemmed form.
+type EdgeNGramFilter struct {
+	Min, Max int
+}
+
+func (e EdgeNGramFilter) Filter(tokens []string) []string {
+	r := make([]string, 0, len(tokens))
+	for _, token := range tokens {
+		runes := []rune(token)
+		for n := max(e.Min, 1); n < len(runes) && n <= e.Max; n++ {
+			r = append(r, string(runes[:n]))
+		}
+		r = append(r, token)
+	}
+	return r
+}
+
+// LongTokenFilter drops, or if truncate is set cuts down, tokens longer
+// than max characters.
+func LongTokenFilter(max int, truncate bool) TokenFilter {
+	return TokenFilterFunc(func(tokens []string) []string {
+		return longTokenFilter(tokens, max, truncate)
+	})
+}
+
+// StopwordFilter drops the tokens in words, or in the built-in English list
+// if words is nil.
+func StopwordFilter(words map[string]struct{}) TokenFilter {
+	if words == nil {
+		words = stopwords
+	}
+	return TokenFilterFunc(func(tokens []string) []string {
+		return stopwordFilter(tokens, words)
+	})
+}
+
+// LemmaFilter replaces tokens found in lemmas with their base form.
+func LemmaFilter(lemmas map[string]string) TokenFilter {
+	return TokenFilterFunc(func(tokens []string) []string {
+		return lemmaFilter(tokens, lemmas)
+	})
+}
+
+// VocabularyFilter keeps only the tokens in vocab.
+func VocabularyFilter(vocab map[string]struct{}) TokenFilter {
+	return TokenFilterFunc(func(tokens []string) []string {
+		return vocabularyFilter(tokens, vocab)
+	})
+}
+
+// DefaultFilters returns the filter chain a's options build, which is what
+// runs unless Filters is set.
+func (a Analyzer) DefaultFilters() []TokenFilter {
+	max := a.MaxTokenLength
+	if max <= 0 {
+		max = defaultMaxTokenLength
+	}
+	r := []TokenFilter{LongTokenFilter(max, a.TruncateLongTokens), LowercaseFilter}
+	if a.Lemmas != nil {
+		r = append(r, LemmaFilter(a.Lemmas))
+	}
+	if a.Synonyms != nil {
+		r = append(r, SynonymFilter(a.Synonyms))
+	}
+	stop := a.Stopwords
+	if stop == nil && a.Language != "" {
+		stop = StopwordLists[a.Language]
+		if stop == nil {
+			stop = map[string]struct{}{}
+		}
+	}
+	r = append(r, StopwordFilter(stop))
+	lang := a.Language
+	if lang == "" {
+		lang = "en"
+	}
+	switch a.StemStrength {
+	case StemLight:
+		r = append(r, LightStemmerFilter)
+	case StemNone:
+	default:
+		r = append(r, SnowballFilter(lang))
+	}
+	if a.FoldAccents {
+		r = append(r, AccentFoldFilter)
+	}
+	if a.Vocabulary != nil {
+		r = append(r, VocabularyFilter(a.Vocabulary))
+	}
+	return r
+}
+
+func (a Analyzer) terms(text string) []string {
+	if a.Contractions != nil {
+		text = expandContractions(text, a.Contractions)
+	}
+	tokenizer := a.Tokenizer
+	if tokenizer == nil {
+		tokenizer = WordTokenizer
+	}
+	filters := a.Filters
+	if filters == nil {
+		filters = a.DefaultFilters()
+	}
+	tokens := tokenizer.Tokenize(text)
+	for _, f := range filters {
+		tokens = f.Filter(tokens)
+	}
+	return tokens
+}
+
+// Analyze returns the terms to index for text.
+func (a Analyzer) Analyze(text string) []string {
+	terms, _ := a.positioned(text)
+	return terms
+}
+
+// positioned returns the terms to index for text and the position of each
+// in the text, or nil positions if every term is at its own index. The
+// extra terms PhoneticFilter and EdgeNGrams emit share the position of the
+// token they came from, so a query reduced to one term per word still
+// finds its phrases.
+func (a Analyzer) positioned(text string) ([]string, []int) {
+	tokens := a.terms(text)
+	if a.Phonetic == nil && a.EdgeNGrams == nil {
+		return tokens, nil
+	}
+	terms := make([]string, 0, len(tokens))
+	var pos []int
+	for i, token := range tokens {
+		stack := []string{token}
+		if a.Phonetic != nil {
+			stack = a.Phonetic.filter(stack)
+		}
+		if a.EdgeNGrams != nil {
+			stack = a.EdgeNGrams.Filter(stack)
+		}
+		terms = append(terms, stack...)
+		for range stack {
+			pos = append(pos, i)
+		}
+	}
+	return terms, pos
+}
+
+// AnalyzeQuery returns the terms to look up for a query. It differs from
+// analyze only where indexing emits extra terms a query must not require.
+func (a Analyzer) AnalyzeQuery(text string) []string {
+	tokens := a.terms(text)
+	if a.Phonetic != nil {
+		tokens = PhoneticFilter{}.filter(tokens)
+	}
+	return tokens
+}
+
+// KeywordAnalyzer indexes text verbatim as one case-sensitive term, for
+// values such as URLs that are matched whole.
+var KeywordAnalyzer = Analyzer{Tokenizer: KeywordTokenizer, Filters: []TokenFilter{}}
+
+// keywordAnalyzer is the name of KeywordAnalyzer in Analyzers. Fields using
+// it are searched with field:value.
+const keywordAnalyzer = "keyword"
+
+// Analyzers are the analyzers fields can name in Config.FieldAnalyzers.
+// Since only the names are saved with an index, add any others before
+// creating or loading an index that uses them.
+var Analyzers = map[string]Analyzer{
+	"standard":      {},
+	"nostem":        {StemStrength: StemNone},
+	"light":         {StemStrength: StemLight},
+	keywordAnalyzer: KeywordAnalyzer,
+}
+
+func keywordTokenize(text string) []string {
+	if text == "" {
+		return nil
+	}
+	return []string{text}
+}
+
+// Analyze returns the terms the default analyzer indexes for text.
+func Analyze(text string) []string {
+	return Analyzer{}.Analyze(text)
+}
@@ -0,0 +1,323 @@
+package fulltextsearch
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestVocabulary(t *testing.T) {
+	vocab := map[string]struct{}{"cat": {}, "run": {}}
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{"cats", []string{"cat"}},
+		{"the cats were running", []string{"cat", "run"}},
+		{"secret password", nil},
+	}
+	a := Analyzer{Vocabulary: vocab}
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			if got := a.Analyze(tt.text); !slices.Equal(got, tt.want) {
+				t.Errorf("Analyze(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+
+	idx := NewIndex(Config{Analyzer: a})
+	idx.Add([]Document{{ID: 0, Text: "the cats ran past the secret password"}})
+	got := slices.Sorted(idx.Fields["text"].Postings.keys())
+	if want := []string{"cat"}; !slices.Equal(got, want) {
+		t.Errorf("indexed terms = %q, want %q", got, want)
+	}
+}
+
+func TestLongTokens(t *testing.T) {
+	blob := strings.Repeat("q", 10000)
+	text := "plate " + blob + " donut"
+	tests := []struct {
+		name string
+		a    Analyzer
+		want []string
+	}{
+		{"dropped", Analyzer{}, []string{"plate", "donut"}},
+		{"truncated", Analyzer{TruncateLongTokens: true}, []string{"plate", blob[:defaultMaxTokenLength], "donut"}},
+		{"own limit", Analyzer{MaxTokenLength: 5, TruncateLongTokens: true}, []string{"plate", "qqqqq", "donut"}},
+		{"own limit dropping", Analyzer{MaxTokenLength: 4}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.a.Analyze(text); !slices.Equal(got, tt.want) {
+				t.Errorf("Analyze() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStemStrength(t *testing.T) {
+	tests := []struct {
+		strength StemStrength
+		word     string
+		want     string
+	}{
+		{StemFull, "organization", "organ"},
+		{StemFull, "organ", "organ"},
+		{StemLight, "organization", "organization"},
+		{StemLight, "organ", "organ"},
+		{StemLight, "organs", "organ"},
+		{StemLight, "cities", "city"},
+		{StemNone, "organs", "organs"},
+	}
+	for _, tt := range tests {
+		a := Analyzer{StemStrength: tt.strength}
+		if got := a.Analyze(tt.word); !slices.Equal(got, []string{tt.want}) {
+			t.Errorf("Analyzer{StemStrength: %q}.Analyze(%q) = %q, want [%q]", tt.strength, tt.word, got, tt.want)
+		}
+	}
+}
+
+func TestLemmas(t *testing.T) {
+	docs := []Document{
+		{ID: 0, Text: "a mouse in the house"},
+		{ID: 1, Text: "a flock of geese"},
+	}
+	lemmas := map[string]string{"mice": "mouse", "geese": "goose"}
+	tests := []struct {
+		name   string
+		lemmas map[string]string
+		query  string
+		want   []int
+	}{
+		{"without lemmas", nil, "mice", nil},
+		{"query form", lemmas, "mice", []int{0}},
+		{"indexed form", lemmas, "goose", []int{1}},
+		{"capitalized", lemmas, "Geese", []int{1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			idx := NewIndex(Config{Analyzer: Analyzer{Lemmas: tt.lemmas}})
+			idx.Add(docs)
+			if got := idx.Search(tt.query); !slices.Equal(got, tt.want) {
+				t.Errorf("Search(%s) = %v, want %v", tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestContractions(t *testing.T) {
+	docs := []Document{
+		{ID: 0, Text: "Don't panic"},
+		{ID: 1, Text: "do not disturb"},
+		{ID: 2, Text: "it’s raining"},
+	}
+	tests := []struct {
+		name         string
+		contractions map[string]string
+		query        string
+		want         []int
+	}{
+		{"expanded query", EnglishContractions, `"do not"`, []int{0, 1}},
+		{"contracted query", EnglishContractions, "don't", []int{0, 1}},
+		{"without the option", nil, `"do not"`, []int{1}},
+		{"curly apostrophe", EnglishContractions, `"it is raining"`, []int{2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// keep every word, since the expansions are mostly stopwords
+			idx := NewIndex(Config{Analyzer: Analyzer{Contractions: tt.contractions, Stopwords: map[string]struct{}{}}})
+			idx.Add(docs)
+			if got := idx.Search(tt.query); !slices.Equal(got, tt.want) {
+				t.Errorf("Search(%s) = %v, want %v", tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPipeline(t *testing.T) {
+	commaTokenizer := TokenizerFunc(func(text string) []string { return strings.Split(text, ",") })
+	reverse := TokenFilterFunc(func(tokens []string) []string {
+		r := slices.Clone(tokens)
+		slices.Reverse(r)
+		return r
+	})
+	tests := []struct {
+		name string
+		a    Analyzer
+		text string
+		want []string
+	}{
+		{"default", Analyzer{}, "The Running Cats", []string{"run", "cat"}},
+		{"default filters", Analyzer{Filters: Analyzer{}.DefaultFilters()}, "The Running Cats", []string{"run", "cat"}},
+		{"no filters", Analyzer{Filters: []TokenFilter{}}, "The Running Cats", []string{"The", "Running", "Cats"}},
+		{"lowercase only", Analyzer{Filters: []TokenFilter{LowercaseFilter}}, "The Running Cats", []string{"the", "running", "cats"}},
+		{"filters in order", Analyzer{Filters: []TokenFilter{LowercaseFilter, reverse, StemmerFilter}}, "The Running Cats", []string{"cat", "run", "the"}},
+		{"own tokenizer", Analyzer{Tokenizer: commaTokenizer}, "New York,Cats", []string{"new york", "cat"}},
+		{"keyword", KeywordAnalyzer, "https://example.com/A Page", []string{"https://example.com/A Page"}},
+		{"keyword empty", KeywordAnalyzer, "", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.a.Analyze(tt.text); !slices.Equal(got, tt.want) {
+				t.Errorf("Analyze(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+
+	// Documents and queries go through the same pipeline.
+	idx := NewIndex(Config{Analyzer: Analyzer{Filters: []TokenFilter{LowercaseFilter}}})
+	idx.Add([]Document{{ID: 0, Text: "The Running Cats"}, {ID: 1, Text: "a cat runs"}})
+	for query, want := range map[string][]int{"RUNNING": {0}, "cat": {1}, "the": {0}} {
+		if got := idx.Search(query); !slices.Equal(got, want) {
+			t.Errorf("Search(%s) = %v, want %v", query, got, want)
+		}
+	}
+}
+
+func TestFoldAccents(t *testing.T) {
+	tests := []struct {
+		word, want string
+	}{
+		{"café", "cafe"},
+		{"cafe\u0301", "cafe"}, // already decomposed
+		{"naïve", "naiv"},
+		{"Ångström", "angstrom"},
+		{"straße", "strasse"},
+		{"œuvre", "oeuvr"},
+		{"ﬁne", "fine"},
+		{"łódź", "lodz"},
+		{"東京", "東京"},
+	}
+	a := Analyzer{FoldAccents: true}
+	for _, tt := range tests {
+		t.Run(tt.word, func(t *testing.T) {
+			if got := a.Analyze(tt.word); !slices.Equal(got, []string{tt.want}) {
+				t.Errorf("Analyze(%q) = %q, want [%q]", tt.word, got, tt.want)
+			}
+		})
+	}
+
+	for _, fold := range []bool{false, true} {
+		idx := NewIndex(Config{Analyzer: Analyzer{FoldAccents: fold}})
+		idx.Add([]Document{{ID: 0, Text: "a café in Zürich"}, {ID: 1, Text: "a cafe in Zurich"}})
+		want := []int{1}
+		if fold {
+			want = []int{0, 1}
+		}
+		for _, query := range []string{"cafe", "zurich"} {
+			if got := idx.Search(query); !slices.Equal(got, want) {
+				t.Errorf("with FoldAccents %v, Search(%s) = %v, want %v", fold, query, got, want)
+			}
+		}
+	}
+}
+
+func TestNGramTokenizer(t *testing.T) {
+	tests := []struct {
+		name     string
+		min, max int
+		text     string
+		want     []string
+	}{
+		{"bigrams", 2, 2, "abcd", []string{"ab", "bc", "cd"}},
+		{"two to three", 2, 3, "abcd", []string{"ab", "abc", "bc", "bcd", "cd"}},
+		{"short word whole", 3, 4, "ab abcd", []string{"ab", "abc", "abcd", "bcd"}},
+		{"runes", 2, 2, "çaé", []string{"ça", "aé"}},
+		{"separate words", 2, 2, "ab, cd", []string{"ab", "cd"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NGramTokenizer(tt.min, tt.max).Tokenize(tt.text); !slices.Equal(got, tt.want) {
+				t.Errorf("NGramTokenizer(%d, %d).Tokenize(%q) = %q, want %q", tt.min, tt.max, tt.text, got, tt.want)
+			}
+		})
+	}
+
+	idx := NewIndex(Config{Analyzer: Analyzer{Tokenizer: NGramTokenizer(3, 5), Filters: []TokenFilter{LowercaseFilter}}})
+	idx.Add([]Document{{ID: 0, Text: "Photosynthesis"}, {ID: 1, Text: "synthesizer"}, {ID: 2, Text: "photograph"}})
+	for query, want := range map[string][]int{"synth": {0, 1}, "photo": {0, 2}, "graph": {2}, "xyz": nil} {
+		if got := idx.Search(query); !slices.Equal(got, want) {
+			t.Errorf("Search(%s) = %v, want %v", query, got, want)
+		}
+	}
+}
+
+func TestEdgeNGrams(t *testing.T) {
+	tests := []struct {
+		name     string
+		min, max int
+		tokens   []string
+		want     []string
+	}{
+		{"prefixes", 3, 10, []string{"donut"}, []string{"don", "donu", "donut"}},
+		{"capped", 1, 2, []string{"donut"}, []string{"d", "do", "donut"}},
+		{"short token", 3, 10, []string{"do"}, []string{"do"}},
+		{"zero min", 0, 2, []string{"abc"}, []string{"a", "ab", "abc"}},
+		{"runes", 2, 3, []string{"éclair"}, []string{"éc", "écl", "éclair"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := (EdgeNGramFilter{tt.min, tt.max}).Filter(tt.tokens); !slices.Equal(got, tt.want) {
+				t.Errorf("EdgeNGramFilter{%d, %d}.Filter(%q) = %q, want %q", tt.min, tt.max, tt.tokens, got, tt.want)
+			}
+		})
+	}
+
+	// Prefixes are indexed but not required of queries, and share their
+	// word's position, so phrases still match.
+	idx := NewIndex(Config{Analyzer: Analyzer{EdgeNGrams: &EdgeNGramFilter{Min: 2, Max: 10}}})
+	idx.Add([]Document{{ID: 0, Text: "glazed donut"}, {ID: 1, Text: "donkey ride"}, {ID: 2, Text: "a doughnut"}})
+	for query, want := range map[string][]int{
+		"do":             {0, 1, 2},
+		"don":            {0, 1},
+		"donu":           {0},
+		"donut":          {0},
+		"gla don":        {0},
+		`"glazed donut"`: {0},
+		`"glaz donu"`:    {0},
+		"dx":             nil,
+	} {
+		if got := idx.Search(query); !slices.Equal(got, want) {
+			t.Errorf("Search(%s) = %v, want %v", query, got, want)
+		}
+	}
+}
+
+func TestCJKTokenizer(t *testing.T) {
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{"東京都", []string{"東京", "京都"}},
+		{"東", []string{"東"}},
+		{"東京 tower", []string{"東京", "tower"}},
+		{"iPhoneを買った", []string{"iPhone", "を買", "買っ", "った"}},
+		{"서울특별시", []string{"서울", "울특", "특별", "별시"}},
+		{"plain words, 42", []string{"plain", "words", "42"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			if got := CJKTokenizer.Tokenize(tt.text); !slices.Equal(got, tt.want) {
+				t.Errorf("CJKTokenizer.Tokenize(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+
+	idx := NewIndex(Config{Analyzer: Analyzer{Tokenizer: CJKTokenizer}})
+	idx.Add([]Document{
+		{ID: 0, Text: "東京都に住んでいます"},
+		{ID: 1, Text: "京都の寺"},
+		{ID: 2, Text: "Tokyo tower"},
+	})
+	for query, want := range map[string][]int{
+		"京都":    {0, 1},
+		"東京都":   {0},
+		"東京":    {0},
+		"寺":     nil, // only in the bigram の寺
+		"tower": {2},
+	} {
+		if got := idx.Search(query); !slices.Equal(got, want) {
+			t.Errorf("Search(%s) = %v, want %v", query, got, want)
+		}
+	}
+}
@@ -0,0 +1,210 @@
+package fulltextsearch
+
+import (
+	"math/bits"
+	"sync"
+)
+
+// bitmap is a bitset of document IDs. For a term in a large share of the
+// documents it is smaller than the posting list, at one bit per document
+// in the index rather than a whole int per match, and ANDing, ORing or
+// ANDNOTing two takes one machine word per 64 documents.
+//
+// It is left uncompressed: only terms in at least one document in 64 get
+// one, and a plain bitmap of those is never larger than the posting list
+// it stands for, so a compressed form would save little for the cost of
+// slower words to combine.
+type bitmap []uint64
+
+func newBitmap(size int) bitmap {
+	return make(bitmap, (size+63)/64)
+}
+
+// set adds id to b, which must have room for it: id must be at least zero
+// and less than the size b was made with, rounded up to a multiple of 64.
+func (b bitmap) set(id int) {
+	b[id>>6] |= 1 << (id & 63)
+}
+
+// contains reports whether id is in b. IDs out of its range, negative ones
+// included, never are.
+func (b bitmap) contains(id int) bool {
+	i := uint(id) >> 6
+	return i < uint(len(b)) && b[i]&(1<<(id&63)) != 0
+}
+
+// and returns the documents in both b and o.
+func (b bitmap) and(o bitmap) bitmap {
+	r := make(bitmap, min(len(b), len(o)))
+	for i := range r {
+		r[i] = b[i] & o[i]
+	}
+	return r
+}
+
+// or adds the documents in o to b, which must be at least as large.
+func (b bitmap) or(o bitmap) {
+	for i, w := range o {
+		b[i] |= w
+	}
+}
+
+// ids returns the documents in b in order.
+func (b bitmap) ids() []int {
+	var r []int
+	for i, w := range b {
+		for w != 0 {
+			r = append(r, i<<6+bits.TrailingZeros64(w))
+			w &= w - 1
+		}
+	}
+	return r
+}
+
+// bitmapMinDocs is the fewest documents a term must be in to get a bitmap;
+// below it the posting list is cheap enough to walk.
+const bitmapMinDocs = 4096
+
+// bitmapCache holds the bitmaps of frequent terms, built on first use after
+// the index last changed.
+type bitmapCache struct {
+	mu   sync.Mutex
+	maps map[string]bitmap // field name + "\x00" + term -> bitmap
+}
+
+func (c *bitmapCache) reset() {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	c.maps = nil
+	c.mu.Unlock()
+}
+
+// bitmap returns the bitmap of term in the named field, whose posting list
+// is ids, or nil if the index doesn't keep bitmaps or the term is too rare
+// for one to pay off. Bitmaps have no room for negative IDs, so an index
+// holding any, which Add allows, keeps none.
+func (idx *Index) bitmap(name, term string, ids []int) bitmap {
+	if idx.bitmaps == nil || len(ids) < bitmapMinDocs || idx.hasNegativeIDs() {
+		return nil
+	}
+	size := idx.NextID()
+	if len(ids)*64 < size {
+		return nil
+	}
+	c := idx.bitmaps
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	key := name + "\x00" + term
+	if b, ok := c.maps[key]; ok {
+		return b
+	}
+	b := newBitmap(size)
+	for _, id := range ids {
+		b.set(id)
+	}
+	if c.maps == nil {
+		c.maps = make(map[string]bitmap)
+	}
+	c.maps[key] = b
+	return b
+}
+
+// hasNegativeIDs reports whether any document, live or deleted, has an ID
+// below zero.
+func (idx *Index) hasNegativeIDs() bool {
+	return len(idx.IDs) > 0 && idx.IDs[0] < 0 ||
+		len(idx.Deleted) > 0 && idx.Deleted[0] < 0
+}
+
+// matchBitmaps is matchField for indexes keeping bitmaps: the bitmaps of
+// frequent terms are combined a word at a time, and only the rare terms'
+// posting lists are walked.
+func (idx *Index) matchBitmaps(name string, f *field, terms []string, op Operator, d *Diagnostics) []int {
+	var bms []bitmap
+	var lists [][]int
+	for _, term := range terms {
+		ids, ok := f.Postings.lookup(term)
+		if d != nil {
+			d.Postings[name+":"+term] = len(ids)
+		}
+		if !ok && op == And {
+			return nil
+		}
+		if b := idx.bitmap(name, term, ids); b != nil {
+			bms = append(bms, b)
+		} else {
+			lists = append(lists, ids)
+		}
+	}
+
+	if op == Or {
+		if len(bms) == 0 {
+			var r []int
+			for _, ids := range lists {
+				r = union(r, ids)
+			}
+			return r
+		}
+		acc := newBitmap(idx.NextID())
+		for _, b := range bms {
+			acc.or(b)
+		}
+		for _, ids := range lists {
+			for _, id := range ids {
+				acc.set(id)
+			}
+		}
+		return acc.ids()
+	}
+
+	var acc bitmap
+	for i, b := range bms {
+		if i == 0 {
+			acc = b
+		} else {
+			acc = acc.and(b)
+		}
+	}
+	if len(lists) == 0 {
+		if acc == nil {
+			return nil
+		}
+		return acc.ids()
+	}
+	r := lists[0]
+	for _, ids := range lists[1:] {
+		var steps int
+		r, steps = intersectionSteps(r, ids)
+		if d != nil {
+			d.IntersectionSteps += steps
+		}
+	}
+	if acc == nil {
+		return r
+	}
+	kept := make([]int, 0, len(r))
+	for _, id := range r {
+		if acc.contains(id) {
+			kept = append(kept, id)
+		}
+	}
+	return kept
+}
+
+// differenceTerm returns ids without the documents containing term in the
+// named field f.
+func (idx *Index) differenceTerm(name string, f *field, term string, ids []int) []int {
+	b := idx.bitmap(name, term, f.Postings.get(term))
+	if b == nil {
+		return difference(ids, f.Postings.get(term))
+	}
+	r := make([]int, 0, len(ids))
+	for _, id := range ids {
+		if !b.contains(id) {
+			r = append(r, id)
+		}
+	}
+	return r
+}
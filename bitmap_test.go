@@ -0,0 +1,103 @@
+package fulltextsearch
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestBitmap(t *testing.T) {
+	b := newBitmap(130)
+	for _, id := range []int{0, 63, 64, 129} {
+		b.set(id)
+	}
+	tests := []struct {
+		id   int
+		want bool
+	}{
+		{0, true},
+		{1, false},
+		{63, true},
+		{64, true},
+		{129, true},
+		{130, false},
+		{1 << 20, false},
+		{-1, false},
+		{-64, false},
+	}
+	for _, tt := range tests {
+		if got := b.contains(tt.id); got != tt.want {
+			t.Errorf("contains(%d) = %v, want %v", tt.id, got, tt.want)
+		}
+	}
+	if got, want := b.ids(), []int{0, 63, 64, 129}; !slices.Equal(got, want) {
+		t.Errorf("ids() = %v, want %v", got, want)
+	}
+
+	o := newBitmap(70)
+	o.set(1)
+	o.set(64)
+	if got, want := b.and(o).ids(), []int{64}; !slices.Equal(got, want) {
+		t.Errorf("and() = %v, want %v", got, want)
+	}
+	b.or(o)
+	if got, want := b.ids(), []int{0, 1, 63, 64, 129}; !slices.Equal(got, want) {
+		t.Errorf("ids() after or = %v, want %v", got, want)
+	}
+}
+
+func TestBitmapPostings(t *testing.T) {
+	queries := []string{
+		"common",
+		"common half",
+		"half third",
+		"half rare",
+		"common -half",
+		"third -half",
+		"third rare",
+	}
+	tests := []struct {
+		name  string
+		first int
+	}{
+		{"from zero", 0},
+		// Bitmaps can't hold negative IDs, so the index must do without.
+		{"negative IDs", -100},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var docs []Document
+			for i := range 9000 {
+				words := []string{"common"}
+				if i%2 == 0 {
+					words = append(words, "half")
+				}
+				if i%3 == 0 {
+					words = append(words, "third")
+				}
+				if i%500 == 0 {
+					words = append(words, "rare")
+				}
+				docs = append(docs, Document{ID: tt.first + i, Text: strings.Join(words, " ")})
+			}
+			lists := NewIndex(Config{})
+			lists.Add(docs)
+			bitmaps := NewIndex(Config{BitmapPostings: true})
+			bitmaps.Add(docs)
+
+			for _, q := range queries {
+				want := lists.Search(q)
+				if len(want) == 0 {
+					t.Fatalf("Search(%q) without bitmaps found nothing", q)
+				}
+				if got := bitmaps.Search(q); !slices.Equal(got, want) {
+					t.Errorf("Search(%q) = %d documents, want %d", q, len(got), len(want))
+				}
+				want = lists.SearchAny(q)
+				if got := bitmaps.SearchAny(q); !slices.Equal(got, want) {
+					t.Errorf("SearchAny(%q) = %d documents, want %d", q, len(got), len(want))
+				}
+			}
+		})
+	}
+}
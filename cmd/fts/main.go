@@ -0,0 +1,135 @@
+// Command fts builds an index of the English Wikipedia abstract dump, or
+// loads it if it was built before, and runs a sample search.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"runtime"
+
+	"github.com/InterruptSpeed/fulltextsearch"
+)
+
+func main() {
+	rebuildStale := flag.Bool("rebuild-stale", false, "rebuild the index if the source dump is newer than it")
+	rebuildCorrupt := flag.Bool("rebuild-corrupt", true, "rebuild the index if it is corrupt or unreadable, e.g. saved by an older version, rather than failing")
+	addFilename := flag.String("add", "", "add the documents of another abstract dump to an existing index")
+	ndjsonFilename := flag.String("add-ndjson", "", "add the documents of a newline-delimited JSON file, or - for standard input, to an existing index")
+	feedURL := flag.String("feed", "", "add the items of an RSS or Atom feed not yet in an existing index")
+	workers := flag.Int("workers", runtime.GOMAXPROCS(0), "number of goroutines analyzing documents while indexing")
+	flag.Parse()
+
+	idxFilename := "enwiki.idx"
+	srcFilename := "enwiki-latest-abstract1.xml.gz"
+	cfg := fulltextsearch.Config{StoreDocuments: true}
+	idx := fulltextsearch.NewIndex(cfg)
+
+	rebuild, err := fulltextsearch.NeedsRebuild(idxFilename, srcFilename, *rebuildStale)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if !rebuild {
+		// path/to/whatever exists
+		log.Println("full text search index exists; using...")
+		// Load also reads the stored abstracts from enwiki.idx.docs
+		err := idx.Load(idxFilename)
+		// an index saved by an older version, or with another analyzer,
+		// is as unusable as a corrupt one
+		unreadable := errors.Is(err, fulltextsearch.ErrCorruptIndex) ||
+			errors.Is(err, fulltextsearch.ErrNotIndex) ||
+			errors.Is(err, fulltextsearch.ErrFormatVersion) ||
+			errors.Is(err, fulltextsearch.ErrAnalyzerMismatch)
+		if unreadable && *rebuildCorrupt {
+			log.Println(err)
+			idx = fulltextsearch.NewIndex(cfg)
+			rebuild = true
+		} else if unreadable {
+			log.Fatalf("%s: %v; run with -rebuild-corrupt to rebuild it", idxFilename, err)
+		} else if err != nil {
+			panic(err)
+		}
+	}
+	if !rebuild {
+		if *addFilename != "" || *ndjsonFilename != "" {
+			var docs []fulltextsearch.Document
+			var err error
+			src := *addFilename
+			if *ndjsonFilename != "" {
+				// e.g. some-export | go run ./cmd/fts -add-ndjson -
+				src = *ndjsonFilename
+				err = fulltextsearch.StreamNDJSON(src, fulltextsearch.DefaultMapping, func(doc fulltextsearch.Document) error {
+					docs = append(docs, doc)
+					return nil
+				})
+			} else {
+				docs, err = fulltextsearch.LoadDocuments(src, fulltextsearch.DefaultDumpOptions)
+			}
+			if err != nil {
+				log.Fatal(err)
+			}
+			// documents already indexed keep their IDs, found by URL, and
+			// are only reindexed if they changed; new ones are numbered
+			// after them and only they are appended to enwiki.idx.docs
+			idx.AssignIDs(docs)
+			indexed, skipped := idx.Reindex(docs)
+			log.Printf("indexed %d documents from %s, %d unchanged", indexed, src, skipped)
+			if err := idx.Save(idxFilename); err != nil {
+				panic(err)
+			}
+		}
+		if *feedURL != "" {
+			// items are known by their links, so each run only adds what
+			// was published since the last
+			added, err := idx.AddFeed(context.Background(), *feedURL)
+			if err != nil {
+				log.Fatal(err)
+			}
+			log.Printf("indexed %d new items from %s", added, *feedURL)
+			if err := idx.Save(idxFilename); err != nil {
+				panic(err)
+			}
+		}
+	} else {
+		// path does *not* exist, is stale or is corrupt, so build index and
+		// save
+		log.Println("full text search index does not exist, is stale or is corrupt; rebuilding...")
+
+		// the dump is streamed and indexed a batch at a time rather than
+		// decoded into memory whole
+		err := idx.AddDump(srcFilename, fulltextsearch.IndexOptions{
+			Workers:  *workers,
+			Progress: func(p fulltextsearch.Progress) { log.Println(p) },
+			Dump:     fulltextsearch.DefaultDumpOptions,
+		})
+		if err != nil {
+			log.Fatal(err)
+			return
+		}
+
+		//idx.Add([]fulltextsearch.Document{{ID: 1, Text: "A donut on a glass plate. Only the donuts."}})
+		//idx.Add([]fulltextsearch.Document{{ID: 2, Text: "donut is a donut"}})
+
+		if err := idx.Save(idxFilename); err != nil {
+			panic(err)
+		}
+	}
+
+	query := "small wild cat"
+	r := idx.Rank(query, nil)
+	if len(r) == 0 {
+		if s := idx.DidYouMean(query); s != "" {
+			fmt.Printf("no results; did you mean: %s?\n", s)
+		}
+		return
+	}
+	// the doc store saves re-reading the whole xml file to show abstracts
+	if err := idx.Resolve(r); err != nil {
+		log.Fatal(err)
+	}
+	for _, res := range r {
+		fmt.Printf("[%d]\t%.3f\t%s\t%s\n\t%s\n", res.ID, res.Score, res.Title, res.URL, res.Text)
+	}
+}
@@ -0,0 +1,64 @@
+package fulltextsearch
+
+import (
+	"math"
+	"time"
+)
+
+// CompositeScoring blends relevance with document freshness and importance.
+// A result's final score is
+//
+//	Relevance*bm25/maxBM25 + Recency*0.5^(age/HalfLife) + Boost*boost
+//
+// where BM25 is normalized against the best result so the weights stay
+// comparable whatever the query.
+type CompositeScoring struct {
+	Relevance float64
+	Recency   float64
+	Boost     float64
+	// HalfLife is the age at which a document's recency signal halves.
+	HalfLife time.Duration
+	// Now is the time ages are measured from; zero means time.Now().
+	Now time.Time
+}
+
+var DefaultCompositeScoring = CompositeScoring{
+	Relevance: 1,
+	Recency:   0.2,
+	Boost:     0.1,
+	HalfLife:  365 * 24 * time.Hour,
+}
+
+// recency decays from 1 for a brand new document towards 0. Undated
+// documents get 0.
+func (c CompositeScoring) recency(date, now time.Time) float64 {
+	if date.IsZero() || c.HalfLife <= 0 {
+		return 0
+	}
+	age := now.Sub(date)
+	if age < 0 {
+		age = 0
+	}
+	return math.Pow(0.5, float64(age)/float64(c.HalfLife))
+}
+
+// rescore replaces the BM25 scores in r with composite scores.
+func (c CompositeScoring) rescore(r []Result, boosts *cowMap[int, float64]) {
+	now := c.Now
+	if now.IsZero() {
+		now = time.Now()
+	}
+	var max float64
+	for _, res := range r {
+		max = math.Max(max, res.Score)
+	}
+	for i := range r {
+		var rel float64
+		if max > 0 {
+			rel = r[i].Score / max
+		}
+		r[i].Score = c.Relevance*rel +
+			c.Recency*c.recency(r[i].Date, now) +
+			c.Boost*boosts.get(r[i].ID)
+	}
+}
@@ -0,0 +1,38 @@
+package fulltextsearch
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestCompositeScoring(t *testing.T) {
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	docs := []Document{
+		{ID: 0, Text: "cat cat cat", Date: now.AddDate(-5, 0, 0)},
+		{ID: 1, Text: "cat and a dog and a bird", Date: now.AddDate(0, 0, -1)},
+		{ID: 2, Text: "cat and a dog", Date: now.AddDate(-1, 0, 0), Boost: 1},
+	}
+	tests := []struct {
+		name    string
+		scoring *CompositeScoring
+		want    []int
+	}{
+		{"relevance only", nil, []int{0, 2, 1}},
+		{"weighted towards recency", &CompositeScoring{Relevance: 0.2, Recency: 1, HalfLife: 365 * 24 * time.Hour, Now: now}, []int{1, 2, 0}},
+		{"weighted towards boosts", &CompositeScoring{Relevance: 0.2, Boost: 1, Now: now}, []int{2, 0, 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			idx := NewIndex(Config{Scoring: tt.scoring})
+			idx.Add(docs)
+			var got []int
+			for _, r := range idx.Rank("cat", nil) {
+				got = append(got, r.ID)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("Rank(cat) = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,332 @@
+package fulltextsearch
+
+import (
+	"hash/maphash"
+	"iter"
+	"maps"
+	"sync/atomic"
+)
+
+// cowMap is a map that clones in constant time, for the copy-on-write
+// versions of LiveIndex and the copies SyncIndex backs up. It is a hash
+// array mapped trie: a clone shares every node with the original, and
+// either of them changing a key copies only the nodes on the path to it,
+// the first time, so an update costs in proportion to the keys it touches
+// rather than to the size of the map.
+//
+// Slice values are shared too, and are clipped when their node is copied,
+// so appending to one never writes into an array the other version sees.
+// Values must otherwise be replaced rather than modified in place, as
+// insertAt and removeAt do.
+//
+// The zero value is an empty map ready to use, and as with a Go map, a nil
+// *cowMap reads as empty. A cowMap may be read, and cloned, concurrently
+// but not written.
+type cowMap[K comparable, V any] struct {
+	root *cowNode[K, V]
+	n    int
+	// owner marks the nodes this map may change in place, those it made
+	// since it was last cloned.
+	owner *cowOwner
+}
+
+// cowOwner identifies a version of a cowMap. Cloning the map freezes it,
+// as its nodes are shared from then on, and the map takes a new owner
+// when it is next written. Freezing rather than replacing the owner keeps
+// cloning free of writes to the map, which readers may be using.
+type cowOwner struct {
+	frozen atomic.Bool
+}
+
+// cowNode is a node of the trie: either internal, with a child for each
+// value of the next cowBits bits of the key's hash, or a leaf holding the
+// entries whose hashes agree so far.
+type cowNode[K comparable, V any] struct {
+	owner    *cowOwner
+	children *[1 << cowBits]*cowNode[K, V]
+	entries  []cowEntry[K, V]
+}
+
+type cowEntry[K comparable, V any] struct {
+	hash  uint64
+	key   K
+	value V
+}
+
+const (
+	cowBits = 5
+	// cowLeafSize is how many entries a leaf holds before it splits.
+	cowLeafSize = 8
+	// cowMaxShift is the deepest shift of the hash; leaves there hold
+	// whatever collides.
+	cowMaxShift = 64 - cowBits
+)
+
+var cowSeed = maphash.MakeSeed()
+
+// cowHash hashes key, taking shortcuts for the index's key types.
+func cowHash[K comparable](key K) uint64 {
+	switch k := any(&key).(type) {
+	case *int:
+		// splitmix64's finalizer, a bijection, so distinct IDs never
+		// collide.
+		h := uint64(*k)
+		h = (h ^ h>>30) * 0xbf58476d1ce4e5b9
+		h = (h ^ h>>27) * 0x94d049bb133111eb
+		return h ^ h>>31
+	case *string:
+		return maphash.String(cowSeed, *k)
+	}
+	return maphash.Comparable(cowSeed, key)
+}
+
+func newCowMap[K comparable, V any](m map[K]V) cowMap[K, V] {
+	var r cowMap[K, V]
+	for k, v := range m {
+		r.set(k, v)
+	}
+	return r
+}
+
+// clone returns a copy of m sharing all its nodes. Neither changes any of
+// them in place after this.
+func (m *cowMap[K, V]) clone() cowMap[K, V] {
+	if m.owner != nil {
+		m.owner.frozen.Store(true)
+	}
+	return cowMap[K, V]{root: m.root, n: m.n}
+}
+
+// owns reports whether m may change n in place, or, for reads, whether
+// slices in n are m's alone to append to.
+func (m *cowMap[K, V]) owns(n *cowNode[K, V]) bool {
+	return n.owner == m.owner && !m.owner.frozen.Load()
+}
+
+// own gives m a new owner if it has none or it has been cloned, ahead of
+// changing it.
+func (m *cowMap[K, V]) own() {
+	if m.owner == nil || m.owner.frozen.Load() {
+		m.owner = new(cowOwner)
+	}
+}
+
+func (m *cowMap[K, V]) len() int {
+	if m == nil {
+		return 0
+	}
+	return m.n
+}
+
+// get returns the value of key, or the zero value if there is none.
+func (m *cowMap[K, V]) get(key K) V {
+	v, _ := m.lookup(key)
+	return v
+}
+
+func (m *cowMap[K, V]) lookup(key K) (V, bool) {
+	var zero V
+	if m == nil {
+		return zero, false
+	}
+	h := cowHash(key)
+	n := m.root
+	for shift := 0; n != nil; shift += cowBits {
+		if n.children != nil {
+			n = n.children[h>>shift&(1<<cowBits-1)]
+			continue
+		}
+		for i := range n.entries {
+			if e := &n.entries[i]; e.hash == h && e.key == key {
+				v := e.value
+				if !m.owns(n) {
+					// Whoever changes it will copy the node, clipping
+					// its slices, but this one may be appended to first.
+					clipValue(&v)
+				}
+				return v, true
+			}
+		}
+		break
+	}
+	return zero, false
+}
+
+func (m *cowMap[K, V]) set(key K, value V) {
+	m.own()
+	var added bool
+	e := cowEntry[K, V]{cowHash(key), key, value}
+	m.root, added = m.setIn(m.root, e, 0)
+	if added {
+		m.n++
+	}
+}
+
+func (m *cowMap[K, V]) setIn(n *cowNode[K, V], e cowEntry[K, V], shift int) (*cowNode[K, V], bool) {
+	if n == nil {
+		return &cowNode[K, V]{owner: m.owner, entries: []cowEntry[K, V]{e}}, true
+	}
+	n = m.mutable(n)
+	if n.children != nil {
+		i := e.hash >> shift & (1<<cowBits - 1)
+		var added bool
+		n.children[i], added = m.setIn(n.children[i], e, shift+cowBits)
+		return n, added
+	}
+	for i := range n.entries {
+		if n.entries[i].hash == e.hash && n.entries[i].key == e.key {
+			n.entries[i].value = e.value
+			return n, false
+		}
+	}
+	n.entries = append(n.entries, e)
+	if len(n.entries) > cowLeafSize && shift < cowMaxShift {
+		children := new([1 << cowBits]*cowNode[K, V])
+		for _, e := range n.entries {
+			i := e.hash >> shift & (1<<cowBits - 1)
+			if children[i] == nil {
+				children[i] = &cowNode[K, V]{owner: m.owner}
+			}
+			children[i].entries = append(children[i].entries, e)
+		}
+		n.children, n.entries = children, nil
+	}
+	return n, true
+}
+
+// setAll sets every key of src in m.
+func (m *cowMap[K, V]) setAll(src *cowMap[K, V]) {
+	for k, v := range src.all() {
+		m.set(k, v)
+	}
+}
+
+func (m *cowMap[K, V]) delete(key K) {
+	if m == nil {
+		return
+	}
+	m.own()
+	var deleted bool
+	m.root, deleted = m.deleteIn(m.root, cowHash(key), key, 0)
+	if deleted {
+		m.n--
+	}
+}
+
+// deleteIn deletes key from n, returning nil for n if it is left empty.
+func (m *cowMap[K, V]) deleteIn(n *cowNode[K, V], h uint64, key K, shift int) (*cowNode[K, V], bool) {
+	if n == nil {
+		return nil, false
+	}
+	if n.children != nil {
+		i := h >> shift & (1<<cowBits - 1)
+		child, deleted := m.deleteIn(n.children[i], h, key, shift+cowBits)
+		if !deleted {
+			return n, false
+		}
+		n = m.mutable(n)
+		n.children[i] = child
+		for _, c := range n.children {
+			if c != nil {
+				return n, true
+			}
+		}
+		return nil, true
+	}
+	for i, e := range n.entries {
+		if e.hash == h && e.key == key {
+			if len(n.entries) == 1 {
+				return nil, true
+			}
+			// Leave the entries as they were for any iteration under
+			// way, which will go on to the next.
+			n = m.mutable(n)
+			n.entries = removeAt(n.entries, i)
+			return n, true
+		}
+	}
+	return n, false
+}
+
+// mutable returns n if m may change it in place, or else a copy it may.
+func (m *cowMap[K, V]) mutable(n *cowNode[K, V]) *cowNode[K, V] {
+	if m.owns(n) {
+		return n
+	}
+	c := &cowNode[K, V]{owner: m.owner}
+	if n.children != nil {
+		children := *n.children
+		c.children = &children
+		return c
+	}
+	c.entries = make([]cowEntry[K, V], len(n.entries), len(n.entries)+1)
+	copy(c.entries, n.entries)
+	for i := range c.entries {
+		clipValue(&c.entries[i].value)
+	}
+	return c
+}
+
+// all returns an iterator over the entries of m, in no particular order.
+// As with a Go map, entries may be set and deleted during the iteration.
+func (m *cowMap[K, V]) all() iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		if m != nil {
+			m.each(m.root, yield)
+		}
+	}
+}
+
+// keys returns an iterator over the keys of m, in no particular order.
+func (m *cowMap[K, V]) keys() iter.Seq[K] {
+	return func(yield func(K) bool) {
+		if m != nil {
+			m.each(m.root, func(k K, _ V) bool { return yield(k) })
+		}
+	}
+}
+
+func (m *cowMap[K, V]) each(n *cowNode[K, V], yield func(K, V) bool) bool {
+	if n == nil {
+		return true
+	}
+	if n.children != nil {
+		for _, c := range n.children {
+			if !m.each(c, yield) {
+				return false
+			}
+		}
+		return true
+	}
+	owned := m.owns(n)
+	for _, e := range n.entries {
+		if !owned {
+			clipValue(&e.value)
+		}
+		if !yield(e.key, e.value) {
+			return false
+		}
+	}
+	return true
+}
+
+// toMap returns the entries of m as a Go map, for saving, or nil if m is.
+func (m *cowMap[K, V]) toMap() map[K]V {
+	if m == nil {
+		return nil
+	}
+	return maps.Collect(m.all())
+}
+
+// clipValue clips v if it is one of the slice types the index keeps in
+// cowMaps.
+func clipValue[V any](v *V) {
+	switch s := any(v).(type) {
+	case *[]int:
+		*s = (*s)[:len(*s):len(*s)]
+	case *[][]int:
+		*s = (*s)[:len(*s):len(*s)]
+	case *[]string:
+		*s = (*s)[:len(*s):len(*s)]
+	}
+}
@@ -0,0 +1,63 @@
+package fulltextsearch
+
+import (
+	"maps"
+	"math/rand/v2"
+	"slices"
+	"testing"
+)
+
+func TestCowMap(t *testing.T) {
+	rng := rand.New(rand.NewPCG(1, 2))
+	type version struct {
+		m    cowMap[int, []int]
+		want map[int][]int
+	}
+	versions := []*version{{want: map[int][]int{}}}
+	for range 5000 {
+		v := versions[rng.IntN(len(versions))]
+		k := rng.IntN(500)
+		switch op := rng.IntN(10); {
+		case op == 0 && len(versions) < 20:
+			versions = append(versions, &version{m: v.m.clone(), want: maps.Clone(v.want)})
+		case op < 3:
+			v.m.delete(k)
+			delete(v.want, k)
+		default:
+			// Append, as posting lists grow, to catch versions sharing
+			// an array.
+			ids := append(v.m.get(k), rng.IntN(1000))
+			v.m.set(k, ids)
+			v.want[k] = slices.Clone(ids)
+		}
+	}
+	for i, v := range versions {
+		if v.m.len() != len(v.want) {
+			t.Errorf("version %d: len() = %d, want %d", i, v.m.len(), len(v.want))
+		}
+		if got := v.m.toMap(); !maps.EqualFunc(got, v.want, slices.Equal) {
+			t.Errorf("version %d: entries = %v, want %v", i, got, v.want)
+		}
+	}
+}
+
+func TestCloneSharesUntouchedPostings(t *testing.T) {
+	idx := NewIndex(Config{})
+	for id := range 1000 {
+		idx.Add([]Document{{ID: id, Text: "glass plate"}})
+	}
+	l := NewLiveIndex(idx)
+	if err := l.AddBatch([]Document{{ID: 1000, Text: "glass donut"}}); err != nil {
+		t.Fatal(err)
+	}
+	before, after := idx.Fields["text"], l.Snapshot().Fields["text"]
+	if a, b := before.Postings.get("plate"), after.Postings.get("plate"); &a[0] != &b[0] {
+		t.Error("AddBatch copied the posting list of a term it didn't touch")
+	}
+	if got := len(before.Postings.get("glass")); got != 1000 {
+		t.Errorf("old version has %d documents for glass, want 1000", got)
+	}
+	if got := len(after.Postings.get("glass")); got != 1001 {
+		t.Errorf("new version has %d documents for glass, want 1001", got)
+	}
+}
@@ -0,0 +1,93 @@
+package fulltextsearch
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// TextExtensions are the extensions of the text files LoadDir indexes, in
+// lower case.
+var TextExtensions = []string{".txt", ".md", ".markdown"}
+
+// HTMLExtensions are the extensions of the web pages LoadDir indexes, in
+// lower case.
+var HTMLExtensions = []string{".html", ".htm"}
+
+// LoadDir reads every text file and web page under root as a document; see
+// StreamDir.
+func LoadDir(root string) ([]Document, error) {
+	var docs []Document
+	err := StreamDir(root, func(doc Document) error {
+		docs = append(docs, doc)
+		return nil
+	})
+	return docs, err
+}
+
+// StreamDir walks the directory tree at root in lexical order, calling fn
+// with each file whose extension is one of TextExtensions as a document:
+// its path is the URL, its first non-blank line the title and the rest the
+// text, and its modification time the date. A Markdown heading's leading
+// #s are dropped from the title. Files with one of HTMLExtensions are read
+// with ParseHTML instead, titled by their file name if they have no
+// <title>. Hidden directories, such as .git, are skipped. It stops at the
+// first error fn returns and returns it.
+func StreamDir(root string, fn func(Document) error) error {
+	id := 0
+	return filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if path != root && strings.HasPrefix(d.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		ext := strings.ToLower(filepath.Ext(path))
+		isHTML := slices.Contains(HTMLExtensions, ext)
+		if !isHTML && !slices.Contains(TextExtensions, ext) {
+			return nil
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		var doc Document
+		if isHTML {
+			if doc, err = ParseHTML(bytes.NewReader(content)); err != nil {
+				return fmt.Errorf("%s: %w", path, err)
+			}
+			if doc.Title == "" {
+				doc.Title = strings.TrimSuffix(d.Name(), filepath.Ext(path))
+			}
+		} else {
+			doc.Title, doc.Text = splitTitle(string(bytes.TrimSpace(content)))
+		}
+		doc.ID = id
+		doc.URL = filepath.ToSlash(path)
+		sum := sha1.Sum([]byte(doc.URL))
+		doc.URLSHA1 = sum[:]
+		doc.Date = info.ModTime()
+		id++
+		return fn(doc)
+	})
+}
+
+// splitTitle splits the content of a text file into its first line, minus
+// any Markdown heading marker, and the rest.
+func splitTitle(content string) (title, text string) {
+	title, text, _ = strings.Cut(content, "\n")
+	title = strings.TrimSpace(strings.TrimLeft(title, "#"))
+	return title, strings.TrimSpace(text)
+}
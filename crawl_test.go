@@ -0,0 +1,83 @@
+package fulltextsearch
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadDir(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		"donuts.md":         "# Donuts\n\nA glazed donut.\n",
+		"notes/bagels.TXT":  "\n  Bagels\nToasted, with cream cheese.",
+		"notes/plates.html": "<html><head><title>Plates</title></head><body><p>A glass plate.</p><script>x()</script></body></html>",
+		"notes/bowls.htm":   "<p>A glass bowl.</p>",
+		"image.png":         "not text",
+		".git/HEAD.txt":     "hidden",
+	}
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	docs, err := LoadDir(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Document{
+		{Title: "Donuts", URL: "donuts.md", Text: "A glazed donut."},
+		{Title: "Bagels", URL: "notes/bagels.TXT", Text: "Toasted, with cream cheese."},
+		{Title: "bowls", URL: "notes/bowls.htm", Text: "A glass bowl."},
+		{Title: "Plates", URL: "notes/plates.html", Text: "A glass plate."},
+	}
+	if len(docs) != len(want) {
+		t.Fatalf("LoadDir() = %d documents, want %d", len(docs), len(want))
+	}
+	for i, doc := range docs {
+		w := want[i]
+		w.ID = i
+		w.URL = filepath.ToSlash(filepath.Join(root, w.URL))
+		if doc.ID != w.ID || doc.Title != w.Title || doc.URL != w.URL || doc.Text != w.Text {
+			t.Errorf("document %d = {%d %q %q %q}, want {%d %q %q %q}",
+				i, doc.ID, doc.Title, doc.URL, doc.Text, w.ID, w.Title, w.URL, w.Text)
+		}
+		if doc.Date.IsZero() || len(doc.URLSHA1) == 0 {
+			t.Errorf("document %d has no date or URLSHA1", i)
+		}
+	}
+}
+
+func TestStreamDirErrors(t *testing.T) {
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "a.txt"), []byte("Donuts"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join(root, "missing"), filepath.Join(root, "b.txt")); err != nil {
+		t.Skip(err)
+	}
+	var docs int
+	err := StreamDir(root, func(Document) error {
+		docs++
+		return nil
+	})
+	if !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("StreamDir() with a dangling link = %v, want %v", err, os.ErrNotExist)
+	}
+	if docs != 1 {
+		t.Errorf("StreamDir() read %d documents before failing, want 1", docs)
+	}
+
+	if err := StreamDir(filepath.Join(root, "missing"), func(Document) error { return nil }); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("StreamDir(missing directory) = %v, want %v", err, os.ErrNotExist)
+	}
+	stop := errors.New("stop")
+	if err := StreamDir(root, func(Document) error { return stop }); err != stop {
+		t.Errorf("StreamDir() = %v, want the error fn returned", err)
+	}
+}
@@ -0,0 +1,81 @@
+package fulltextsearch
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// LoadCSV reads the documents of the CSV file at path, or a TSV file if
+// its name ends in .tsv, mapping its columns according to m.
+func LoadCSV(path string, m FieldMapping) ([]Document, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	comma := ','
+	if strings.EqualFold(filepath.Ext(path), ".tsv") {
+		comma = '\t'
+	}
+	var docs []Document
+	err = StreamCSV(f, comma, m, func(doc Document) error {
+		docs = append(docs, doc)
+		return nil
+	})
+	return docs, err
+}
+
+// StreamCSV reads CSV with fields separated by comma from r one row at a
+// time, calling fn with each as a document. The first row names the
+// columns, which m maps to document fields; a Tags column holds
+// comma-separated tags. Rows may be short, leaving out trailing empty
+// columns, but a row with more fields than the header is an error. It
+// stops at the first error fn returns and returns it.
+func StreamCSV(r io.Reader, comma rune, m FieldMapping, fn func(Document) error) error {
+	cr := csv.NewReader(r)
+	cr.Comma = comma
+	cr.ReuseRecord = true
+	// Rows may leave out trailing empty columns, though not have more
+	// than the header, which is checked below.
+	cr.FieldsPerRecord = -1
+	// Tab-separated exports seldom quote fields, so a stray quote is
+	// taken literally rather than failing the row.
+	cr.LazyQuotes = comma == '\t'
+	header, err := cr.Read()
+	if err == io.EOF {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	columns := make([]string, len(header))
+	copy(columns, header)
+
+	for n := 0; ; n++ {
+		row, err := cr.Read()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if len(row) > len(columns) {
+			line, _ := cr.FieldPos(0)
+			return fmt.Errorf("line %d: %d fields, but the header names %d", line, len(row), len(columns))
+		}
+		fields := make(map[string]any, len(columns))
+		for i, value := range row {
+			fields[columns[i]] = value
+		}
+		doc, err := m.document(fields, n)
+		if err != nil {
+			line, _ := cr.FieldPos(0)
+			return fmt.Errorf("line %d: %w", line, err)
+		}
+		if err := fn(doc); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package fulltextsearch
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestLoadCSV(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		content string
+	}{
+		{"csv", "docs.csv", "id,title,text,tags\n" +
+			"3,Donuts,\"A glazed donut, with sprinkles.\",\"sweet,fried\"\n" +
+			"5,Bagels\n"},
+		{"tsv", "docs.TSV", "id\ttitle\ttext\ttags\n" +
+			"3\tDonuts\tA glazed donut, with sprinkles.\tsweet,fried\n" +
+			"5\tBagels\n"},
+	}
+	want := []Document{
+		{ID: 3, Title: "Donuts", Text: "A glazed donut, with sprinkles.", Tags: []string{"sweet", "fried"}},
+		{ID: 5, Title: "Bagels"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), tt.file)
+			if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			docs, err := LoadCSV(path, DefaultMapping)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(docs) != len(want) {
+				t.Fatalf("LoadCSV() = %d documents, want %d", len(docs), len(want))
+			}
+			for i, doc := range docs {
+				w := want[i]
+				if doc.ID != w.ID || doc.Title != w.Title || doc.Text != w.Text || !slices.Equal(doc.Tags, w.Tags) {
+					t.Errorf("document %d = %+v, want %+v", i, doc, w)
+				}
+			}
+		})
+	}
+}
+
+func TestStreamCSVMalformed(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		// docs is how many documents are read before the error.
+		docs int
+	}{
+		{"ragged row", "id,title\n1,Donuts\n2,Bagels,extra\n", 1},
+		{"bare quote", "id,title\n1,Do\"nuts\n", 0},
+		{"unterminated quote", "id,title\n1,Donuts\n2,\"Bagels\n", 1},
+		{"invalid ID", "id,title\nseven,Donuts\n", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var docs int
+			err := StreamCSV(strings.NewReader(tt.input), ',', DefaultMapping, func(Document) error {
+				docs++
+				return nil
+			})
+			if err == nil {
+				t.Error("StreamCSV() error = nil, want an error")
+			}
+			if docs != tt.docs {
+				t.Errorf("StreamCSV() read %d documents before failing, want %d", docs, tt.docs)
+			}
+		})
+	}
+}
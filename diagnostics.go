@@ -0,0 +1,57 @@
+package fulltextsearch
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"time"
+)
+
+// Diagnostics records the work a search did, to help explain slow queries.
+type Diagnostics struct {
+	// Postings holds the length of the posting list scanned for each
+	// analyzed query term in each field, keyed field:term; zero means the
+	// field doesn't have the term.
+	Postings map[string]int
+	// Expansions holds the terms each wildcard or fuzzy query word, such
+	// as hydro* or colour~1, expanded to, and whether they were capped.
+	Expansions map[string]Expansion
+	// UncheckedPhrases lists the quoted phrases whose word order couldn't
+	// be checked, since the index has neither positions nor a document
+	// store to build them from, so their words matched anywhere.
+	UncheckedPhrases []string
+	// IntersectionSteps counts loop iterations spent intersecting lists.
+	IntersectionSteps int
+	Results           int
+	Elapsed           time.Duration
+}
+
+func (d *Diagnostics) String() string {
+	s := fmt.Sprintf("%d results in %s, postings %v, %d intersection steps",
+		d.Results, d.Elapsed, d.Postings, d.IntersectionSteps)
+	for _, word := range slices.Sorted(maps.Keys(d.Expansions)) {
+		e := d.Expansions[word]
+		s += fmt.Sprintf(", %s expanded to %d terms", word, len(e.Terms))
+		if e.Truncated {
+			s += " (truncated)"
+		}
+	}
+	if len(d.UncheckedPhrases) > 0 {
+		s += fmt.Sprintf(", phrases matched as separate words %q", d.UncheckedPhrases)
+	}
+	return s
+}
+
+// SearchDiagnostics runs Search and also reports what it cost.
+func (idx *Index) SearchDiagnostics(text string) ([]int, *Diagnostics) {
+	return idx.searchDiagnostics(text, idx.DefaultOperator)
+}
+
+func (idx *Index) searchDiagnostics(text string, op Operator) ([]int, *Diagnostics) {
+	d := &Diagnostics{Postings: make(map[string]int), Expansions: make(map[string]Expansion)}
+	start := time.Now()
+	r := idx.match(text, op, d)
+	d.Elapsed = time.Since(start)
+	d.Results = len(r)
+	return r, d
+}
@@ -0,0 +1,91 @@
+package fulltextsearch
+
+import "testing"
+
+func TestDiagnosticsPostingsPerField(t *testing.T) {
+	idx := NewIndex(Config{})
+	idx.Add([]Document{
+		{ID: 0, Title: "Wild cat", Text: "the cat sat"},
+		{ID: 1, Text: "a cat and a donut"},
+		{ID: 2, Text: "another cat"},
+	})
+	_, d := idx.SearchDiagnostics("cat")
+	for key, want := range map[string]int{"text:cat": 3, "title:cat": 1} {
+		if got := d.Postings[key]; got != want {
+			t.Errorf("Postings[%q] = %d, want %d", key, got, want)
+		}
+	}
+}
+
+func TestRankMatchesDefaultFieldsOnly(t *testing.T) {
+	idx := NewIndex(Config{DetectLanguages: true})
+	idx.Add([]Document{
+		{ID: 0, Text: "The cat is sitting on the mat and it is looking at the birds in the garden."},
+		{ID: 1, Text: "The word en is a unit of measurement in typography."},
+	})
+	r := idx.Rank("en", nil)
+	if len(r) != 1 || r[0].ID != 1 {
+		t.Errorf("Rank(en) = %v, want only document 1", r)
+	}
+	if r := idx.Rank("lang:en", nil); len(r) != 2 {
+		t.Errorf("Rank(lang:en) matched %d documents, want 2", len(r))
+	}
+}
+
+func TestDiagnosticsMultiTermQuery(t *testing.T) {
+	idx := NewIndex(Config{MaxExpansions: 2})
+	idx.Add([]Document{
+		{ID: 0, Text: "hydrogen cat donut"},
+		{ID: 1, Text: "hydroxide cat"},
+		{ID: 2, Text: "hydrofoil cat donut"},
+		{ID: 3, Text: "hydrology donut"},
+	})
+	tests := []struct {
+		query         string
+		postings      map[string]int
+		expansions    map[string]int // word -> number of terms
+		truncated     map[string]bool
+		intersections bool
+	}{
+		{
+			query:         "cat donut",
+			postings:      map[string]int{"text:cat": 3, "text:donut": 3, "title:cat": 0},
+			intersections: true,
+		},
+		{
+			query:      "cat hydro*",
+			postings:   map[string]int{"text:cat": 3},
+			expansions: map[string]int{"hydro*": 2},
+			truncated:  map[string]bool{"hydro*": true},
+		},
+		{
+			query:      "hydrox*",
+			expansions: map[string]int{"hydrox*": 1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			_, d := idx.SearchDiagnostics(tt.query)
+			for key, want := range tt.postings {
+				if got, ok := d.Postings[key]; !ok || got != want {
+					t.Errorf("Postings[%q] = %d, %t, want %d", key, got, ok, want)
+				}
+			}
+			if len(d.Expansions) != len(tt.expansions) {
+				t.Errorf("Expansions = %v, want %d", d.Expansions, len(tt.expansions))
+			}
+			for word, want := range tt.expansions {
+				e := d.Expansions[word]
+				if len(e.Terms) != want || e.Truncated != tt.truncated[word] {
+					t.Errorf("Expansions[%q] = %v, want %d terms, truncated %t", word, e, want, tt.truncated[word])
+				}
+			}
+			if tt.intersections && d.IntersectionSteps == 0 {
+				t.Error("IntersectionSteps = 0")
+			}
+			if d.Elapsed <= 0 {
+				t.Errorf("Elapsed = %v", d.Elapsed)
+			}
+		})
+	}
+}
@@ -0,0 +1,177 @@
+package fulltextsearch
+
+import (
+	"compress/gzip"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/xml"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+type Document struct {
+	Title string `xml:"title"`
+	URL   string `xml:"url"`
+	Text  string `xml:"abstract"`
+	// URLSHA1 is the SHA-1 of URL, which identifies the document across
+	// dumps; see DocumentKey.
+	URLSHA1 []byte
+	// ID is the document's number within one index. Unlike URLSHA1 it is
+	// only stable for as long as the index is extended rather than rebuilt.
+	ID    int
+	Date  time.Time `xml:"-"`
+	Boost float64   `xml:"-"`
+	Tags  []string  `xml:"tag"`
+	Links []Sublink `xml:"links>sublink"`
+	// Language is the ISO 639-1 code of the document's language, selecting
+	// how it is analyzed. It is detected if empty and the index detects
+	// languages.
+	Language string `xml:"-"`
+	// Extra holds any further fields, by name. They are analyzed like text
+	// unless the index gives them an analyzer of their own.
+	Extra map[string]string `xml:"-"`
+}
+
+// Sublink is a link to a section of the article, as listed in the dump.
+type Sublink struct {
+	Anchor string `xml:"anchor"`
+	Link   string `xml:"link"`
+}
+
+// DumpOptions configures how an abstract dump is loaded.
+type DumpOptions struct {
+	// ExcludedNamespaces are the title prefixes of non-article pages, which
+	// are dropped. Nil keeps every page.
+	ExcludedNamespaces []string
+	// Links keeps each document's section links, so their anchor text is
+	// indexed as the "anchors" field.
+	Links bool
+}
+
+// DefaultDumpOptions drop the usual non-article pages.
+var DefaultDumpOptions = DumpOptions{
+	ExcludedNamespaces: []string{
+		"Wikipedia:", "Category:", "Template:", "Portal:", "File:",
+		"Help:", "Draft:", "Module:", "MediaWiki:", "TimedText:",
+	},
+}
+
+func LoadDocuments(path string, opts DumpOptions) ([]Document, error) {
+	var docs []Document
+	err := StreamDocuments(path, opts, func(doc Document) error {
+		docs = append(docs, doc)
+		return nil
+	})
+	return docs, err
+}
+
+// StreamDocuments decodes the gzipped abstract dump at path, calling fn with
+// each document in turn, so the dump never has to fit in memory. It stops
+// at the first error fn returns and returns it.
+func StreamDocuments(path string, opts DumpOptions, fn func(Document) error) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return err
+	}
+	defer gz.Close()
+
+	return StreamDocumentsReader(gz, opts, fn)
+}
+
+// titlePrefix starts every title in the enwiki abstract dumps.
+const titlePrefix = "Wikipedia: "
+
+// articleTitle strips the dump's "Wikipedia: " marker from title and reports
+// whether what's left is an article rather than a page in one of the
+// excluded namespaces.
+func (opts DumpOptions) articleTitle(title string) (string, bool) {
+	title = strings.TrimPrefix(title, titlePrefix)
+	for _, ns := range opts.ExcludedNamespaces {
+		if strings.HasPrefix(title, ns) {
+			return title, false
+		}
+	}
+	return title, true
+}
+
+// LoadDocumentsReader decodes an abstract dump from r. Any decompression is
+// left to the caller.
+func LoadDocumentsReader(r io.Reader, opts DumpOptions) ([]Document, error) {
+	var docs []Document
+	err := StreamDocumentsReader(r, opts, func(doc Document) error {
+		docs = append(docs, doc)
+		return nil
+	})
+	return docs, err
+}
+
+// StreamDocumentsReader decodes an abstract dump from r one <doc> element
+// at a time, calling fn with each article. Documents are numbered in the
+// order they are read, skipping the pages that are left out.
+func StreamDocumentsReader(r io.Reader, opts DumpOptions, fn func(Document) error) error {
+	decoder := xml.NewDecoder(r)
+	id := 0
+	for {
+		tok, err := decoder.Token()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		start, ok := tok.(xml.StartElement)
+		if !ok || start.Name.Local != "doc" {
+			continue
+		}
+		var doc Document
+		if err := decoder.DecodeElement(&doc, &start); err != nil {
+			return err
+		}
+
+		title, ok := opts.articleTitle(doc.Title)
+		if !ok {
+			continue
+		}
+		doc.Title = title
+		if !opts.Links {
+			doc.Links = nil
+		}
+		h := sha1.New()
+		io.WriteString(h, doc.URL)
+		doc.URLSHA1 = h.Sum(nil)
+		doc.ID = id
+		id++
+
+		//file, _ := xml.MarshalIndent(doc, "", " ")
+		//_ = ioutil.WriteFile(fmt.Sprintf("docs/%d.xml", doc.ID), file, 0644)
+		if err := fn(doc); err != nil {
+			return err
+		}
+	}
+}
+
+// DocumentKey returns the stable key of doc: the hex SHA-1 of its URL, as
+// in URLSHA1, which stays the same when a newer dump numbers documents
+// differently. Documents without a URL have no key.
+func DocumentKey(doc Document) string {
+	if len(doc.URLSHA1) > 0 {
+		return hex.EncodeToString(doc.URLSHA1)
+	}
+	return urlKey(doc.URL)
+}
+
+// urlKey returns the key of the document at url, or "" for no url.
+func urlKey(url string) string {
+	if url == "" {
+		return ""
+	}
+	sum := sha1.Sum([]byte(url))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,181 @@
+package fulltextsearch
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+const testDump = `<feed>
+<doc>
+<title>Wikipedia: Anarchism</title>
+<url>https://en.wikipedia.org/wiki/Anarchism</url>
+<abstract>Anarchism is a political philosophy.</abstract>
+<links>
+<sublink linktype="nav"><anchor>History</anchor><link>https://en.wikipedia.org/wiki/Anarchism#History</link></sublink>
+</links>
+</doc>
+<doc>
+<title>Wikipedia: Autism</title>
+<url>https://en.wikipedia.org/wiki/Autism</url>
+<abstract>Autism is a neurodevelopmental condition.</abstract>
+</doc>
+</feed>`
+
+func TestLoadDocumentsReader(t *testing.T) {
+	docs, err := LoadDocumentsReader(strings.NewReader(testDump), DefaultDumpOptions)
+	if err != nil {
+		t.Fatalf("LoadDocumentsReader() error = %v", err)
+	}
+	want := []Document{
+		{ID: 0, Title: "Anarchism", URL: "https://en.wikipedia.org/wiki/Anarchism", Text: "Anarchism is a political philosophy."},
+		{ID: 1, Title: "Autism", URL: "https://en.wikipedia.org/wiki/Autism", Text: "Autism is a neurodevelopmental condition."},
+	}
+	if len(docs) != len(want) {
+		t.Fatalf("LoadDocumentsReader() = %d documents, want %d", len(docs), len(want))
+	}
+	for i, doc := range docs {
+		w := want[i]
+		if doc.ID != w.ID || doc.Title != w.Title || doc.URL != w.URL || doc.Text != w.Text {
+			t.Errorf("document %d = {%d %q %q %q}, want {%d %q %q %q}",
+				i, doc.ID, doc.Title, doc.URL, doc.Text, w.ID, w.Title, w.URL, w.Text)
+		}
+		if got, want := DocumentKey(doc), urlKey(w.URL); got != want {
+			t.Errorf("DocumentKey(document %d) = %s, want %s", i, got, want)
+		}
+	}
+}
+
+func TestLoadDocumentsReaderMalformed(t *testing.T) {
+	if _, err := LoadDocumentsReader(strings.NewReader("<feed><doc><title>x</doc>"), DefaultDumpOptions); err == nil {
+		t.Error("LoadDocumentsReader(malformed XML) error = nil, want an error")
+	}
+}
+
+func TestExcludedNamespaces(t *testing.T) {
+	dump := `<feed>
+<doc><title>Wikipedia: Category:Anarchism</title><url>https://en.wikipedia.org/wiki/Category:Anarchism</url><abstract>A category.</abstract></doc>
+<doc><title>Wikipedia: Anarchism</title><url>https://en.wikipedia.org/wiki/Anarchism</url><abstract>A philosophy.</abstract></doc>
+<doc><title>Wikipedia: Template:Infobox</title><url>https://en.wikipedia.org/wiki/Template:Infobox</url><abstract>A template.</abstract></doc>
+</feed>`
+	tests := []struct {
+		name     string
+		excluded []string
+		want     []string
+	}{
+		{"default", DefaultDumpOptions.ExcludedNamespaces, []string{"Anarchism"}},
+		{"categories only", []string{"Category:"}, []string{"Anarchism", "Template:Infobox"}},
+		{"none", nil, []string{"Category:Anarchism", "Anarchism", "Template:Infobox"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			docs, err := LoadDocumentsReader(strings.NewReader(dump), DumpOptions{ExcludedNamespaces: tt.excluded})
+			if err != nil {
+				t.Fatalf("LoadDocumentsReader() error = %v", err)
+			}
+			var got []string
+			for i, doc := range docs {
+				if doc.ID != i {
+					t.Errorf("document %q ID = %d, want %d", doc.Title, doc.ID, i)
+				}
+				got = append(got, doc.Title)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("titles = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAnchorsSearchable(t *testing.T) {
+	tests := []struct {
+		name      string
+		loadLinks bool
+		want      []int
+	}{
+		{"links loaded", true, []int{0}},
+		{"links dropped", false, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			docs, err := LoadDocumentsReader(strings.NewReader(testDump), DumpOptions{Links: tt.loadLinks})
+			if err != nil {
+				t.Fatal(err)
+			}
+			idx := NewIndex(Config{})
+			idx.Add(docs)
+			if got := idx.Search("anchors:history"); !slices.Equal(got, tt.want) {
+				t.Errorf("Search(anchors:history) = %v, want %v", got, tt.want)
+			}
+			if got := idx.SearchFields("history", FieldSearch{Fields: []string{"anchors"}}); !slices.Equal(got, tt.want) {
+				t.Errorf("SearchFields(history, anchors) = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// writeDump writes dump gzipped to a file, as the abstract dumps are
+// distributed, and returns its path.
+func writeDump(t *testing.T, dump string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "abstract.xml.gz")
+	var b bytes.Buffer
+	zw := gzip.NewWriter(&b)
+	if _, err := zw.Write([]byte(dump)); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, b.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestStreamDocuments(t *testing.T) {
+	path := writeDump(t, testDump)
+
+	var titles []string
+	err := StreamDocuments(path, DefaultDumpOptions, func(doc Document) error {
+		if doc.ID != len(titles) {
+			t.Errorf("document %q ID = %d, want %d", doc.Title, doc.ID, len(titles))
+		}
+		titles = append(titles, doc.Title)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("StreamDocuments() error = %v", err)
+	}
+	if want := []string{"Anarchism", "Autism"}; !slices.Equal(titles, want) {
+		t.Errorf("StreamDocuments() titles = %q, want %q", titles, want)
+	}
+
+	// fn's error stops the stream.
+	errStop := errors.New("stop")
+	calls := 0
+	err = StreamDocuments(path, DefaultDumpOptions, func(Document) error {
+		calls++
+		return errStop
+	})
+	if !errors.Is(err, errStop) || calls != 1 {
+		t.Errorf("StreamDocuments() with fn failing = %v after %d calls, want %v after 1", err, calls, errStop)
+	}
+
+	// The dump must be gzipped.
+	plain := filepath.Join(t.TempDir(), "abstract.xml")
+	if err := os.WriteFile(plain, []byte(testDump), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := StreamDocuments(plain, DefaultDumpOptions, func(Document) error { return nil }); err == nil {
+		t.Error("StreamDocuments(uncompressed dump) succeeded, want an error")
+	}
+	if err := StreamDocuments(filepath.Join(t.TempDir(), "missing.xml.gz"), DefaultDumpOptions, func(Document) error { return nil }); err == nil {
+		t.Error("StreamDocuments(missing file) succeeded, want an error")
+	}
+}
@@ -0,0 +1,224 @@
+package fulltextsearch
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
+	"errors"
+	"slices"
+)
+
+// fieldData is the on-disk form of a field. Terms are written in order,
+// front coded: each as the length of the prefix it shares with the term
+// before it, then the length and bytes of the rest. Neighbouring terms
+// mostly share a prefix, as they would a path through a trie, so this is
+// far smaller than gob spelling out every key of a map.
+//
+// Each posting list is packed into bytes as unsigned varints: the number
+// of documents, the gaps between their ascending IDs, their term
+// frequencies and then, if the field has positions, each document's
+// position count and gaps. Gaps and frequencies are mostly small, so most
+// take a single byte rather than the several gob spends on a full int.
+type fieldData struct {
+	Terms []byte
+	// Postings holds the packed posting list of each term, in the same
+	// order.
+	Postings [][]byte
+	// Lengths packs the number of documents, their ID gaps and their
+	// lengths, in the same way.
+	Lengths      []byte
+	TotalLength  int
+	HasPositions bool
+}
+
+var errCorruptPostings = errors.New("corrupt posting list")
+
+func (f *field) GobEncode() ([]byte, error) {
+	terms := slices.Sorted(f.Postings.keys())
+	d := fieldData{
+		Postings:     make([][]byte, len(terms)),
+		TotalLength:  f.TotalLength,
+		HasPositions: f.Positions != nil,
+	}
+	prev := ""
+	for i, term := range terms {
+		d.Terms = appendFrontCoded(d.Terms, prev, term)
+		prev = term
+
+		ids := f.Postings.get(term)
+		b := binary.AppendUvarint(nil, uint64(len(ids)))
+		b = appendGaps(b, ids)
+		for _, n := range f.Freqs.get(term) {
+			b = binary.AppendUvarint(b, uint64(n))
+		}
+		if f.Positions != nil {
+			for _, pos := range f.Positions.get(term) {
+				b = binary.AppendUvarint(b, uint64(len(pos)))
+				b = appendGaps(b, pos)
+			}
+		}
+		d.Postings[i] = b
+	}
+	ids := slices.Sorted(f.Lengths.keys())
+	d.Lengths = binary.AppendUvarint(nil, uint64(len(ids)))
+	d.Lengths = appendGaps(d.Lengths, ids)
+	for _, id := range ids {
+		d.Lengths = binary.AppendUvarint(d.Lengths, uint64(f.Lengths.get(id)))
+	}
+
+	var buf bytes.Buffer
+	err := gob.NewEncoder(&buf).Encode(d)
+	return buf.Bytes(), err
+}
+
+func (f *field) GobDecode(data []byte) error {
+	var d fieldData
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&d); err != nil {
+		return err
+	}
+	*f = *newField()
+	f.TotalLength = d.TotalLength
+	if d.HasPositions {
+		f.Positions = new(cowMap[string, [][]int])
+	}
+	terms := uvarintReader{b: d.Terms}
+	term := ""
+	for _, b := range d.Postings {
+		term = terms.frontCoded(term)
+		if terms.err != nil {
+			return terms.err
+		}
+		r := uvarintReader{b: b}
+		n := r.count()
+		ids := r.gaps(n)
+		if r.err != nil {
+			return r.err
+		}
+		freqs := make([]int, n)
+		for i := range freqs {
+			freqs[i] = r.next()
+		}
+		if d.HasPositions {
+			pos := make([][]int, n)
+			for i := range pos {
+				if pos[i] = r.gaps(r.count()); r.err != nil {
+					return r.err
+				}
+			}
+			f.Positions.set(term, pos)
+		}
+		if r.err != nil {
+			return r.err
+		}
+		f.Postings.set(term, ids)
+		f.Freqs.set(term, freqs)
+	}
+	r := uvarintReader{b: d.Lengths}
+	ids := r.gaps(r.count())
+	for _, id := range ids {
+		f.Lengths.set(id, r.next())
+	}
+	return r.err
+}
+
+// appendGaps appends the ascending values as varints of the gaps between
+// them, the first relative to zero. A negative first value, which only
+// IDs can be, wraps around to a huge one and back again when read.
+func appendGaps(b []byte, values []int) []byte {
+	prev := 0
+	for _, v := range values {
+		b = binary.AppendUvarint(b, uint64(v-prev))
+		prev = v
+	}
+	return b
+}
+
+// appendFrontCoded appends term, which follows prev in order, as the length
+// of their common prefix and the length and bytes of the rest.
+func appendFrontCoded(b []byte, prev, term string) []byte {
+	shared := 0
+	for shared < len(prev) && shared < len(term) && prev[shared] == term[shared] {
+		shared++
+	}
+	b = binary.AppendUvarint(b, uint64(shared))
+	b = binary.AppendUvarint(b, uint64(len(term)-shared))
+	return append(b, term[shared:]...)
+}
+
+// uvarintReader reads back what the GobEncode methods pack, remembering
+// the first error so callers can check once at the end.
+type uvarintReader struct {
+	b   []byte
+	err error
+}
+
+func (r *uvarintReader) next() int {
+	v := r.wrapped()
+	if v < 0 {
+		r.fail()
+		return 0
+	}
+	return v
+}
+
+// wrapped reads a value that may be negative, as the first of appendGaps
+// may be.
+func (r *uvarintReader) wrapped() int {
+	v, n := binary.Uvarint(r.b)
+	if n <= 0 {
+		r.fail()
+		return 0
+	}
+	r.b = r.b[n:]
+	return int(v)
+}
+
+// count reads the number of values that follow, which can't be more than
+// the bytes left since every value takes at least one, so a corrupt count
+// fails here rather than sizing a huge slice.
+func (r *uvarintReader) count() int {
+	n := r.next()
+	if n > len(r.b) {
+		r.fail()
+		return 0
+	}
+	return n
+}
+
+func (r *uvarintReader) fail() {
+	if r.err == nil {
+		r.err = errCorruptPostings
+	}
+}
+
+// frontCoded reads the term after prev written by appendFrontCoded.
+func (r *uvarintReader) frontCoded(prev string) string {
+	shared, n := r.next(), r.next()
+	if r.err != nil || shared > len(prev) || n > len(r.b) {
+		r.fail()
+		return ""
+	}
+	term := prev[:shared] + string(r.b[:n])
+	r.b = r.b[n:]
+	return term
+}
+
+// gaps reads n values written by appendGaps.
+func (r *uvarintReader) gaps(n int) []int {
+	if r.err != nil || n < 0 || n > len(r.b) {
+		// Every value takes at least a byte.
+		r.fail()
+		return nil
+	}
+	values := make([]int, n)
+	prev := 0
+	for i := range values {
+		if i == 0 {
+			prev = r.wrapped()
+		} else {
+			prev += r.next()
+		}
+		values[i] = prev
+	}
+	return values
+}
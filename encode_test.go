@@ -0,0 +1,71 @@
+package fulltextsearch
+
+import (
+	"path/filepath"
+	"reflect"
+	"slices"
+	"testing"
+)
+
+func TestFieldRoundTrip(t *testing.T) {
+	docs := []Document{
+		{ID: -5, Text: "donut"},
+		{ID: 0, Text: "glass plate, glass bowl and a glass donut"},
+		{ID: 1, Title: "Plates", Text: "a plate on a plate on a plate"},
+		{ID: 200, Text: "donut"},
+		{ID: 1 << 40, Text: "a glass bowl"},
+	}
+	tests := []struct {
+		name string
+		cfg  Config
+	}{
+		{"positions", Config{}},
+		{"no positions", Config{LazyPositions: true, StoreDocuments: true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			idx := NewIndex(tt.cfg)
+			idx.Add(docs)
+			path := filepath.Join(t.TempDir(), "idx")
+			if err := idx.Save(path); err != nil {
+				t.Fatal(err)
+			}
+			loaded := NewIndex(tt.cfg)
+			if err := loaded.Load(path); err != nil {
+				t.Fatal(err)
+			}
+			for name, want := range idx.Fields {
+				got, ok := loaded.Fields[name]
+				if !ok {
+					t.Errorf("field %s missing after Load", name)
+					continue
+				}
+				if !reflect.DeepEqual(got.Postings.toMap(), want.Postings.toMap()) {
+					t.Errorf("field %s: Postings = %v, want %v", name, got.Postings.toMap(), want.Postings.toMap())
+				}
+				if !reflect.DeepEqual(got.Freqs.toMap(), want.Freqs.toMap()) {
+					t.Errorf("field %s: Freqs = %v, want %v", name, got.Freqs.toMap(), want.Freqs.toMap())
+				}
+				if !reflect.DeepEqual(got.Positions.toMap(), want.Positions.toMap()) {
+					t.Errorf("field %s: Positions = %v, want %v", name, got.Positions.toMap(), want.Positions.toMap())
+				}
+				if !reflect.DeepEqual(got.Lengths.toMap(), want.Lengths.toMap()) || got.TotalLength != want.TotalLength {
+					t.Errorf("field %s: Lengths = %v (total %d), want %v (total %d)", name, got.Lengths.toMap(), got.TotalLength, want.Lengths.toMap(), want.TotalLength)
+				}
+			}
+			for _, q := range []string{"glass donut", "plate on a plate", "glass bowl"} {
+				want, err := idx.Phrase(q)
+				if err != nil {
+					t.Fatal(err)
+				}
+				got, err := loaded.Phrase(q)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(want) == 0 || !slices.Equal(got, want) {
+					t.Errorf("Phrase(%q) after Load = %v, want %v", q, got, want)
+				}
+			}
+		})
+	}
+}
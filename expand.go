@@ -0,0 +1,196 @@
+package fulltextsearch
+
+import (
+	"iter"
+	"path"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// defaultMaxExpansions caps how many index terms one wildcard or fuzzy
+// query term may expand to, unless Config.MaxExpansions is set.
+const defaultMaxExpansions = 64
+
+// Expansion is the set of text field terms a wildcard or fuzzy query term
+// stands for.
+type Expansion struct {
+	Terms []string
+	// Truncated is set when more terms matched than the cap allowed, in
+	// which case only the most frequent were kept.
+	Truncated bool
+}
+
+// limitExpansion keeps the most frequent of terms, up to the index's cap.
+func (idx *Index) limitExpansion(f *field, terms []string) Expansion {
+	max := idx.maxExpansions
+	if max <= 0 {
+		max = defaultMaxExpansions
+	}
+	sort.Slice(terms, func(i, j int) bool {
+		a, b := len(f.Postings.get(terms[i])), len(f.Postings.get(terms[j]))
+		if a != b {
+			return a > b
+		}
+		return terms[i] < terms[j]
+	})
+	if len(terms) <= max {
+		return Expansion{Terms: terms}
+	}
+	return Expansion{Terms: terms[:max], Truncated: true}
+}
+
+// termDict is a sorted term dictionary. Terms are kept in maps, which can't
+// be scanned by prefix, so the dictionary is built from one on the first
+// prefix lookup after the index last changed.
+type termDict struct {
+	mu    sync.Mutex
+	terms []string
+}
+
+func (d *termDict) reset() {
+	d.mu.Lock()
+	d.terms = nil
+	d.mu.Unlock()
+}
+
+// sorted returns terms in order, sorting them only if the dictionary was
+// reset since the last call.
+func (d *termDict) sorted(terms iter.Seq[string]) []string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.terms == nil {
+		d.terms = slices.Sorted(terms)
+	}
+	return d.terms
+}
+
+// withPrefix returns the terms of the sorted slice dict starting with
+// prefix.
+func withPrefix(dict []string, prefix string) []string {
+	i := sort.SearchStrings(dict, prefix)
+	j := i
+	for j < len(dict) && strings.HasPrefix(dict[j], prefix) {
+		j++
+	}
+	return dict[i:j]
+}
+
+// inRange returns the terms of the sorted slice dict from from up to but
+// not including to, or to the end if to is empty.
+func inRange(dict []string, from, to string) []string {
+	i := sort.SearchStrings(dict, from)
+	j := len(dict)
+	if to != "" {
+		j = i + sort.SearchStrings(dict[i:], to)
+	}
+	return dict[i:j]
+}
+
+// wildcards are the characters that make a query word a wildcard pattern:
+// * matches any run of characters and ? any single one.
+const wildcards = "*?"
+
+// isWildcard reports whether word is a wildcard pattern, such as hydro*.
+func isWildcard(word string) bool {
+	return strings.ContainsAny(word, wildcards)
+}
+
+// ExpandPrefix returns the text field terms starting with prefix, which is
+// lowercased but not otherwise analyzed.
+func (idx *Index) ExpandPrefix(prefix string) Expansion {
+	return idx.ExpandWildcard(prefix + "*")
+}
+
+// ExpandWildcard returns the text field terms matching pattern, which is
+// lowercased but not otherwise analyzed. Only the terms sharing the
+// pattern's literal prefix are tested, so patterns that start with a
+// wildcard have to scan every term.
+func (idx *Index) ExpandWildcard(pattern string) Expansion {
+	f, ok := idx.Fields["text"]
+	if !ok {
+		return Expansion{}
+	}
+	pattern = strings.ToLower(pattern)
+	prefix := pattern
+	if i := strings.IndexAny(pattern, wildcards); i >= 0 {
+		prefix = pattern[:i]
+	}
+	var terms []string
+	for _, term := range withPrefix(idx.dict.sorted(f.Postings.keys()), prefix) {
+		if ok, _ := path.Match(pattern, term); ok {
+			terms = append(terms, term)
+		}
+	}
+	return idx.limitExpansion(f, terms)
+}
+
+// ExpandRange returns the text field terms from from up to but not
+// including to, or to the last term if to is empty. Both bounds are
+// lowercased but not otherwise analyzed.
+func (idx *Index) ExpandRange(from, to string) Expansion {
+	f, ok := idx.Fields["text"]
+	if !ok {
+		return Expansion{}
+	}
+	dict := idx.dict.sorted(f.Postings.keys())
+	terms := inRange(dict, strings.ToLower(from), strings.ToLower(to))
+	// limitExpansion sorts terms in place, and dict is shared.
+	return idx.limitExpansion(f, slices.Clone(terms))
+}
+
+// fuzzyRe matches a fuzzy query word, as in donutt~ or donutt~1, which
+// matches the index terms within the given edit distance of the word.
+var fuzzyRe = regexp.MustCompile(`^([^~]+)~(\d+)?$`)
+
+// defaultFuzziness is the edit distance of a fuzzy word with no explicit
+// distance.
+const defaultFuzziness = 2
+
+// fuzzyWord returns word without any ~N suffix and the edit distance to
+// match it within: N, or the index's fuzziness for plain words. Zero means
+// the word is matched exactly, as are words the analyzer doesn't reduce to
+// a single term, such as stopwords.
+func (idx *Index) fuzzyWord(word string) (string, float64) {
+	dist := idx.fuzziness
+	if m := fuzzyRe.FindStringSubmatch(word); m != nil {
+		word, dist = m[1], defaultFuzziness
+		if m[2] != "" {
+			n, _ := strconv.Atoi(m[2])
+			dist = float64(n)
+		}
+	}
+	if len(idx.analyzer.AnalyzeQuery(word)) != 1 {
+		return word, 0
+	}
+	return word, dist
+}
+
+// ExpandFuzzy returns the text field terms within maxDist edits of word.
+func (idx *Index) ExpandFuzzy(word string, maxDist float64) Expansion {
+	f, ok := idx.Fields["text"]
+	if !ok {
+		return Expansion{}
+	}
+	var terms []string
+	for _, c := range idx.Corrections(word, maxDist) {
+		terms = append(terms, c.Term)
+	}
+	return idx.limitExpansion(f, terms)
+}
+
+// SearchExpansion returns the documents containing any of e's terms.
+func (idx *Index) SearchExpansion(e Expansion) []int {
+	f, ok := idx.Fields["text"]
+	if !ok {
+		return nil
+	}
+	var r []int
+	for _, term := range e.Terms {
+		r = union(r, f.Postings.get(term))
+	}
+	return idx.live(r)
+}
@@ -0,0 +1,91 @@
+package fulltextsearch
+
+import (
+	"path/filepath"
+	"slices"
+	"strconv"
+	"testing"
+)
+
+func TestExpansionCap(t *testing.T) {
+	// word0 .. word9, where wordN is in the first N+1 documents
+	var docs []Document
+	for i := range 10 {
+		text := "zebra"
+		for j := i; j < 10; j++ {
+			text += " word" + strconv.Itoa(j)
+		}
+		docs = append(docs, Document{ID: i, Text: text})
+	}
+	idx := NewIndex(Config{MaxExpansions: 3})
+	idx.Add(docs)
+	tests := []struct {
+		name          string
+		expand        func() Expansion
+		want          []string
+		wantTruncated bool
+	}{
+		{"broad prefix", func() Expansion { return idx.ExpandPrefix("word") }, []string{"word9", "word8", "word7"}, true},
+		{"narrow prefix", func() Expansion { return idx.ExpandPrefix("zeb") }, []string{"zebra"}, false},
+		{"broad wildcard", func() Expansion { return idx.ExpandWildcard("w?rd*") }, []string{"word9", "word8", "word7"}, true},
+		{"broad fuzzy", func() Expansion { return idx.ExpandFuzzy("word", 1) }, []string{"word9", "word8", "word7"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.expand()
+			if !slices.Equal(got.Terms, tt.want) || got.Truncated != tt.wantTruncated {
+				t.Errorf("expansion = %q (truncated %v), want %q (truncated %v)", got.Terms, got.Truncated, tt.want, tt.wantTruncated)
+			}
+		})
+	}
+}
+
+func TestExpandRange(t *testing.T) {
+	// Terms sharing prefixes of every length, which Save front codes.
+	terms := []string{"pla", "plat", "plate", "plated", "plates", "platform", "play", "café", "zebra"}
+	idx := NewIndex(Config{})
+	for i, term := range terms {
+		idx.AddTokenized(i, []string{term})
+	}
+	path := filepath.Join(t.TempDir(), "idx")
+	if err := idx.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	loaded := NewIndex(Config{})
+	if err := loaded.Load(path); err != nil {
+		t.Fatal(err)
+	}
+	if got := slices.Sorted(loaded.Fields["text"].Postings.keys()); !slices.Equal(got, slices.Sorted(slices.Values(terms))) {
+		t.Errorf("terms after Load = %q, want %q", got, terms)
+	}
+
+	tests := []struct {
+		name     string
+		from, to string
+		want     []string
+	}{
+		{"everything", "", "", []string{"café", "pla", "plat", "plate", "plated", "plates", "platform", "play", "zebra"}},
+		{"from is inclusive", "plate", "", []string{"plate", "plated", "plates", "platform", "play", "zebra"}},
+		{"to is exclusive", "pla", "plate", []string{"pla", "plat"}},
+		{"bounds between terms", "plab", "platf", []string{"plat", "plate", "plated", "plates"}},
+		{"bounds lowercased", "PLATE", "PLATES", []string{"plate", "plated"}},
+		{"from equals to", "plate", "plate", nil},
+		{"from after to", "zebra", "pla", nil},
+		{"past the last term", "zz", "", nil},
+		{"before the first term", "", "a", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := idx.ExpandRange(tt.from, tt.to).Terms
+			slices.Sort(got)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("ExpandRange(%q, %q) = %q, want %q", tt.from, tt.to, got, tt.want)
+			}
+			got = loaded.ExpandRange(tt.from, tt.to).Terms
+			slices.Sort(got)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("ExpandRange(%q, %q) after Load = %q, want %q", tt.from, tt.to, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,62 @@
+package fulltextsearch
+
+import (
+	"fmt"
+	"strings"
+)
+
+// TermExplanation shows what the analyzer made of one word of a query,
+// which makes stemming surprises like "mice" not matching "mouse" visible.
+type TermExplanation struct {
+	Original string
+	// Terms are the analyzed forms looked up in the index. It's empty when
+	// the analyzer dropped the word, e.g. as a stopword.
+	Terms []string
+	// Docs is how many documents the word matches on its own, as Search
+	// matches it: in any of the default fields, each analyzing it in its
+	// own way, so Terms are only its analyzed form in the text.
+	Docs int
+	// InDoc reports, for WhyNoMatch, whether the document's text contains
+	// Terms.
+	InDoc bool
+}
+
+func (e TermExplanation) String() string {
+	if len(e.Terms) == 0 {
+		return fmt.Sprintf("%s -> (dropped)", e.Original)
+	}
+	return fmt.Sprintf("%s -> %s (%d docs)", e.Original, strings.Join(e.Terms, " "), e.Docs)
+}
+
+// Explain analyzes query one word at a time.
+func (idx *Index) Explain(query string) []TermExplanation {
+	words := tokenize(query)
+	r := make([]TermExplanation, len(words))
+	for i, word := range words {
+		r[i].Original = word
+		r[i].Terms = idx.analyzer.AnalyzeQuery(word)
+		if len(r[i].Terms) > 0 {
+			r[i].Docs = len(idx.live(idx.matchTerms(word, And, nil)))
+		}
+	}
+	return r
+}
+
+// WhyNoMatch explains query against document id, flagging which words the
+// document contains.
+func (idx *Index) WhyNoMatch(query string, id int) []TermExplanation {
+	r := idx.Explain(query)
+	f := idx.Fields["text"]
+	for i := range r {
+		if f == nil || len(r[i].Terms) == 0 {
+			continue
+		}
+		r[i].InDoc = true
+		for _, term := range r[i].Terms {
+			if f.freq(term, id) == 0 {
+				r[i].InDoc = false
+			}
+		}
+	}
+	return r
+}
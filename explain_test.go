@@ -0,0 +1,44 @@
+package fulltextsearch
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestExplain(t *testing.T) {
+	idx := NewIndex(Config{})
+	idx.Add([]Document{
+		{ID: 0, Text: "a mouse in the house"},
+		{ID: 1, Text: "two mice"},
+		{ID: 2, Text: "a field mouse"},
+	})
+	got := idx.Explain("mice mouse the")
+	want := []TermExplanation{
+		{Original: "mice", Terms: []string{"mice"}, Docs: 1},
+		{Original: "mouse", Terms: []string{"mous"}, Docs: 2},
+		{Original: "the"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("Explain() = %v, want %v", got, want)
+	}
+	for i := range got {
+		if got[i].Original != want[i].Original || !slices.Equal(got[i].Terms, want[i].Terms) || got[i].Docs != want[i].Docs {
+			t.Errorf("Explain()[%d] = %v, want %v", i, got[i], want[i])
+		}
+	}
+	if s := got[0].String(); s != "mice -> mice (1 docs)" {
+		t.Errorf("String() = %q, want %q", s, "mice -> mice (1 docs)")
+	}
+	if s := got[2].String(); s != "the -> (dropped)" {
+		t.Errorf("String() = %q, want %q", s, "the -> (dropped)")
+	}
+}
+
+func TestWhyNoMatch(t *testing.T) {
+	idx := NewIndex(Config{})
+	idx.Add([]Document{{ID: 0, Text: "a mouse in the house"}})
+	got := idx.WhyNoMatch("mice house", 0)
+	if len(got) != 2 || got[0].InDoc || !got[1].InDoc {
+		t.Errorf("WhyNoMatch(mice house) = %+v, want mice not in the document and house in it", got)
+	}
+}
@@ -0,0 +1,247 @@
+package fulltextsearch
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// rssItem is an item of an RSS 2.0 or RSS 1.0 feed.
+type rssItem struct {
+	Title       string   `xml:"title"`
+	Link        string   `xml:"link"`
+	GUID        string   `xml:"guid"`
+	Description string   `xml:"description"`
+	PubDate     string   `xml:"pubDate"`
+	DCDate      string   `xml:"http://purl.org/dc/elements/1.1/ date"`
+	Categories  []string `xml:"category"`
+}
+
+// atomEntry is an entry of an Atom feed.
+type atomEntry struct {
+	Title string `xml:"title"`
+	Links []struct {
+		Href string `xml:"href,attr"`
+		Rel  string `xml:"rel,attr"`
+	} `xml:"link"`
+	ID         string `xml:"id"`
+	Summary    string `xml:"summary"`
+	Content    string `xml:"content"`
+	Published  string `xml:"published"`
+	Updated    string `xml:"updated"`
+	Categories []struct {
+		Term string `xml:"term,attr"`
+	} `xml:"category"`
+}
+
+// feedDateLayouts are the layouts of feed dates: RSS uses RFC 822 dates,
+// often with a single-digit day, and Atom RFC 3339 ones.
+var feedDateLayouts = []string{
+	time.RFC1123Z, time.RFC1123, "Mon, 2 Jan 2006 15:04:05 -0700",
+	"Mon, 2 Jan 2006 15:04:05 MST", time.RFC3339,
+}
+
+func parseFeedDate(s string) time.Time {
+	s = strings.TrimSpace(s)
+	for _, layout := range feedDateLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+var errNotFeed = errors.New("not an RSS or Atom feed")
+
+// ParseFeed reads the items of an RSS or Atom feed from r as documents,
+// numbered in order: the title, the link as URL, the summary as text and
+// the categories as tags. Summaries are usually HTML, which is reduced to
+// its text. An item without a link takes its GUID or ID as URL instead. A
+// feed with no items has no documents, but anything without an <rss>,
+// <rdf:RDF> or Atom <feed> element, such as a web page, is an error.
+func ParseFeed(r io.Reader) ([]Document, error) {
+	decoder := xml.NewDecoder(r)
+	decoder.Strict = false
+	decoder.Entity = xml.HTMLEntity
+	var docs []Document
+	var isFeed bool
+	for {
+		tok, err := decoder.Token()
+		if err == io.EOF {
+			if !isFeed {
+				return nil, errNotFeed
+			}
+			return docs, nil
+		} else if err != nil {
+			return docs, err
+		}
+		start, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		var doc Document
+		switch start.Name.Local {
+		case "rss", "RDF", "feed":
+			isFeed = true
+			continue
+		case "item":
+			var item rssItem
+			if err := decoder.DecodeElement(&item, &start); err != nil {
+				return docs, err
+			}
+			doc = item.document()
+		case "entry":
+			var entry atomEntry
+			if err := decoder.DecodeElement(&entry, &start); err != nil {
+				return docs, err
+			}
+			doc = entry.document()
+		default:
+			continue
+		}
+		doc.ID = len(docs)
+		if doc.URL != "" {
+			sum := sha1.Sum([]byte(doc.URL))
+			doc.URLSHA1 = sum[:]
+		}
+		docs = append(docs, doc)
+	}
+}
+
+func (item rssItem) document() Document {
+	doc := Document{
+		Title: collapseSpace(item.Title),
+		URL:   strings.TrimSpace(item.Link),
+		Text:  htmlText(item.Description),
+		Date:  parseFeedDate(item.PubDate),
+		Tags:  item.Categories,
+	}
+	if doc.URL == "" {
+		doc.URL = strings.TrimSpace(item.GUID)
+	}
+	if doc.Date.IsZero() {
+		doc.Date = parseFeedDate(item.DCDate)
+	}
+	return doc
+}
+
+func (entry atomEntry) document() Document {
+	doc := Document{
+		Title: collapseSpace(entry.Title),
+		Text:  htmlText(entry.Summary),
+		Date:  parseFeedDate(entry.Published),
+	}
+	for _, link := range entry.Links {
+		if link.Rel == "" || link.Rel == "alternate" {
+			doc.URL = strings.TrimSpace(link.Href)
+			break
+		}
+	}
+	if doc.URL == "" {
+		doc.URL = strings.TrimSpace(entry.ID)
+	}
+	if doc.Text == "" {
+		doc.Text = htmlText(entry.Content)
+	}
+	if doc.Date.IsZero() {
+		doc.Date = parseFeedDate(entry.Updated)
+	}
+	for _, c := range entry.Categories {
+		doc.Tags = append(doc.Tags, c.Term)
+	}
+	return doc
+}
+
+// htmlText returns the visible text of an HTML fragment.
+func htmlText(s string) string {
+	doc, err := ParseHTML(strings.NewReader(s))
+	if err != nil {
+		return collapseSpace(s)
+	}
+	return doc.Text
+}
+
+// FetchFeed fetches the RSS or Atom feed at url and reads its items, as
+// ParseFeed does.
+func FetchFeed(ctx context.Context, url string) ([]Document, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetching %s: %s", url, resp.Status)
+	}
+	docs, err := ParseFeed(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("reading feed %s: %w", url, err)
+	}
+	return docs, nil
+}
+
+// AddFeed fetches the feed at url and indexes the items that aren't in the
+// index yet, returning how many there were. Items are recognised by their
+// URLs, as DocumentKey does, so calling it now and then, say on every run
+// of a program that saves the index in between, only picks up new items.
+func (idx *Index) AddFeed(ctx context.Context, url string) (int, error) {
+	docs, err := FetchFeed(ctx, url)
+	if err != nil {
+		return 0, err
+	}
+	return idx.addNew(docs), nil
+}
+
+// addNew indexes the documents whose keys aren't in the index, numbering
+// them from NextID, and returns how many there were.
+func (idx *Index) addNew(docs []Document) int {
+	var fresh []Document
+	seen := make(map[string]bool)
+	for _, doc := range docs {
+		key := DocumentKey(doc)
+		if _, ok := idx.Keys.lookup(key); ok || seen[key] {
+			continue
+		}
+		if key != "" {
+			seen[key] = true
+		}
+		fresh = append(fresh, doc)
+	}
+	idx.AssignIDs(fresh)
+	idx.Add(fresh)
+	return len(fresh)
+}
+
+// PollFeed fetches the feed at url every interval until ctx is done,
+// indexing the new items as AddFeed does and calling report with how many
+// there were or with the error fetching them. The index is only locked
+// while the items are added.
+func (s *SyncIndex) PollFeed(ctx context.Context, url string, interval time.Duration, report func(added int, err error)) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		docs, err := FetchFeed(ctx, url)
+		added := 0
+		if err == nil {
+			s.Write(func(idx *Index) { added = idx.addNew(docs) })
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		report(added, err)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
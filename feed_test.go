@@ -0,0 +1,135 @@
+package fulltextsearch
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testRSS = `<?xml version="1.0"?>
+<rss version="2.0"><channel><title>Bakery</title>
+<item>
+<title>Glazed  donuts</title>
+<link>https://example.com/donuts</link>
+<description>&lt;p&gt;A &lt;b&gt;glazed&lt;/b&gt; donut&amp;nbsp;recipe&lt;/p&gt;</description>
+<pubDate>Fri, 1 Mar 2024 10:00:00 +0000</pubDate>
+<category>sweet</category><category>fried</category>
+</item>
+<item>
+<title>Bagels</title>
+<guid>https://example.com/bagels</guid>
+<description>Toasted bagels</description>
+</item>
+</channel></rss>`
+
+const testAtom = `<?xml version="1.0" encoding="utf-8"?>
+<feed xmlns="http://www.w3.org/2005/Atom"><title>Bakery</title>
+<entry>
+<title>Glazed donuts</title>
+<link rel="self" href="https://example.com/feed/donuts"/>
+<link href="https://example.com/donuts"/>
+<id>tag:example.com,2024:donuts</id>
+<content type="html">&lt;p&gt;A glazed donut recipe&lt;/p&gt;</content>
+<updated>2024-03-01T10:00:00Z</updated>
+<category term="sweet"/>
+</entry>
+</feed>`
+
+func TestParseFeed(t *testing.T) {
+	date := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name string
+		feed string
+		want []Document
+	}{
+		{"rss", testRSS, []Document{
+			{ID: 0, Title: "Glazed donuts", URL: "https://example.com/donuts", Text: "A glazed donut recipe", Date: date, Tags: []string{"sweet", "fried"}},
+			{ID: 1, Title: "Bagels", URL: "https://example.com/bagels", Text: "Toasted bagels"},
+		}},
+		{"atom", testAtom, []Document{
+			{ID: 0, Title: "Glazed donuts", URL: "https://example.com/donuts", Text: "A glazed donut recipe", Date: date, Tags: []string{"sweet"}},
+		}},
+		{"rss with no items", `<rss version="2.0"><channel><title>Bakery</title></channel></rss>`, nil},
+		{"atom with no entries", `<feed xmlns="http://www.w3.org/2005/Atom"><title>Bakery</title></feed>`, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			docs, err := ParseFeed(strings.NewReader(tt.feed))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(docs) != len(tt.want) {
+				t.Fatalf("ParseFeed() = %d documents, want %d", len(docs), len(tt.want))
+			}
+			for i, doc := range docs {
+				w := tt.want[i]
+				if doc.ID != w.ID || doc.Title != w.Title || doc.URL != w.URL || doc.Text != w.Text ||
+					!doc.Date.Equal(w.Date) || !slices.Equal(doc.Tags, w.Tags) {
+					t.Errorf("document %d = %+v, want %+v", i, doc, w)
+				}
+			}
+		})
+	}
+}
+
+func TestParseFeedMalformed(t *testing.T) {
+	tests := []struct {
+		name string
+		feed string
+	}{
+		{"web page", `<html><body><p>Not a feed</p></body></html>`},
+		{"empty", ``},
+		{"truncated", testRSS[:len(testRSS)/2]},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseFeed(strings.NewReader(tt.feed)); err == nil {
+				t.Error("ParseFeed() error = nil, want an error")
+			}
+		})
+	}
+}
+
+func TestAddFeed(t *testing.T) {
+	feed := testRSS
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/feed" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(feed))
+	}))
+	defer srv.Close()
+
+	idx := NewIndex(Config{})
+	ctx := context.Background()
+	for i, want := range []int{2, 0} {
+		added, err := idx.AddFeed(ctx, srv.URL+"/feed")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if added != want {
+			t.Errorf("AddFeed() call %d added %d items, want %d", i+1, added, want)
+		}
+	}
+	feed = testAtom
+	if added, err := idx.AddFeed(ctx, srv.URL+"/feed"); err != nil || added != 0 {
+		t.Errorf("AddFeed() of the same items in Atom = %d, %v, want 0, nil", added, err)
+	}
+	if got, want := idx.Search("glazed"), []int{0}; !slices.Equal(got, want) {
+		t.Errorf("Search(glazed) = %v, want %v", got, want)
+	}
+
+	if _, err := idx.AddFeed(ctx, srv.URL+"/missing"); err == nil {
+		t.Error("AddFeed() of a missing feed error = nil, want an error")
+	}
+	feed = `<html><body>Moved</body></html>`
+	if _, err := idx.AddFeed(ctx, srv.URL+"/feed"); !errors.Is(err, errNotFeed) {
+		t.Errorf("AddFeed() of a web page = %v, want %v", err, errNotFeed)
+	}
+}
@@ -0,0 +1,71 @@
+package fulltextsearch
+
+import (
+	"net/url"
+	"strings"
+)
+
+// FieldSearch configures SearchFields.
+type FieldSearch struct {
+	// Fields are searched in this order.
+	Fields []string
+	// Fallback stops at the first field that has any matches, so later
+	// fields are only consulted when earlier ones come up empty. Otherwise
+	// the matches in every field are combined.
+	Fallback bool
+}
+
+// DefaultFieldSearch searches titles, then falls back to the text and tags.
+var DefaultFieldSearch = FieldSearch{
+	Fields:   []string{"title", "text", tagField},
+	Fallback: true,
+}
+
+// SearchFields returns the documents in which some one of fs.Fields contains
+// every query term.
+func (idx *Index) SearchFields(query string, fs FieldSearch) []int {
+	var r []int
+	for _, name := range fs.Fields {
+		ids := idx.live(idx.matchField(name, query, idx.DefaultOperator, nil))
+		if fs.Fallback && len(ids) > 0 {
+			return ids
+		}
+		r = union(r, ids)
+	}
+	return r
+}
+
+// pathField holds the words of each document's URL path, when the index is
+// configured with URLPaths.
+const pathField = "path"
+
+// urlPath returns the path of rawURL with its separators and escapes
+// spelled out as spaces, so "https://en.wikipedia.org/wiki/New_York" gives
+// "wiki New York". The host is left out since most documents share it.
+func urlPath(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return strings.Join(strings.FieldsFunc(u.Path, func(r rune) bool {
+		return r == '/' || r == '_' || r == '-'
+	}), " ")
+}
+
+// defaultFieldWeights returns the weights ranked searches multiply field
+// scores by unless Config.FieldWeights says otherwise. A title match says
+// more about what a page is about than one in its text.
+func defaultFieldWeights() map[string]float64 {
+	return map[string]float64{
+		"title":   2,
+		pathField: 1.5,
+	}
+}
+
+// weight returns the weight of the named field's score, 1 by default.
+func (idx *Index) weight(name string) float64 {
+	if w, ok := idx.fieldWeights[name]; ok {
+		return w
+	}
+	return 1
+}
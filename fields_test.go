@@ -0,0 +1,133 @@
+package fulltextsearch
+
+import (
+	"maps"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestSearchFieldsFallback(t *testing.T) {
+	idx := NewIndex(Config{})
+	idx.Add([]Document{
+		{ID: 0, Title: "Volcano", Text: "a mountain that erupts"},
+		{ID: 1, Title: "Mount Etna", Text: "a volcano in Sicily"},
+		{ID: 2, Title: "Lava", Text: "molten rock", Tags: []string{"geology"}},
+	})
+	both := FieldSearch{Fields: []string{"title", "text"}}
+	tests := []struct {
+		name  string
+		query string
+		fs    FieldSearch
+		want  []int
+	}{
+		{"title matches", "volcano", DefaultFieldSearch, []int{0}},
+		{"falls back to the text", "sicily", DefaultFieldSearch, []int{1}},
+		{"falls back to the tags", "geology", DefaultFieldSearch, []int{2}},
+		{"nothing anywhere", "glacier", DefaultFieldSearch, nil},
+		{"without fallback", "volcano", both, []int{0, 1}},
+		{"text first", "volcano", FieldSearch{Fields: []string{"text", "title"}, Fallback: true}, []int{1}},
+		{"every term in one field", "mount sicily", both, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := idx.SearchFields(tt.query, tt.fs); !slices.Equal(got, tt.want) {
+				t.Errorf("SearchFields(%s, %v) = %v, want %v", tt.query, tt.fs, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFieldAnalyzers(t *testing.T) {
+	docs := []Document{
+		{ID: 0, Title: "Running", Text: "a sport", URL: "https://example.com/wiki/Running"},
+		{ID: 1, Title: "Sport", Text: "people running", URL: "https://example.com/wiki/Sport"},
+	}
+	cfg := Config{
+		FieldAnalyzers: map[string]string{"title": "nostem", "text": "keyword"},
+		ExactFields:    []string{"url"},
+	}
+	idx := NewIndex(cfg)
+	idx.Add(docs)
+
+	tests := []struct {
+		query string
+		want  []int
+	}{
+		// The title isn't stemmed; the text, whose analyzer can't be
+		// changed, is.
+		{"running", []int{0, 1}},
+		{"run", []int{1}},
+		{"title:running", []int{0}},
+		{"title:run", nil},
+		{"text:run", []int{1}},
+		{`url:"https://example.com/wiki/Sport"`, []int{1}},
+		{"url:sport", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			if got := idx.Search(tt.query); !slices.Equal(got, tt.want) {
+				t.Errorf("Search(%s) = %v, want %v", tt.query, got, tt.want)
+			}
+		})
+	}
+
+	// The analyzers are saved with the index.
+	path := filepath.Join(t.TempDir(), "index")
+	if err := idx.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	loaded := NewIndex(Config{})
+	if err := loaded.Load(path); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"title": "nostem", "url": "keyword"}
+	if !maps.Equal(loaded.FieldAnalyzers, want) {
+		t.Errorf("FieldAnalyzers after Load = %v, want %v", loaded.FieldAnalyzers, want)
+	}
+	for _, tt := range tests {
+		if got := loaded.Search(tt.query); !slices.Equal(got, tt.want) {
+			t.Errorf("after Load, Search(%s) = %v, want %v", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestURLPath(t *testing.T) {
+	tests := []struct {
+		url, want string
+	}{
+		{"https://en.wikipedia.org/wiki/New_York", "wiki New York"},
+		{"https://en.wikipedia.org/wiki/Caf%C3%A9", "wiki Café"},
+		{"https://example.com/a-b/c_d/?q=x#top", "a b c d"},
+		{"https://example.com", ""},
+		{"%zz", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			if got := urlPath(tt.url); got != tt.want {
+				t.Errorf("urlPath(%q) = %q, want %q", tt.url, got, tt.want)
+			}
+		})
+	}
+
+	docs := []Document{
+		{ID: 0, Title: "The Big Apple", Text: "a city", URL: "https://en.wikipedia.org/wiki/New_York"},
+		{ID: 1, Title: "Jersey", Text: "an island", URL: "https://en.wikipedia.org/wiki/Jersey"},
+	}
+	for _, paths := range []bool{false, true} {
+		idx := NewIndex(Config{URLPaths: paths})
+		idx.Add(docs)
+		var want []int
+		if paths {
+			want = []int{0}
+		}
+		for _, query := range []string{"york", "path:york", "new york"} {
+			if got := idx.Search(query); !slices.Equal(got, want) {
+				t.Errorf("with URLPaths %v, Search(%s) = %v, want %v", paths, query, got, want)
+			}
+		}
+		if got := idx.Search("jersey"); !slices.Equal(got, []int{1}) {
+			t.Errorf("with URLPaths %v, Search(jersey) = %v, want [1]", paths, got)
+		}
+	}
+}
@@ -0,0 +1,200 @@
+package fulltextsearch
+
+import (
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Query terms of the form _exists_:field and _missing_:field restrict
+// results to documents that have, or lack, a non-empty value for field.
+const (
+	existsPrefix  = "_exists_:"
+	missingPrefix = "_missing_:"
+)
+
+type existsFilter struct {
+	field  string
+	exists bool
+}
+
+// tagField is the multi-valued exact keyword field holding document tags.
+const tagField = "tag"
+
+// exactTerm is a field:value query term for an exact keyword field, matched
+// verbatim.
+type exactTerm struct {
+	field string
+	value string
+}
+
+// exactValueRe matches a quoted value of a field, as in title:"New York",
+// which for an exact field is matched whole, spaces and all.
+var exactValueRe = regexp.MustCompile(`(\S+):"([^"]*)"`)
+
+// scopedTerm is a field:word query term, as in title:anarchism, for an
+// analyzed field. The word is only looked for in that field.
+type scopedTerm struct {
+	field string
+	word  string
+}
+
+// excludePrefix marks a query word, as in "cat -wild", whose documents are
+// removed from the results.
+const excludePrefix = "-"
+
+// phraseRe matches a quoted phrase, as in "small wild cat", whose terms
+// must occur next to each other and in order. A trailing ~N, as in
+// "wild cat"~5, relaxes it to a proximity query: the terms must all occur,
+// in any order, with at most N other positions among them, as PhraseRank's
+// Slop allows.
+var phraseRe = regexp.MustCompile(`"([^"]*)"(?:~(\d+))?`)
+
+// phraseQuery is a quoted phrase of a query. Slop is zero for an exact
+// phrase.
+type phraseQuery struct {
+	text string
+	slop int
+}
+
+// queryFilters are the parts of a query that narrow the matches rather than
+// adding terms to match and score.
+type queryFilters struct {
+	exists   []existsFilter
+	exact    []exactTerm
+	excluded []string // words, before analysis
+	phrases  []phraseQuery
+	expanded []Expansion // wildcard and fuzzy words; a document needs one of each
+	patterns []string    // the words expanded, in the order of expanded
+	scoped   []scopedTerm
+}
+
+func (q queryFilters) empty() bool {
+	return len(q.exists) == 0 && len(q.exact) == 0 && len(q.excluded) == 0 &&
+		len(q.phrases) == 0 && len(q.expanded) == 0 && len(q.scoped) == 0
+}
+
+// presence returns the names of the fields doc has a value for.
+func (doc Document) presence() []string {
+	var r []string
+	for name, text := range doc.fields() {
+		if text != "" {
+			r = append(r, name)
+		}
+	}
+	if !doc.Date.IsZero() {
+		r = append(r, "date")
+	}
+	if len(doc.Tags) > 0 {
+		r = append(r, tagField)
+	}
+	if doc.Language != "" {
+		r = append(r, langField)
+	}
+	return r
+}
+
+// parseFilters splits the filters out of query, returning the rest of the
+// query text. The words of a phrase stay in the query text as well, so they
+// are matched and scored like any others.
+func (idx *Index) parseFilters(query string) (string, queryFilters) {
+	var rest []string
+	var q queryFilters
+	query = exactValueRe.ReplaceAllStringFunc(query, func(s string) string {
+		m := exactValueRe.FindStringSubmatch(s)
+		if !idx.isExact(m[1]) {
+			return s
+		}
+		q.exact = append(q.exact, exactTerm{field: m[1], value: m[2]})
+		return " "
+	})
+	for _, m := range phraseRe.FindAllStringSubmatch(query, -1) {
+		slop, _ := strconv.Atoi(m[2]) // digits only, or empty for zero
+		q.phrases = append(q.phrases, phraseQuery{text: m[1], slop: slop})
+	}
+	query = phraseRe.ReplaceAllString(query, " $1 ")
+	for _, word := range strings.Fields(query) {
+		if f, ok := strings.CutPrefix(word, existsPrefix); ok {
+			q.exists = append(q.exists, existsFilter{field: f, exists: true})
+		} else if f, ok := strings.CutPrefix(word, missingPrefix); ok {
+			q.exists = append(q.exists, existsFilter{field: f, exists: false})
+		} else if f, v, ok := strings.Cut(word, ":"); ok && idx.isExact(f) {
+			q.exact = append(q.exact, exactTerm{field: f, value: v})
+		} else if f, v, ok := strings.Cut(word, ":"); ok && v != "" && idx.Fields[f] != nil {
+			q.scoped = append(q.scoped, scopedTerm{field: f, word: v})
+		} else if w, ok := strings.CutPrefix(word, excludePrefix); ok && w != "" {
+			q.excluded = append(q.excluded, w)
+		} else if isWildcard(word) {
+			q.expanded = append(q.expanded, idx.ExpandWildcard(word))
+			q.patterns = append(q.patterns, word)
+		} else if w, dist := idx.fuzzyWord(word); dist > 0 {
+			q.expanded = append(q.expanded, idx.ExpandFuzzy(w, dist))
+			q.patterns = append(q.patterns, word)
+		} else {
+			rest = append(rest, w)
+		}
+	}
+	return strings.Join(rest, " "), q
+}
+
+// filter narrows ids by q. Excluded words drop documents that contain them
+// in any field. Phrases that can't be checked, for want of positions, are
+// logged and, if d is non-nil, listed in it.
+func (idx *Index) filter(ids []int, q queryFilters, d *Diagnostics) []int {
+	for _, t := range q.exact {
+		var postings []int
+		if f, ok := idx.Fields[t.field]; ok {
+			postings = f.Postings.get(t.value)
+		}
+		ids = intersection(ids, postings)
+	}
+	for _, f := range q.exists {
+		if f.exists {
+			ids = intersection(ids, idx.Present[f.field])
+		} else {
+			ids = difference(ids, idx.Present[f.field])
+		}
+	}
+	for _, t := range q.scoped {
+		ids = intersection(ids, idx.matchField(t.field, t.word, And, nil))
+	}
+	for _, e := range q.expanded {
+		ids = intersection(ids, idx.SearchExpansion(e))
+	}
+	for _, p := range q.phrases {
+		var err error
+		if ids, err = idx.phraseFilter(ids, p); err != nil {
+			log.Printf("phrase %q matched as separate words: %v", p.text, err)
+			if d != nil {
+				d.UncheckedPhrases = append(d.UncheckedPhrases, p.text)
+			}
+		}
+	}
+	for _, word := range q.excluded {
+		for name, f := range idx.Fields {
+			for _, term := range idx.queryTerms(name, word) {
+				ids = idx.differenceTerm(name, f, term, ids)
+			}
+		}
+	}
+	return ids
+}
+
+// difference returns the IDs in a that are not in b.
+func difference(a []int, b []int) []int {
+	r := make([]int, 0, len(a))
+	var i, j int
+	for i < len(a) && j < len(b) {
+		if a[i] < b[j] {
+			r = append(r, a[i])
+			i++
+		} else if a[i] > b[j] {
+			j++
+		} else {
+			i++
+			j++
+		}
+	}
+	return append(r, a[i:]...)
+}
@@ -0,0 +1,97 @@
+package fulltextsearch
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestExistenceFilters(t *testing.T) {
+	idx := NewIndex(Config{})
+	idx.Add([]Document{
+		{ID: 0, Text: "cat", URL: "https://example.com/cat"},
+		{ID: 1, Text: "cat"},
+		{ID: 2, Text: "dog", URL: "https://example.com/dog", Extra: map[string]string{"author": "Ann"}},
+		{ID: 3, Text: "cat", Extra: map[string]string{"author": ""}},
+	})
+	tests := []struct {
+		query string
+		want  []int
+	}{
+		{"_exists_:url", []int{0, 2}},
+		{"_missing_:url", []int{1, 3}},
+		{"cat _exists_:url", []int{0}},
+		{"cat _missing_:url", []int{1, 3}},
+		{"_exists_:author", []int{2}},
+		{"cat _exists_:author", nil},
+		{"_exists_:url _missing_:author", []int{0}},
+		{"_exists_:nosuchfield", nil},
+		{"cat _missing_:nosuchfield", []int{0, 1, 3}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			if got := idx.Search(tt.query); !slices.Equal(got, tt.want) {
+				t.Errorf("Search(%s) = %v, want %v", tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExactFields(t *testing.T) {
+	idx := NewIndex(Config{ExactFields: []string{"sku"}})
+	idx.Add([]Document{
+		{ID: 0, Text: "blue mug", Extra: map[string]string{"sku": "SKU-12345"}},
+		{ID: 1, Text: "red mug", Extra: map[string]string{"sku": "SKU-123"}},
+		{ID: 2, Text: "mug tree", Extra: map[string]string{"sku": "Large Mug Tree"}},
+	})
+	tests := []struct {
+		query string
+		want  []int
+	}{
+		{"sku:SKU-12345", []int{0}},
+		{"sku:sku-12345", nil},
+		{"sku:SKU-1234", nil},
+		{"mug sku:SKU-123", []int{1}},
+		{`sku:"Large Mug Tree"`, []int{2}},
+		{`sku:"large mug tree"`, nil},
+		{"sku:Large", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			if got := idx.Search(tt.query); !slices.Equal(got, tt.want) {
+				t.Errorf("Search(%s) = %v, want %v", tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTags(t *testing.T) {
+	docs, err := LoadDocumentsReader(strings.NewReader(`<feed>
+<doc><title>Wikipedia: Photosynthesis</title><url>u0</url><abstract>How plants make food.</abstract><tag>science</tag><tag>biology</tag></doc>
+<doc><title>Wikipedia: Baking</title><url>u1</url><abstract>How bread is made.</abstract><tag>food</tag></doc>
+</feed>`), DefaultDumpOptions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	idx := NewIndex(Config{})
+	idx.Add(docs)
+	tests := []struct {
+		query string
+		want  []int
+	}{
+		{"tag:science", []int{0}},
+		{"tag:biology", []int{0}},
+		{"tag:food", []int{1}},
+		{"food tag:science", []int{0}},
+		{"tag:science tag:food", nil},
+		{"tag:Science", nil},
+		{"tag:scien", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			if got := idx.Search(tt.query); !slices.Equal(got, tt.want) {
+				t.Errorf("Search(%s) = %v, want %v", tt.query, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,369 @@
+package fulltextsearch
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha1"
+	"encoding/binary"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"maps"
+	"math"
+	"slices"
+	"strings"
+	"time"
+)
+
+// An index file starts with a header,
+//
+//	indexMagic | format version | analyzer fingerprint
+//
+// the version being a little-endian uint32 and the fingerprint 8 bytes, and
+// then holds a series of sections. Each section is its name, prefixed by
+// its length as a uvarint, the length of its data as a little-endian
+// uint64, the data, which is gob encoded, and the CRC-32 (Castagnoli) of
+// all of that, also little-endian, so a corrupt name is caught as surely as
+// corrupt data. The docs section holds everything about
+// the documents, each field is in a section of its own named fieldSection
+// and the field name, and an empty end section closes the file, so a file
+// cut short between sections is caught too. Snapshots add the doc store in
+// a store section. The whole file may be gzip compressed, which read
+// detects from gzip's own magic bytes. Readers skip sections they
+// don't know, so adding an optional one doesn't need a new version.
+const (
+	indexMagic = "FTSINDEX"
+	// formatVersion is bumped whenever the layout or the encoding of a
+	// section changes so that older readers would misread it.
+	formatVersion = 3
+
+	docsSection  = "docs"
+	fieldSection = "field "
+	storeSection = "store"
+	endSection   = "end"
+	// maxSectionName bounds section names, so a corrupt length can't make
+	// read allocate without limit.
+	maxSectionName = 1 << 12
+)
+
+var (
+	ErrNotIndex         = errors.New("not an index file")
+	ErrFormatVersion    = errors.New("unsupported index format version")
+	ErrAnalyzerMismatch = errors.New("index was built with a different analyzer")
+	// ErrCorruptIndex is returned for index files that fail their checksums
+	// or are cut short, which should be rebuilt.
+	ErrCorruptIndex = errors.New("index file is corrupt")
+)
+
+var crcTable = crc32.MakeTable(crc32.Castagnoli)
+
+// Compression is how an index file is compressed.
+type Compression int
+
+const (
+	NoCompression Compression = iota
+	// Gzip compresses at gzip's fastest level, which gets most of the
+	// savings on posting lists already packed as varints.
+	Gzip
+)
+
+// gzipMagic starts every gzip stream.
+const gzipMagic = "\x1f\x8b"
+
+// indexDocs is the docs section: every saved part of an Index but Fields.
+type indexDocs struct {
+	DefaultOperator Operator
+	IDs             []int
+	Dates           map[int]time.Time
+	Present         map[string][]int
+	Hashes          map[int][sha1.Size]byte
+	Boosts          map[int]float64
+	Titles          map[int]string
+	URLs            map[int]string
+	TitleWords      map[string]int
+	FieldAnalyzers  map[string]string
+	Keys            map[string]int
+	Deleted         []int
+	Forward         map[int][]string
+}
+
+func (idx *Index) docs() indexDocs {
+	return indexDocs{
+		DefaultOperator: idx.DefaultOperator,
+		IDs:             idx.IDs,
+		Dates:           idx.Dates.toMap(),
+		Present:         idx.Present,
+		Hashes:          idx.Hashes.toMap(),
+		Boosts:          idx.Boosts.toMap(),
+		Titles:          idx.Titles.toMap(),
+		URLs:            idx.URLs.toMap(),
+		TitleWords:      idx.TitleWords.toMap(),
+		FieldAnalyzers:  idx.FieldAnalyzers,
+		Keys:            idx.Keys.toMap(),
+		Deleted:         idx.Deleted,
+		Forward:         idx.Forward.toMap(),
+	}
+}
+
+// setDocs sets what the docs section holds. Gob leaves out empty maps, so
+// the index keeps its own, made by NewIndex, for any that are nil.
+func (idx *Index) setDocs(d indexDocs) {
+	idx.DefaultOperator = d.DefaultOperator
+	idx.IDs = d.IDs
+	idx.Dates = newCowMap(d.Dates)
+	idx.Present = orKeep(d.Present, idx.Present)
+	idx.Hashes = newCowMap(d.Hashes)
+	idx.Boosts = newCowMap(d.Boosts)
+	idx.Titles = newCowMap(d.Titles)
+	idx.URLs = newCowMap(d.URLs)
+	idx.TitleWords = newCowMap(d.TitleWords)
+	idx.FieldAnalyzers = orKeep(d.FieldAnalyzers, idx.FieldAnalyzers)
+	idx.Keys = newCowMap(d.Keys)
+	idx.Deleted = d.Deleted
+	if d.Forward != nil {
+		forward := newCowMap(d.Forward)
+		idx.Forward = &forward
+	}
+}
+
+func orKeep[M ~map[K]V, K comparable, V any](m, old M) M {
+	if m == nil {
+		return old
+	}
+	return m
+}
+
+// fingerprintText is analyzed to fingerprint an analyzer. It exercises
+// stemming, stopwords, contractions, accents, numbers and CJK text, so
+// analyzers that would index it differently almost surely have different
+// fingerprints.
+const fingerprintText = "The quick brown fox's jumping over 2 lazy dogs, naïvely running to the café. Don't stop: 東京の地下鉄"
+
+// fingerprint identifies how a analyzes text, from the terms it makes of
+// fingerprintText. Unlike the analyzer's settings, which hold functions
+// and interfaces, the terms can be compared across runs.
+func (a Analyzer) fingerprint() [8]byte {
+	h := sha1.New()
+	for _, term := range a.Analyze(fingerprintText) {
+		io.WriteString(h, term)
+		h.Write([]byte{0})
+	}
+	var fp [8]byte
+	copy(fp[:], h.Sum(nil))
+	return fp
+}
+
+// write writes the index in the format described above, with its doc
+// store, if it has one, if withStore is set.
+func (idx *Index) write(w io.Writer, withStore bool) error {
+	if idx.compression != Gzip {
+		return idx.writeSections(w, withStore)
+	}
+	zw, err := gzip.NewWriterLevel(w, gzip.BestSpeed)
+	if err != nil {
+		return err
+	}
+	if err := idx.writeSections(zw, withStore); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+func (idx *Index) writeSections(w io.Writer, withStore bool) error {
+	bw := bufio.NewWriter(w)
+	header := binary.LittleEndian.AppendUint32([]byte(indexMagic), formatVersion)
+	fp := idx.analyzer.fingerprint()
+	if _, err := bw.Write(append(header, fp[:]...)); err != nil {
+		return err
+	}
+	if err := writeSection(bw, docsSection, idx.docs()); err != nil {
+		return err
+	}
+	for _, name := range slices.Sorted(maps.Keys(idx.Fields)) {
+		if err := writeSection(bw, fieldSection+name, idx.Fields[name]); err != nil {
+			return err
+		}
+	}
+	if withStore && idx.store != nil {
+		if err := writeSection(bw, storeSection, docStore(idx.store.toMap())); err != nil {
+			return err
+		}
+	}
+	if err := writeSection(bw, endSection, nil); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+// writeSection writes v as the named section, or an empty section for nil.
+func writeSection(w io.Writer, name string, v any) error {
+	var buf bytes.Buffer
+	if v != nil {
+		if err := gob.NewEncoder(&buf).Encode(v); err != nil {
+			return fmt.Errorf("encoding section %q: %w", name, err)
+		}
+	}
+	b := binary.AppendUvarint(nil, uint64(len(name)))
+	b = append(b, name...)
+	b = binary.LittleEndian.AppendUint64(b, uint64(buf.Len()))
+	crc := crc32.Update(crc32.Checksum(b, crcTable), crcTable, buf.Bytes())
+	sum := binary.LittleEndian.AppendUint32(nil, crc)
+	for _, p := range [][]byte{b, buf.Bytes(), sum} {
+		if _, err := w.Write(p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// read reads an index written by write, refusing files of another format
+// version or made with another analyzer. Every section's checksum is
+// verified, and the index is only changed once they all have been.
+func (idx *Index) read(r io.Reader) error {
+	br := bufio.NewReader(r)
+	compressed := false
+	if magic, _ := br.Peek(len(gzipMagic)); string(magic) == gzipMagic {
+		zr, err := gzip.NewReader(br)
+		if err != nil {
+			return fmt.Errorf("%w: %w", ErrCorruptIndex, err)
+		}
+		defer zr.Close()
+		br = bufio.NewReader(gzipErrors{zr})
+		compressed = true
+	}
+	if err := idx.readHeader(br); err != nil {
+		// A compressed header is only checksummed at the end of the
+		// stream, so corruption there must be ruled out before the
+		// header is blamed.
+		if compressed {
+			if _, cerr := io.Copy(io.Discard, br); cerr != nil {
+				return cerr
+			}
+		}
+		return err
+	}
+
+	var docs indexDocs
+	var store docStore
+	fields := make(map[string]*field)
+	for {
+		name, data, err := readSection(br)
+		if err == io.EOF {
+			return fmt.Errorf("no end section: %w", ErrCorruptIndex)
+		} else if err != nil {
+			return err
+		}
+		if name == endSection {
+			// Read to the end of any gzip stream, which checks its own
+			// checksum there.
+			if _, err := io.Copy(io.Discard, br); err != nil {
+				return err
+			}
+			break
+		}
+		r := bytes.NewReader(data)
+		switch {
+		case name == docsSection:
+			err = gob.NewDecoder(r).Decode(&docs)
+		case strings.HasPrefix(name, fieldSection):
+			f := new(field)
+			err = gob.NewDecoder(r).Decode(f)
+			fields[strings.TrimPrefix(name, fieldSection)] = f
+		case name == storeSection && idx.store != nil:
+			err = gob.NewDecoder(r).Decode(&store)
+		}
+		if err != nil {
+			return fmt.Errorf("decoding section %q: %w: %w", name, ErrCorruptIndex, err)
+		}
+	}
+	for name, n := range docs.FieldAnalyzers {
+		if _, ok := Analyzers[n]; !ok {
+			return fmt.Errorf("field %s: unknown analyzer %q", name, n)
+		}
+	}
+	idx.setDocs(docs)
+	idx.Fields = fields
+	if store != nil {
+		idx.store = store.cow()
+		idx.storeFile = storeFile{}
+		idx.positions.reset()
+	}
+	return nil
+}
+
+// readHeader reads the header and checks it is for this format version and
+// analyzer.
+func (idx *Index) readHeader(r io.Reader) error {
+	header := make([]byte, len(indexMagic)+4+8)
+	if _, err := io.ReadFull(r, header); err == io.EOF || err == io.ErrUnexpectedEOF {
+		return ErrNotIndex
+	} else if err != nil {
+		return err
+	}
+	if string(header[:len(indexMagic)]) != indexMagic {
+		return ErrNotIndex
+	}
+	header = header[len(indexMagic):]
+	if v := binary.LittleEndian.Uint32(header); v != formatVersion {
+		return fmt.Errorf("%w %d, want %d; rebuild the index", ErrFormatVersion, v, formatVersion)
+	}
+	if [8]byte(header[4:]) != idx.analyzer.fingerprint() {
+		return fmt.Errorf("%w; rebuild the index or load it with the analyzer it was built with", ErrAnalyzerMismatch)
+	}
+	return nil
+}
+
+// readSection reads the next section, returning its name and data once
+// the data has been read in full and its checksum verified, so nothing is
+// decoded from a corrupt section. It returns io.EOF at the end of the file.
+func readSection(r *bufio.Reader) (string, []byte, error) {
+	n, err := binary.ReadUvarint(r)
+	if err == io.ErrUnexpectedEOF {
+		return "", nil, fmt.Errorf("section header cut short: %w", ErrCorruptIndex)
+	} else if err != nil {
+		return "", nil, err
+	}
+	if n > maxSectionName {
+		return "", nil, fmt.Errorf("section name of %d bytes: %w", n, ErrCorruptIndex)
+	}
+	name := make([]byte, n+8)
+	if _, err := io.ReadFull(r, name); err != nil {
+		return "", nil, fmt.Errorf("section header cut short: %w", ErrCorruptIndex)
+	}
+	size := binary.LittleEndian.Uint64(name[n:])
+	// The data is read as it comes rather than into a buffer of the
+	// claimed size, so a corrupt size runs into the end of the file
+	// instead of allocating it.
+	data, err := io.ReadAll(io.LimitReader(r, int64(min(size, math.MaxInt64))))
+	if err != nil {
+		return "", nil, err
+	}
+	var sum [4]byte
+	if uint64(len(data)) != size {
+		return "", nil, fmt.Errorf("section %q cut short: %w", name[:n], ErrCorruptIndex)
+	}
+	if _, err := io.ReadFull(r, sum[:]); err != nil {
+		return "", nil, fmt.Errorf("section %q cut short: %w", name[:n], ErrCorruptIndex)
+	}
+	crc := crc32.Checksum(binary.AppendUvarint(nil, n), crcTable)
+	crc = crc32.Update(crc, crcTable, name)
+	if binary.LittleEndian.Uint32(sum[:]) != crc32.Update(crc, crcTable, data) {
+		return "", nil, fmt.Errorf("section %q: checksum mismatch: %w", name[:n], ErrCorruptIndex)
+	}
+	return string(name[:n]), data, nil
+}
+
+// gzipErrors reports the errors of a gzip stream, other than its end, as
+// corruption, since they mean it didn't decompress.
+type gzipErrors struct{ r io.Reader }
+
+func (g gzipErrors) Read(p []byte) (int, error) {
+	n, err := g.r.Read(p)
+	if err != nil && err != io.EOF {
+		err = fmt.Errorf("%w: %w", ErrCorruptIndex, err)
+	}
+	return n, err
+}
@@ -0,0 +1,215 @@
+package fulltextsearch
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestCorruptIndex(t *testing.T) {
+	docs := []Document{
+		{ID: 0, Title: "Donuts", Text: "a glazed donut with sprinkles"},
+		{ID: 3, Title: "Bagels", Text: "a toasted bagel with cream cheese"},
+	}
+	tests := []struct {
+		name        string
+		compression Compression
+		// header is how many leading bytes aren't covered by a checksum.
+		header int
+	}{
+		{"plain", NoCompression, 20},
+		{"gzip", Gzip, 10},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Config{StoreDocuments: true, Compression: tt.compression}
+			idx := NewIndex(cfg)
+			idx.Add(docs)
+			var buf bytes.Buffer
+			if err := idx.Snapshot(&buf); err != nil {
+				t.Fatal(err)
+			}
+			saved := buf.Bytes()
+			for i := tt.header; i < len(saved); i++ {
+				b := bytes.Clone(saved)
+				b[i] ^= 0xff
+				err := NewIndex(cfg).RestoreSnapshot(bytes.NewReader(b))
+				if !errors.Is(err, ErrCorruptIndex) {
+					t.Fatalf("flipping byte %d of %d: RestoreSnapshot() = %v, want %v", i, len(saved), err, ErrCorruptIndex)
+				}
+			}
+			for i := range len(saved) - tt.header {
+				err := NewIndex(cfg).RestoreSnapshot(bytes.NewReader(saved[:tt.header+i]))
+				if !errors.Is(err, ErrCorruptIndex) {
+					t.Fatalf("cutting to %d of %d bytes: RestoreSnapshot() = %v, want %v", tt.header+i, len(saved), err, ErrCorruptIndex)
+				}
+			}
+		})
+	}
+}
+
+func TestCorruptIndexHeader(t *testing.T) {
+	idx := NewIndex(Config{})
+	idx.Add([]Document{{ID: 0, Text: "donut"}})
+	var buf bytes.Buffer
+	if err := idx.Snapshot(&buf); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name string
+		at   int
+		want error
+	}{
+		{"magic", 0, ErrNotIndex},
+		{"version", 8, ErrFormatVersion},
+		{"fingerprint", 12, ErrAnalyzerMismatch},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := bytes.Clone(buf.Bytes())
+			b[tt.at] ^= 0xff
+			if err := NewIndex(Config{}).RestoreSnapshot(bytes.NewReader(b)); !errors.Is(err, tt.want) {
+				t.Errorf("RestoreSnapshot() = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestAnalyzerMismatch(t *testing.T) {
+	idx := NewIndex(Config{})
+	idx.Add([]Document{{ID: 0, Text: "running donuts"}})
+	path := filepath.Join(t.TempDir(), "idx")
+	if err := idx.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name     string
+		analyzer Analyzer
+		want     error
+	}{
+		{"same", Analyzer{}, nil},
+		{"explicit defaults", Analyzer{StemStrength: StemFull}, nil},
+		{"no stemming", Analyzer{StemStrength: StemNone}, ErrAnalyzerMismatch},
+		{"another language", Analyzer{Language: "de"}, ErrAnalyzerMismatch},
+		{"folded accents", Analyzer{FoldAccents: true}, ErrAnalyzerMismatch},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			loaded := NewIndex(Config{Analyzer: tt.analyzer})
+			if err := loaded.Load(path); !errors.Is(err, tt.want) {
+				t.Fatalf("Load() = %v, want %v", err, tt.want)
+			}
+			if tt.want == nil && loaded.DocCount() != 1 {
+				t.Errorf("DocCount() after Load = %d, want 1", loaded.DocCount())
+			}
+		})
+	}
+	// The file is still there, so it's up to Load to refuse it.
+	if rebuild, err := NeedsRebuild(path, "", false); err != nil || rebuild {
+		t.Errorf("NeedsRebuild() = %v, %v, want false, nil", rebuild, err)
+	}
+}
+
+func TestSaveLoadEmpty(t *testing.T) {
+	tests := []struct {
+		name string
+		docs []Document
+	}{
+		{"no documents", nil},
+		{"empty text", []Document{{ID: 0, Title: "Donuts"}}},
+		{"stopwords only", []Document{{ID: 0, Text: "the and a"}}},
+		{"empty document", []Document{{ID: 4}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Config{StoreDocuments: true}
+			idx := NewIndex(cfg)
+			idx.Add(tt.docs)
+			path := filepath.Join(t.TempDir(), "idx")
+			if err := idx.Save(path); err != nil {
+				t.Fatal(err)
+			}
+			loaded := NewIndex(cfg)
+			if err := loaded.Load(path); err != nil {
+				t.Fatal(err)
+			}
+			if got, want := loaded.DocCount(), idx.DocCount(); got != want {
+				t.Errorf("DocCount() after Load = %d, want %d", got, want)
+			}
+			for name, f := range idx.Fields {
+				got, ok := loaded.Fields[name]
+				if !ok {
+					t.Errorf("field %s missing after Load", name)
+					continue
+				}
+				if got.Postings.len() != f.Postings.len() || got.Lengths.len() != f.Lengths.len() {
+					t.Errorf("field %s after Load has %d terms and %d lengths, want %d and %d", name, got.Postings.len(), got.Lengths.len(), f.Postings.len(), f.Lengths.len())
+				}
+			}
+			if got, want := loaded.Search("donuts"), idx.Search("donuts"); !slices.Equal(got, want) {
+				t.Errorf("Search(donuts) after Load = %v, want %v", got, want)
+			}
+			for _, doc := range tt.docs {
+				if _, ok := loaded.store.lookup(doc.ID); !ok {
+					t.Errorf("document %d not in the store after Load", doc.ID)
+				}
+			}
+		})
+	}
+}
+
+func TestCompressedSaveLoad(t *testing.T) {
+	docs := randomDocs(rand.New(rand.NewPCG(5, 6)), 0, 300)
+	want := NewIndex(Config{})
+	want.Add(slices.Clone(docs))
+	texts := make(map[int]string)
+	for _, doc := range docs {
+		texts[doc.ID] = doc.Text
+	}
+
+	dir := t.TempDir()
+	sizes := make(map[Compression]int64)
+	for _, c := range []Compression{NoCompression, Gzip} {
+		idx := NewIndex(Config{StoreDocuments: true, Compression: c})
+		idx.Add(slices.Clone(docs))
+		path := filepath.Join(dir, fmt.Sprint("idx", c))
+		if err := idx.Save(path); err != nil {
+			t.Fatal(err)
+		}
+		b, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if gz := bytes.HasPrefix(b, []byte{0x1f, 0x8b}); gz != (c == Gzip) {
+			t.Errorf("with compression %d, file is gzipped: %v", c, gz)
+		}
+		sizes[c] = int64(len(b))
+
+		// Loading detects compression whatever the loading index is
+		// configured with.
+		for _, lc := range []Compression{NoCompression, Gzip} {
+			loaded := NewIndex(Config{StoreDocuments: true, Compression: lc})
+			if err := loaded.Load(path); err != nil {
+				t.Fatalf("loading compression %d with %d: %v", c, lc, err)
+			}
+			sameIndex(t, loaded, want)
+			results := loaded.Rank("cat", nil)
+			if err := loaded.Resolve(results); err != nil {
+				t.Fatal(err)
+			}
+			for _, res := range results {
+				if res.Text != texts[res.ID] {
+					t.Errorf("loading compression %d with %d: document %d text = %q, want %q", c, lc, res.ID, res.Text, texts[res.ID])
+				}
+			}
+		}
+	}
+	if sizes[Gzip] >= sizes[NoCompression] {
+		t.Errorf("gzipped index is %d bytes, uncompressed %d", sizes[Gzip], sizes[NoCompression])
+	}
+}
@@ -1,130 +1,678 @@
-package main
+// Package fulltextsearch is an in-memory full-text search engine, built for
+// the English Wikipedia abstract dumps but usable with any documents.
+//
+// Build an index with NewIndex, fill it with Add and query it with Search
+// for matching IDs or Rank for BM25-scored results. The fts command under
+// cmd/fts is a small example.
+package fulltextsearch
 
 import (
-	"compress/gzip"
 	"crypto/sha1"
-	"encoding/gob"
-	"encoding/xml"
 	"fmt"
 	"io"
 	"log"
+	"maps"
 	"os"
+	"path/filepath"
+	"slices"
+	"sort"
 	"strings"
-	"unicode"
-
-	snowballeng "github.com/kljensen/snowball/english"
+	"time"
 )
 
-type abstract struct {
-	Documents []document `xml:"doc"`
+// field is the inverted index for one document field. Freqs runs parallel
+// to Postings, holding the number of times the term occurs in each document.
+// Positions, if non-nil, also runs parallel to Postings, holding the term's
+// offsets in each document's analyzed terms.
+type field struct {
+	Postings    cowMap[string, []int]
+	Freqs       cowMap[string, []int]
+	Positions   *cowMap[string, [][]int]
+	Lengths     cowMap[int, int] // doc ID -> number of terms in the field
+	TotalLength int
 }
 
-type document struct {
-	Title   string `xml:"title"`
-	URL     string `xml:"url"`
-	Text    string `xml:"abstract"`
-	URLSHA1 []byte
-	ID      int
+func newField() *field {
+	return new(field)
 }
 
-func loadDocuments(path string) ([]document, error) {
+// add indexes terms as document id's value of the field. pos holds the
+// position of each term, or if nil, the terms are at positions 0, 1, 2 and
+// so on.
+func (f *field) add(id int, terms []string, pos []int) {
+	if len(terms) == 0 {
+		return
+	}
+	offsets := make(map[string][]int)
+	for i, term := range terms {
+		p := i
+		if pos != nil {
+			p = pos[i]
+		}
+		offsets[term] = append(offsets[term], p)
+	}
+	for term, pos := range offsets {
+		ids, i := insertSorted(f.Postings.get(term), id)
+		f.Postings.set(term, ids)
+		f.Freqs.set(term, insertAt(f.Freqs.get(term), i, len(pos)))
+		if f.Positions != nil {
+			f.Positions.set(term, insertAt(f.Positions.get(term), i, pos))
+		}
+	}
+	f.Lengths.set(id, len(terms))
+	f.TotalLength += len(terms)
+}
 
-	f, err := os.Open(path)
-	if err != nil {
-		return nil, err
+// remove deletes document id from the field. With no record of which terms
+// the document had, it has to check every posting list.
+func (f *field) remove(id int) {
+	n, ok := f.Lengths.lookup(id)
+	if !ok {
+		return
 	}
-	defer f.Close()
+	for term, ids := range f.Postings.all() {
+		i := sort.SearchInts(ids, id)
+		if i == len(ids) || ids[i] != id {
+			continue
+		}
+		if len(ids) == 1 {
+			f.Postings.delete(term)
+			f.Freqs.delete(term)
+			f.Positions.delete(term)
+			continue
+		}
+		f.Postings.set(term, removeAt(ids, i))
+		f.Freqs.set(term, removeAt(f.Freqs.get(term), i))
+		if f.Positions != nil {
+			f.Positions.set(term, removeAt(f.Positions.get(term), i))
+		}
+	}
+	f.Lengths.delete(id)
+	f.TotalLength -= n
+}
 
-	gz, err := gzip.NewReader(f)
-	if err != nil {
-		return nil, err
+// freq returns how often term occurs in document id.
+func (f *field) freq(term string, id int) int {
+	ids := f.Postings.get(term)
+	i := sort.SearchInts(ids, id)
+	if i < len(ids) && ids[i] == id {
+		return f.Freqs.get(term)[i]
 	}
-	defer gz.Close()
+	return 0
+}
 
-	decoder := xml.NewDecoder(gz)
+func (f *field) avgLength() float64 {
+	if f.Lengths.len() == 0 {
+		return 0
+	}
+	return float64(f.TotalLength) / float64(f.Lengths.len())
+}
 
-	abs := new(abstract)
-	err = decoder.Decode(&abs)
-	if err != nil {
-		return nil, err
+// fields returns the text of each indexed field of doc, keyed by field name.
+func (doc Document) fields() map[string]string {
+	anchors := make([]string, len(doc.Links))
+	for i, l := range doc.Links {
+		anchors[i] = l.Anchor
+	}
+	r := map[string]string{
+		"text":    doc.Text,
+		"title":   doc.Title,
+		"url":     doc.URL,
+		"anchors": strings.Join(anchors, "\n"),
 	}
+	for name, value := range doc.Extra {
+		r[name] = value
+	}
+	return r
+}
 
-	docs := abs.Documents
-	for i := range docs {
-		h := sha1.New()
-		io.WriteString(h, docs[i].URL)
-		docs[i].URLSHA1 = h.Sum(nil)
+// Operator is how a query's terms combine.
+type Operator int
 
-		docs[i].ID = i
+const (
+	And Operator = iota // documents must contain every term
+	Or                  // documents must contain at least one term
+)
 
-		//file, _ := xml.MarshalIndent(docs[i], "", " ")
-		//_ = ioutil.WriteFile(fmt.Sprintf("docs/%d.xml", docs[i].ID), file, 0644)
+type Index struct {
+	// DefaultOperator combines the terms of a query. The zero value is And.
+	DefaultOperator Operator
+
+	IDs     []int // every document, ascending
+	Fields  map[string]*field
+	Dates   cowMap[int, time.Time]
+	Present map[string][]int // field name -> documents with a value for it
+	Hashes  cowMap[int, [sha1.Size]byte]
+	Boosts  cowMap[int, float64]
+	// Titles and URLs are kept for every document so results can be shown
+	// without going back to the source.
+	Titles cowMap[int, string]
+	URLs   cowMap[int, string]
+	// TitleWords counts the titles each lowercased title word occurs in.
+	TitleWords cowMap[string, int]
+	// FieldAnalyzers names the analyzer of each field that has its own.
+	FieldAnalyzers map[string]string
+	// Keys maps the stable key of each document with a URL, from
+	// DocumentKey, to its ID.
+	Keys cowMap[string, int]
+	// Deleted lists, in order, the documents deleted by Delete whose
+	// postings are still in the index.
+	Deleted []int
+	// Forward, if non-nil, holds each document's analyzed text terms in
+	// order. Config.ForwardIndex enables it.
+	Forward *cowMap[int, []string]
+
+	analyzer   Analyzer
+	similarity Similarity
+	bm25       map[string]BM25Params
+	// bm25Default tunes BM25 for fields without their own parameters.
+	bm25Default BM25Params
+	// editCost weights substitutions in fuzzy matching; nil is uniform.
+	editCost SubstitutionCost
+	// maxExpansions caps the terms a wildcard or fuzzy term expands to.
+	maxExpansions int
+	// fuzziness, if positive, matches every query word fuzzily.
+	fuzziness float64
+	// mltTerms is how many terms MoreLikeThis searches with.
+	mltTerms int
+	// scoring, if set, blends BM25 with recency and boosts in rank.
+	scoring *CompositeScoring
+	// detectLanguages detects the language of documents that don't give
+	// one, to analyze them with.
+	detectLanguages bool
+	// defaultFields are searched by query words without a field: prefix.
+	defaultFields []string
+	// fieldWeights multiply the scores of the named fields in rank.
+	fieldWeights map[string]float64
+	// urlPaths indexes the words of URL paths in the path field.
+	urlPaths bool
+	// cacheIDF keeps the IDF of every term between ranked searches.
+	cacheIDF bool
+	idfs     *idfCache
+	// dict lists the text field's terms in order, for wildcard queries.
+	dict *termDict
+	// titleDict lists the words of TitleWords in order, for Suggest.
+	titleDict *termDict
+	// bitmaps, if non-nil, holds bitmaps of the most frequent terms.
+	bitmaps *bitmapCache
+
+	// slowQuery, if positive, makes Search log the diagnostics of any query
+	// that takes at least this long.
+	slowQuery time.Duration
+
+	// queries, if non-nil, logs every search and ranked search.
+	queries *QueryLog
+
+	// store, if non-nil, keeps every added document.
+	store     *cowMap[int, Document]
+	storeFile storeFile
+	// lazyPositions leaves positions out of the text field; they are
+	// built from store on the first phrase query instead.
+	lazyPositions bool
+	positions     *positionCache
+	// compression is how Save and Snapshot compress the index.
+	compression Compression
+}
+
+// Config holds the options of a new index. The zero value is a usable
+// default: English analysis, standard BM25 and a cached IDF.
+type Config struct {
+	Analyzer Analyzer
+	// Similarity is the scoring model of ranked searches. The zero value
+	// is BM25.
+	Similarity Similarity
+	// BM25Default, if set, sets k1 and b for every field not in BM25,
+	// replacing the built-in defaults, including the title's gentler b.
+	BM25Default BM25Params
+	// BM25 overrides the default BM25 parameters of the named fields.
+	BM25 map[string]BM25Params
+	// DisableIDFCache recomputes IDFs on every ranked search.
+	DisableIDFCache bool
+	// Scoring, if set, blends BM25 with recency and boosts in rank.
+	Scoring *CompositeScoring
+	// ExactFields names fields indexed with KeywordAnalyzer, such as url or
+	// an Extra field, and searched with field:value or field:"a value".
+	// It is short for mapping them to "keyword" in FieldAnalyzers.
+	ExactFields []string
+	// FieldAnalyzers names the analyzer of each field, from Analyzers,
+	// e.g. "nostem" for the title. Fields not listed, and the text field
+	// always, use Analyzer. The names are saved with the index.
+	FieldAnalyzers map[string]string
+	// DetectLanguages detects the language of each added document that
+	// doesn't set one. Documents are analyzed in their own language and
+	// can be filtered with lang:xx; queries use Analyzer's language.
+	DetectLanguages bool
+	// DefaultFields are searched by query words without a field: prefix;
+	// a document matches a word if any of them contains it. Nil means the
+	// text and title, and the path with URLPaths.
+	DefaultFields []string
+	// FieldWeights multiply each named field's score in ranked searches,
+	// overriding the default weights, which favor titles. Unlisted fields
+	// weigh 1.
+	FieldWeights map[string]float64
+	// URLPaths indexes the words of each URL's path, such as "new" and
+	// "york" from .../wiki/New_York, in a field named path.
+	URLPaths bool
+	// SlowQuery, if positive, logs the diagnostics of slower searches.
+	SlowQuery time.Duration
+	// QueryLog, if non-nil, records every search.
+	QueryLog *QueryLog
+	// StoreDocuments keeps added documents for Resolve and phrase queries.
+	StoreDocuments bool
+	// ForwardIndex keeps each document's analyzed text for DocTerms.
+	ForwardIndex bool
+	// EditCost weights substitutions in fuzzy matching; nil is uniform.
+	EditCost SubstitutionCost
+	// MaxExpansions caps the terms a wildcard or fuzzy term expands to.
+	MaxExpansions int
+	// Fuzziness, if positive, expands every query word to the index terms
+	// within that edit distance, as if each were written word~N.
+	Fuzziness float64
+	// MoreLikeThisTerms is how many terms MoreLikeThis searches with.
+	MoreLikeThisTerms int
+	// BitmapPostings keeps bitmaps of the terms in a large share of the
+	// documents, built as searches need them, so intersecting and
+	// excluding their huge posting lists is fast.
+	BitmapPostings bool
+	// LazyPositions skips indexing term positions, which phrase queries
+	// then build from the document store when first needed. It saves
+	// memory if phrase queries are rare but requires StoreDocuments.
+	LazyPositions bool
+	// Compression compresses the saved index file, which is smaller but
+	// slower to save and load. Load detects compressed files whatever the
+	// setting.
+	Compression Compression
+}
+
+func NewIndex(cfg Config) *Index {
+	idx := &Index{
+		Fields:          make(map[string]*field),
+		Present:         make(map[string][]int),
+		FieldAnalyzers:  make(map[string]string),
+		analyzer:        cfg.Analyzer,
+		similarity:      cfg.Similarity,
+		bm25:            defaultBM25(),
+		bm25Default:     standardBM25,
+		editCost:        cfg.EditCost,
+		maxExpansions:   cfg.MaxExpansions,
+		fuzziness:       cfg.Fuzziness,
+		mltTerms:        cfg.MoreLikeThisTerms,
+		scoring:         cfg.Scoring,
+		defaultFields:   cfg.DefaultFields,
+		fieldWeights:    defaultFieldWeights(),
+		urlPaths:        cfg.URLPaths,
+		detectLanguages: cfg.DetectLanguages,
+		cacheIDF:        !cfg.DisableIDFCache,
+		idfs:            new(idfCache),
+		dict:            new(termDict),
+		titleDict:       new(termDict),
+		slowQuery:       cfg.SlowQuery,
+		queries:         cfg.QueryLog,
+		lazyPositions:   cfg.LazyPositions,
+		compression:     cfg.Compression,
+		positions:       new(positionCache),
+	}
+	if cfg.BM25Default != (BM25Params{}) {
+		idx.bm25 = make(map[string]BM25Params)
+		idx.bm25Default = cfg.BM25Default
+	}
+	maps.Copy(idx.bm25, cfg.BM25)
+	maps.Copy(idx.fieldWeights, cfg.FieldWeights)
+	if len(idx.defaultFields) == 0 {
+		idx.defaultFields = []string{"text", "title"}
+		if idx.urlPaths {
+			idx.defaultFields = append(idx.defaultFields, pathField)
+		}
+	}
+	maps.Copy(idx.FieldAnalyzers, cfg.FieldAnalyzers)
+	for _, name := range cfg.ExactFields {
+		idx.FieldAnalyzers[name] = keywordAnalyzer
 	}
-	return docs, nil
+	// The text field's analyzer is always Analyzer, which phrase, fuzzy
+	// and similar queries rely on.
+	delete(idx.FieldAnalyzers, "text")
+	if cfg.StoreDocuments {
+		idx.store = new(cowMap[int, Document])
+	}
+	if cfg.BitmapPostings {
+		idx.bitmaps = new(bitmapCache)
+	}
+	if cfg.ForwardIndex {
+		idx.Forward = new(cowMap[int, []string])
+	}
+	return idx
 }
 
-func tokenize(text string) []string {
-	return strings.FieldsFunc(text, func(r rune) bool {
-		// Split on any character that is not a letter or a number.
-		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
-	})
+// Add indexes docs. A document with the ID of one already in the index
+// replaces it, as does a later document in docs with the ID of an earlier
+// one.
+func (idx *Index) Add(docs []Document) {
+	idx.positions.reset()
+	idx.idfs.reset()
+	idx.dict.reset()
+	idx.titleDict.reset()
+	idx.bitmaps.reset()
+	for _, doc := range docs {
+		if idx.has(doc.ID) || idx.isDeleted(doc.ID) {
+			// Clear out the document the ID last belonged to.
+			idx.Remove(doc.ID)
+		}
+		idx.IDs, _ = insertSorted(idx.IDs, doc.ID)
+		idx.Hashes.set(doc.ID, doc.contentHash())
+		if idx.detectLanguages && doc.Language == "" {
+			doc.Language = DetectLanguage(doc.languageText())
+		}
+		for _, name := range doc.presence() {
+			idx.Present[name], _ = insertSorted(idx.Present[name], doc.ID)
+		}
+		if !doc.Date.IsZero() {
+			idx.Dates.set(doc.ID, doc.Date)
+		}
+		if doc.Boost != 0 {
+			idx.Boosts.set(doc.ID, doc.Boost)
+		}
+		if doc.Title != "" {
+			idx.Titles.set(doc.ID, doc.Title)
+			idx.addTitleWords(doc.Title)
+		}
+		if doc.URL != "" {
+			idx.URLs.set(doc.ID, doc.URL)
+		}
+		if key := DocumentKey(doc); key != "" {
+			idx.Keys.set(key, doc.ID)
+		}
+		if idx.store != nil {
+			idx.store.set(doc.ID, doc)
+			idx.storeFile.pending = append(idx.storeFile.pending, doc.ID)
+		}
+		a := idx.analyzerFor(doc)
+		for name, text := range doc.fields() {
+			terms, pos := idx.fieldTerms(a, name, text)
+			idx.field(name).add(doc.ID, terms, pos)
+			if name == "text" && idx.Forward != nil {
+				idx.Forward.set(doc.ID, terms)
+			}
+		}
+		if idx.urlPaths && doc.URL != "" {
+			terms, pos := idx.fieldTerms(a, pathField, urlPath(doc.URL))
+			idx.field(pathField).add(doc.ID, terms, pos)
+		}
+		if len(doc.Tags) > 0 {
+			idx.field(tagField).add(doc.ID, doc.Tags, nil)
+		}
+		if doc.Language != "" {
+			idx.field(langField).add(doc.ID, []string{doc.Language}, nil)
+		}
+	}
 }
 
-func lowercaseFilter(tokens []string) []string {
-	r := make([]string, len(tokens))
-	for i, token := range tokens {
-		r[i] = strings.ToLower(token)
+// NextID returns the lowest ID above every document the index has held,
+// deleted ones included.
+func (idx *Index) NextID() int {
+	next := 0
+	if n := len(idx.IDs); n > 0 {
+		next = idx.IDs[n-1] + 1
 	}
-	return r
+	if n := len(idx.Deleted); n > 0 {
+		next = max(next, idx.Deleted[n-1]+1)
+	}
+	return next
 }
 
-var stopwords = map[string]struct{}{ // I wish Go had built-in sets.
-	"a": {}, "and": {}, "be": {}, "have": {}, "i": {},
-	"in": {}, "of": {}, "that": {}, "the": {}, "to": {},
+// Append adds docs as new documents, numbering them from NextID on in
+// place of whatever IDs they had, so an index loaded from an earlier run
+// can be extended without clashing with the documents already in it.
+func (idx *Index) Append(docs []Document) {
+	next := idx.NextID()
+	for i := range docs {
+		docs[i].ID = next + i
+	}
+	idx.Add(docs)
 }
 
-func stopwordFilter(tokens []string) []string {
-	r := make([]string, 0, len(tokens))
-	for _, token := range tokens {
-		if _, ok := stopwords[token]; !ok {
-			r = append(r, token)
+// AssignIDs numbers docs by their stable keys: a document already in the
+// index under the same key gets its ID back, and the rest get new IDs from
+// NextID on. Passing the result to Reindex then updates the index from a
+// newer dump without disturbing the IDs of documents it still has.
+func (idx *Index) AssignIDs(docs []Document) {
+	next := idx.NextID()
+	for i := range docs {
+		if id, ok := idx.Keys.lookup(DocumentKey(docs[i])); ok {
+			docs[i].ID = id
+		} else {
+			docs[i].ID = next
+			next++
 		}
 	}
-	return r
 }
 
-func stemmerFilter(tokens []string) []string {
-	r := make([]string, len(tokens))
-	for i, token := range tokens {
-		r[i] = snowballeng.Stem(token, false)
+// Lookup returns the ID of the document with the stable key, if it is in
+// the index.
+func (idx *Index) Lookup(key string) (int, bool) {
+	id, ok := idx.Keys.lookup(key)
+	if !ok || idx.isDeleted(id) {
+		return 0, false
 	}
-	return r
+	return id, true
 }
 
-func analyze(text string) []string {
-	tokens := tokenize(text)
-	tokens = lowercaseFilter(tokens)
-	tokens = stopwordFilter(tokens)
-	tokens = stemmerFilter(tokens)
-	return tokens
+// Key returns the stable key of document id, or "" if it has no URL.
+func (idx *Index) Key(id int) string {
+	return urlKey(idx.URLs.get(id))
 }
 
-type index map[string][]int
-
-func (idx index) add(docs []document) {
-	for _, doc := range docs {
-		for _, token := range analyze(doc.Text) {
-			ids := idx[token]
-			if ids != nil && ids[len(ids)-1] == doc.ID {
-				// Don't add same ID twice.
-				continue
-			}
-			idx[token] = append(ids, doc.ID)
+// field returns the named field, creating it if need be.
+func (idx *Index) field(name string) *field {
+	f, ok := idx.Fields[name]
+	if !ok {
+		f = newField()
+		if name == "text" && !idx.lazyPositions {
+			f.Positions = new(cowMap[string, [][]int])
 		}
+		idx.Fields[name] = f
 	}
+	return f
+}
+
+// fieldTerms analyzes the text of the named field with its own analyzer,
+// if it has one, or else with a, returning the terms and their positions
+// as Analyzer.positioned does.
+func (idx *Index) fieldTerms(a Analyzer, name, text string) ([]string, []int) {
+	if n, ok := idx.FieldAnalyzers[name]; ok {
+		a = Analyzers[n]
+	}
+	return a.positioned(text)
+}
+
+// analyzerOf returns the analyzer of the named field.
+func (idx *Index) analyzerOf(name string) Analyzer {
+	if n, ok := idx.FieldAnalyzers[name]; ok {
+		return Analyzers[n]
+	}
+	return idx.analyzer
+}
+
+// isExact reports whether the named field holds verbatim keywords.
+func (idx *Index) isExact(name string) bool {
+	return idx.FieldAnalyzers[name] == keywordAnalyzer || name == tagField || name == langField
+}
+
+// queryTerms analyzes query text to search the named field with. Words are
+// taken verbatim for exact fields.
+func (idx *Index) queryTerms(name, text string) []string {
+	if idx.isExact(name) {
+		return strings.Fields(text)
+	}
+	return idx.analyzerOf(name).AnalyzeQuery(text)
+}
+
+// AddTokenized indexes tokens as the text of document docID, bypassing the
+// analyzer, for callers with their own tokenization. Queries are still
+// analyzed, so tokens should be in the form the analyzer would produce. As
+// with Add, any document already indexed as docID is replaced.
+func (idx *Index) AddTokenized(docID int, tokens []string) {
+	if idx.has(docID) || idx.isDeleted(docID) {
+		idx.Remove(docID)
+	}
+	idx.positions.reset()
+	idx.idfs.reset()
+	idx.dict.reset()
+	idx.bitmaps.reset()
+	idx.IDs, _ = insertSorted(idx.IDs, docID)
+	if len(tokens) > 0 {
+		idx.Present["text"], _ = insertSorted(idx.Present["text"], docID)
+	}
+	idx.field("text").add(docID, tokens, nil)
+	if idx.Forward != nil {
+		idx.Forward.set(docID, tokens)
+	}
+}
+
+// DocTerms returns the analyzed terms of document id's text, in order, from
+// the forward index. It returns nil if the forward index is disabled.
+func (idx *Index) DocTerms(id int) []string {
+	return idx.Forward.get(id)
+}
+
+// DocCount returns the number of documents in the index.
+func (idx *Index) DocCount() int {
+	return len(idx.IDs)
+}
+
+// Remove deletes document id from the index.
+func (idx *Index) Remove(id int) {
+	idx.positions.reset()
+	idx.idfs.reset()
+	idx.dict.reset()
+	idx.titleDict.reset()
+	idx.bitmaps.reset()
+	idx.IDs = removeSorted(idx.IDs, id)
+	idx.Deleted = removeSorted(idx.Deleted, id)
+	for name, ids := range idx.Present {
+		idx.Present[name] = removeSorted(ids, id)
+	}
+	idx.Dates.delete(id)
+	idx.Boosts.delete(id)
+	idx.removeTitleWords(idx.Titles.get(id))
+	idx.Titles.delete(id)
+	if key := urlKey(idx.URLs.get(id)); idx.Keys.get(key) == id {
+		idx.Keys.delete(key)
+	}
+	idx.URLs.delete(id)
+	idx.Forward.delete(id)
+	idx.Hashes.delete(id)
+	if idx.store != nil {
+		idx.store.delete(id)
+		idx.storeFile.stale = true
+	}
+	for _, f := range idx.Fields {
+		f.remove(id)
+	}
+}
+
+// Delete deletes document id from the index by recording a tombstone,
+// which is much cheaper than Remove since no posting list is touched. The
+// document's postings stay behind, filtered out of every search, until
+// Compact removes them; meanwhile they still count towards term
+// statistics. Its key and content hash go at once, so re-adding the
+// document, through AssignIDs, Reindex or AddFeed, indexes it afresh under
+// a new ID.
+func (idx *Index) Delete(id int) {
+	i := sort.SearchInts(idx.IDs, id)
+	if i == len(idx.IDs) || idx.IDs[i] != id {
+		return
+	}
+	idx.IDs = removeAt(idx.IDs, i)
+	for name, ids := range idx.Present {
+		idx.Present[name] = removeSorted(ids, id)
+	}
+	if key := urlKey(idx.URLs.get(id)); idx.Keys.get(key) == id {
+		idx.Keys.delete(key)
+	}
+	idx.Hashes.delete(id)
+	idx.Deleted, _ = insertSorted(idx.Deleted, id)
+}
+
+// Compact removes the postings of deleted documents from the index.
+func (idx *Index) Compact() {
+	for _, id := range idx.Deleted {
+		idx.Remove(id)
+	}
+	idx.Deleted = nil
+}
+
+// has reports whether document id is in the index and not deleted.
+func (idx *Index) has(id int) bool {
+	_, ok := slices.BinarySearch(idx.IDs, id)
+	return ok
+}
+
+// isDeleted reports whether id has a tombstone.
+func (idx *Index) isDeleted(id int) bool {
+	_, ok := slices.BinarySearch(idx.Deleted, id)
+	return ok
+}
+
+// live returns ids without the deleted documents.
+func (idx *Index) live(ids []int) []int {
+	if len(idx.Deleted) == 0 {
+		return ids
+	}
+	return difference(ids, idx.Deleted)
+}
+
+// insertSorted adds id to the ascending list ids, returning the new list and
+// where id went. Appending is the common case; anything else builds a new
+// slice, so lists shared with a snapshot are never written through.
+func insertSorted(ids []int, id int) ([]int, int) {
+	i := sort.SearchInts(ids, id)
+	if i == len(ids) {
+		return append(ids, id), i
+	}
+	return insertAt(ids, i, id), i
+}
+
+func removeSorted(ids []int, id int) []int {
+	i := sort.SearchInts(ids, id)
+	if i == len(ids) || ids[i] != id {
+		return ids
+	}
+	return removeAt(ids, i)
+}
+
+func insertAt[T any](s []T, i int, v T) []T {
+	if i == len(s) {
+		return append(s, v)
+	}
+	r := make([]T, 0, len(s)+1)
+	r = append(r, s[:i]...)
+	r = append(r, v)
+	return append(r, s[i:]...)
+}
+
+// removeAt returns s without element i, without modifying s.
+func removeAt[T any](s []T, i int) []T {
+	return append(s[:i:i], s[i+1:]...)
 }
 
 func intersection(a []int, b []int) []int {
+	r, _ := intersectionSteps(a, b)
+	return r
+}
+
+// gallopRatio is how many times longer one list must be than the other for
+// intersection to gallop through it rather than walk it.
+const gallopRatio = 32
+
+// intersectionSteps is intersection that also returns the number of loop
+// iterations it took.
+func intersectionSteps(a []int, b []int) ([]int, int) {
+	if len(a) > len(b) {
+		a, b = b, a
+	}
+	if len(b) > gallopRatio*len(a) {
+		return gallop(a, b)
+	}
 	maxLen := len(a)
 	if len(b) > maxLen {
 		maxLen = len(b)
@@ -142,93 +690,282 @@ func intersection(a []int, b []int) []int {
 			j++
 		}
 	}
-	return r
+	return r, i + j
 }
 
-func (idx index) search(text string) []int {
-	var r []int
-	for _, token := range analyze(text) {
-		if ids, ok := idx[token]; ok {
-			if r == nil {
-				r = ids
+// gallop intersects short with the much longer long by exponential search:
+// for each document of short it strides through long, doubling the stride
+// until it overshoots, then binary searches the last stride. That takes
+// about log(len(long)/len(short)) steps per document rather than the
+// len(long)/len(short) of walking both lists.
+func gallop(short, long []int) ([]int, int) {
+	r := make([]int, 0, len(short))
+	var j, steps int
+	for _, id := range short {
+		if j >= len(long) {
+			break
+		}
+		// Everything before long[j] is smaller than id.
+		stride := 1
+		for j+stride < len(long) && long[j+stride] < id {
+			stride *= 2
+			steps++
+		}
+		lo, hi := j+stride/2, min(j+stride+1, len(long))
+		for lo < hi {
+			mid := int(uint(lo+hi) >> 1)
+			if long[mid] < id {
+				lo = mid + 1
 			} else {
-				r = intersection(r, ids)
+				hi = mid
 			}
-		} else {
-			// Token doesn't exist.
-			return nil
+			steps++
+		}
+		j = lo
+		if j < len(long) && long[j] == id {
+			r = append(r, id)
+			j++
 		}
 	}
-	return r
+	return r, steps
 }
 
-func main() {
-	idxFilename := "enwiki.idx"
-	idx := make(index)
-
-	if _, err := os.Stat(idxFilename); err == nil {
-		// path/to/whatever exists
-		log.Println("full text search index exists; using...")
-		decodeFile, err := os.Open(idxFilename)
-		if err != nil {
-			panic(err)
+func union(a []int, b []int) []int {
+	r := make([]int, 0, len(a)+len(b))
+	var i, j int
+	for i < len(a) && j < len(b) {
+		if a[i] < b[j] {
+			r = append(r, a[i])
+			i++
+		} else if a[i] > b[j] {
+			r = append(r, b[j])
+			j++
+		} else {
+			r = append(r, a[i])
+			i++
+			j++
 		}
-		defer decodeFile.Close()
+	}
+	r = append(r, a[i:]...)
+	return append(r, b[j:]...)
+}
 
-		// Create a decoder
-		decoder := gob.NewDecoder(decodeFile)
+func (idx *Index) Search(text string) []int {
+	return idx.search(text, idx.DefaultOperator)
+}
 
-		// Decode -- We need to pass a pointer otherwise accounts2 isn't modified
-		decoder.Decode(&idx)
-	} else if os.IsNotExist(err) {
-		// path does *not* exist, so build index and save
-		log.Println("full text search index does not exist; rebuilding...")
+// SearchAny returns the documents containing at least one of the query
+// terms, whatever the index's default operator.
+func (idx *Index) SearchAny(text string) []int {
+	return idx.search(text, Or)
+}
 
-		docs, err := loadDocuments("enwiki-latest-abstract1.xml.gz")
-		if err != nil {
-			log.Fatal(err)
-			return
+func (idx *Index) search(text string, op Operator) []int {
+	if idx.slowQuery <= 0 {
+		start := time.Now()
+		r := idx.match(text, op, nil)
+		idx.queries.record(text, len(r), time.Since(start))
+		return r
+	}
+	r, d := idx.searchDiagnostics(text, op)
+	if d.Elapsed >= idx.slowQuery {
+		log.Printf("slow query %q: %s", text, d)
+	}
+	idx.queries.record(text, len(r), d.Elapsed)
+	return r
+}
+
+// match does the work of Search, combining terms with op and recording what
+// it scanned in d if d is non-nil.
+func (idx *Index) match(text string, op Operator, d *Diagnostics) []int {
+	text, filters := idx.parseFilters(text)
+	if d != nil {
+		for i, e := range filters.expanded {
+			d.Expansions[filters.patterns[i]] = e
 		}
+	}
+	if filters.empty() {
+		return idx.live(idx.matchTerms(text, op, d))
+	}
+	if len(idx.queryTerms("text", text)) == 0 {
+		// Only filters were given, perhaps with stopwords, so they apply
+		// to every document.
+		return idx.filter(idx.IDs, filters, d)
+	}
+	return idx.live(idx.filter(idx.matchTerms(text, op, d), filters, d))
+}
 
-		//idx.add([]document{{ID: 1, Text: "A donut on a glass plate. Only the donuts."}})
-		//idx.add([]document{{ID: 2, Text: "donut is a donut"}})
-		idx.add(docs)
+// matchTerms matches the query words against the default fields.
+func (idx *Index) matchTerms(text string, op Operator, d *Diagnostics) []int {
+	if len(idx.defaultFields) == 1 {
+		return idx.matchField(idx.defaultFields[0], text, op, d)
+	}
+	return idx.matchWords(text, op, d, func(name, word string) []int {
+		return idx.matchField(name, word, And, d)
+	})
+}
 
-		// Create a file for IO
-		encodeFile, err := os.Create(idxFilename)
-		if err != nil {
-			panic(err)
+// matchWords combines the documents matching each query word in any of the
+// default fields, as found by match, with op, counting the intersection
+// steps in d if it is non-nil.
+func (idx *Index) matchWords(text string, op Operator, d *Diagnostics, match func(name, word string) []int) []int {
+	var r []int
+	first := true
+	for _, word := range strings.Fields(text) {
+		var ids []int
+		var analyzed bool
+		for _, name := range idx.defaultFields {
+			if len(idx.queryTerms(name, word)) == 0 {
+				continue
+			}
+			analyzed = true
+			ids = union(ids, match(name, word))
 		}
+		switch {
+		case !analyzed:
+			// A stopword everywhere, so it doesn't restrict the matches.
+		case first:
+			r, first = ids, false
+		case op == Or:
+			r = union(r, ids)
+		default:
+			var steps int
+			r, steps = intersectionSteps(r, ids)
+			if d != nil {
+				d.IntersectionSteps += steps
+			}
+		}
+	}
+	return r
+}
 
-		// Since this is a binary format large parts of it will be unreadable
-		encoder := gob.NewEncoder(encodeFile)
-
-		// Write to the file
-		if err := encoder.Encode(idx); err != nil {
-			panic(err)
+// matchField returns the documents whose named field contains every query
+// term, or any of them if op is Or.
+func (idx *Index) matchField(name, text string, op Operator, d *Diagnostics) []int {
+	var r []int
+	f, ok := idx.Fields[name]
+	if !ok {
+		return nil
+	}
+	if idx.bitmaps != nil {
+		return idx.matchBitmaps(name, f, idx.queryTerms(name, text), op, d)
+	}
+	for _, token := range idx.queryTerms(name, text) {
+		ids, ok := f.Postings.lookup(token)
+		if d != nil {
+			d.Postings[name+":"+token] = len(ids)
 		}
-		encodeFile.Close()
-	} else {
-		log.Fatal(err)
-		// Schrodinger: file may or may not exist. See err for details.
+		if op == Or {
+			r = union(r, ids)
+			continue
+		}
+		if !ok {
+			// Token doesn't exist.
+			return nil
+		}
+		if r == nil {
+			r = ids
+		} else {
+			var steps int
+			r, steps = intersectionSteps(r, ids)
+			if d != nil {
+				d.IntersectionSteps += steps
+			}
+		}
+	}
+	return r
+}
 
-		// Therefore, do *NOT* use !os.IsNotExist(err) to test for file existence
+// writeFileAtomic writes to a temporary file in the same directory as path
+// and renames it over path only once write has succeeded and the file is
+// flushed, so a crash or error mid-write leaves any existing file intact.
+func writeFileAtomic(path string, write func(w io.Writer) error) error {
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name()) // no-op once renamed
 
+	if err := write(f); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
+
+// Save writes the index to path and, if it has a doc store, the documents
+// to a companion file beside it.
+func (idx *Index) Save(path string) error {
+	if err := idx.saveStore(path); err != nil {
+		return err
+	}
+	return writeFileAtomic(path, func(w io.Writer) error {
+		return idx.write(w, false)
+	})
+}
+
+// Load reads an index written by Save, along with its doc store if the
+// index keeps one.
+func (idx *Index) Load(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := idx.read(f); err != nil {
+		return fmt.Errorf("loading %s: %w", path, err)
 	}
+	return idx.loadStore(path)
+}
 
-	r := idx.search("small wild cat")
+// Snapshot writes a copy of the index, along with its doc store if it
+// keeps one, to w as a single stream, for backups. The index must not be
+// modified while it is written; LiveIndex.Backup and SyncIndex.Backup take
+// consistent snapshots while updates go on.
+func (idx *Index) Snapshot(w io.Writer) error {
+	return idx.write(w, true)
+}
 
-	fmt.Println(r)
+// RestoreSnapshot reads a snapshot written by Snapshot into idx, which
+// should be new from NewIndex with the configuration the snapshot was
+// taken with, as for Load. Saving the index afterwards writes it and its
+// doc store in full.
+func (idx *Index) RestoreSnapshot(r io.Reader) error {
+	if err := idx.read(r); err != nil {
+		return fmt.Errorf("restoring snapshot: %w", err)
+	}
+	return nil
+}
 
-	// this part is really slow but there isn't a clear way to index
-	// into the original xml file without reading it entirely
-	//docs, err := loadDocuments("enwiki-latest-abstract1.xml.gz")
-	//if err != nil {
-	//	log.Fatal(err)
-	//	return
-	//}
-	//for _, id := range r {
-	//	doc := docs[id]
-	//	fmt.Printf("[%d]\t%s\n", id, doc.Text)
-	//}
+// NeedsRebuild reports whether the index at idxPath has to be built from
+// srcPath: because it doesn't exist yet or, if checkStale is set, because
+// the source has been modified since the index was written.
+func NeedsRebuild(idxPath, srcPath string, checkStale bool) (bool, error) {
+	idxInfo, err := os.Stat(idxPath)
+	if os.IsNotExist(err) {
+		return true, nil
+	} else if err != nil {
+		// Schrodinger: file may or may not exist. See err for details.
+
+		// Therefore, do *NOT* use !os.IsNotExist(err) to test for file existence
+		return false, err
+	}
+	if !checkStale {
+		return false, nil
+	}
+	srcInfo, err := os.Stat(srcPath)
+	if os.IsNotExist(err) {
+		// Nothing to rebuild from, so the index is the best we have.
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	return srcInfo.ModTime().After(idxInfo.ModTime()), nil
 }
@@ -0,0 +1,722 @@
+package fulltextsearch
+
+import (
+	"fmt"
+	"maps"
+	"math"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDeleteThenReAdd(t *testing.T) {
+	tests := []struct {
+		name  string
+		readd func(idx *Index, doc Document) int
+	}{
+		{"AssignIDs and Reindex", func(idx *Index, doc Document) int {
+			docs := []Document{doc}
+			idx.AssignIDs(docs)
+			indexed, _ := idx.Reindex(docs)
+			return indexed
+		}},
+		{"addNew", func(idx *Index, doc Document) int {
+			return idx.addNew([]Document{doc})
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			donut := Document{URL: "https://example.com/donut", Text: "donut"}
+			idx := NewIndex(Config{})
+			docs := []Document{donut, {URL: "https://example.com/plate", Text: "glass plate"}}
+			idx.AssignIDs(docs)
+			idx.Add(docs)
+
+			idx.Delete(0)
+			if got := idx.Search("donut"); len(got) != 0 {
+				t.Fatalf("Search(donut) after Delete = %v, want none", got)
+			}
+			if n := tt.readd(idx, donut); n != 1 {
+				t.Fatalf("re-adding the deleted document indexed %d documents, want 1", n)
+			}
+			if got, want := idx.Search("donut"), []int{2}; !slices.Equal(got, want) {
+				t.Errorf("Search(donut) = %v, want %v", got, want)
+			}
+			if got := idx.DocCount(); got != 2 {
+				t.Errorf("DocCount() = %d, want 2", got)
+			}
+		})
+	}
+}
+
+func TestDocCount(t *testing.T) {
+	tests := []struct {
+		name   string
+		mutate func(idx *Index)
+		want   int
+	}{
+		{"empty", func(*Index) {}, 0},
+		{"add", func(idx *Index) {
+			idx.Add([]Document{{ID: 0, Text: "donut"}, {ID: 1, Text: "plate"}})
+		}, 2},
+		{"add an existing ID", func(idx *Index) {
+			idx.Add([]Document{{ID: 0, Text: "donut"}})
+			idx.Add([]Document{{ID: 0, Text: "plate"}})
+		}, 1},
+		{"repeat an ID in one batch", func(idx *Index) {
+			idx.Add([]Document{{ID: 0, Text: "donut"}, {ID: 0, Text: "plate"}})
+		}, 1},
+		{"repeat an ID in a parallel batch", func(idx *Index) {
+			idx.AddParallel([]Document{{ID: 0, Text: "donut"}, {ID: 1, Text: "glass"}, {ID: 0, Text: "plate"}}, 2)
+		}, 2},
+		{"add tokenized over an existing ID", func(idx *Index) {
+			idx.Add([]Document{{ID: 0, Text: "donut"}})
+			idx.AddTokenized(0, []string{"plate"})
+		}, 1},
+		{"remove", func(idx *Index) {
+			idx.Add([]Document{{ID: 0, Text: "donut"}, {ID: 1, Text: "plate"}})
+			idx.Remove(0)
+		}, 1},
+		{"remove a missing ID", func(idx *Index) {
+			idx.Add([]Document{{ID: 0, Text: "donut"}})
+			idx.Remove(7)
+		}, 1},
+		{"delete and compact", func(idx *Index) {
+			idx.Add([]Document{{ID: 0, Text: "donut"}, {ID: 1, Text: "plate"}, {ID: 2, Text: "glass"}})
+			idx.Delete(1)
+			idx.Delete(1)
+			idx.Compact()
+		}, 2},
+		{"update", func(idx *Index) {
+			idx.Add([]Document{{ID: 0, Text: "donut"}})
+			idx.Update(Document{ID: 0, Text: "plate"})
+		}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			idx := NewIndex(Config{})
+			tt.mutate(idx)
+			if got := idx.DocCount(); got != tt.want {
+				t.Errorf("DocCount() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAddReplacesExistingID(t *testing.T) {
+	idx := NewIndex(Config{})
+	idx.Add([]Document{{ID: 0, Text: "donut"}, {ID: 1, Text: "glass"}})
+	idx.Add([]Document{{ID: 0, Text: "plate"}})
+	if got := idx.Search("donut"); len(got) != 0 {
+		t.Errorf("Search(donut) = %v, want the replaced version gone", got)
+	}
+	if got, want := idx.Search("plate"), []int{0}; !slices.Equal(got, want) {
+		t.Errorf("Search(plate) = %v, want %v", got, want)
+	}
+}
+
+func TestNeedsRebuild(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name       string
+		idxAge     time.Duration // how long ago the index was written; 0 for no index
+		srcAge     time.Duration // likewise for the source
+		checkStale bool
+		want       bool
+	}{
+		{"no index", 0, time.Hour, false, true},
+		{"no index or source", 0, 0, true, true},
+		{"index newer", time.Minute, time.Hour, true, false},
+		{"source newer", time.Hour, time.Minute, true, true},
+		{"source newer but not checked", time.Hour, time.Minute, false, false},
+		{"no source", time.Hour, 0, true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			idxPath, srcPath := filepath.Join(dir, "idx"), filepath.Join(dir, "src.xml.gz")
+			for _, f := range []struct {
+				path string
+				age  time.Duration
+			}{{idxPath, tt.idxAge}, {srcPath, tt.srcAge}} {
+				if f.age == 0 {
+					continue
+				}
+				if err := os.WriteFile(f.path, nil, 0644); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.Chtimes(f.path, now.Add(-f.age), now.Add(-f.age)); err != nil {
+					t.Fatal(err)
+				}
+			}
+			got, err := NeedsRebuild(idxPath, srcPath, tt.checkStale)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("NeedsRebuild() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDocTerms(t *testing.T) {
+	docs := []Document{
+		{ID: 0, Text: "The cats were running home"},
+		{ID: 1, Text: "a glass plate"},
+	}
+	idx := NewIndex(Config{ForwardIndex: true})
+	idx.Add(docs)
+	path := filepath.Join(t.TempDir(), "idx")
+	if err := idx.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	loaded := NewIndex(Config{ForwardIndex: true})
+	if err := loaded.Load(path); err != nil {
+		t.Fatal(err)
+	}
+	for _, doc := range docs {
+		want := Analyzer{}.Analyze(doc.Text)
+		if got := idx.DocTerms(doc.ID); !slices.Equal(got, want) {
+			t.Errorf("DocTerms(%d) = %q, want %q", doc.ID, got, want)
+		}
+		if got := loaded.DocTerms(doc.ID); !slices.Equal(got, want) {
+			t.Errorf("DocTerms(%d) after Load = %q, want %q", doc.ID, got, want)
+		}
+	}
+	noForward := NewIndex(Config{})
+	noForward.Add(docs)
+	if got := noForward.DocTerms(0); got != nil {
+		t.Errorf("DocTerms() without a forward index = %q, want nil", got)
+	}
+}
+
+func TestDefaultOperator(t *testing.T) {
+	docs := []Document{
+		{ID: 0, Text: "cat"},
+		{ID: 1, Text: "cat and dog"},
+		{ID: 2, Text: "dog"},
+		{ID: 3, Text: "bird"},
+	}
+	tests := []struct {
+		name string
+		op   Operator
+		want []int
+	}{
+		{"And by default", And, []int{1}},
+		{"Or", Or, []int{0, 1, 2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			idx := NewIndex(Config{})
+			idx.DefaultOperator = tt.op
+			idx.Add(docs)
+			if got := idx.Search("cat dog"); !slices.Equal(got, tt.want) {
+				t.Errorf("Search(cat dog) = %v, want %v", got, tt.want)
+			}
+			var ranked []int
+			for _, r := range idx.Rank("cat dog", ByID{}) {
+				ranked = append(ranked, r.ID)
+			}
+			if !slices.Equal(ranked, tt.want) {
+				t.Errorf("Rank(cat dog) = %v, want %v", ranked, tt.want)
+			}
+		})
+	}
+	if got, want := NewIndex(Config{}).DefaultOperator, And; got != want {
+		t.Errorf("DefaultOperator = %v, want %v", got, want)
+	}
+
+	idx := NewIndex(Config{})
+	idx.DefaultOperator = Or
+	path := filepath.Join(t.TempDir(), "idx")
+	if err := idx.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	loaded := NewIndex(Config{})
+	if err := loaded.Load(path); err != nil {
+		t.Fatal(err)
+	}
+	if loaded.DefaultOperator != Or {
+		t.Errorf("DefaultOperator after Load = %v, want Or", loaded.DefaultOperator)
+	}
+}
+
+func TestAddTokenized(t *testing.T) {
+	idx := NewIndex(Config{ForwardIndex: true})
+	idx.Add([]Document{{ID: 0, Text: "glass plate"}})
+	idx.AddTokenized(1, []string{"run", "cat", "Dog"})
+	tests := []struct {
+		query string
+		want  []int
+	}{
+		{"cat", []int{1}},
+		// the query is analyzed, so it finds the stemmed token
+		{"running cats", []int{1}},
+		// but the tokens aren't, so a token the analyzer would have
+		// lowercased can't be found
+		{"Dog", nil},
+		{"plate", []int{0}},
+	}
+	for _, tt := range tests {
+		if got := idx.Search(tt.query); !slices.Equal(got, tt.want) {
+			t.Errorf("Search(%s) = %v, want %v", tt.query, got, tt.want)
+		}
+	}
+	if got, want := idx.DocTerms(1), []string{"run", "cat", "Dog"}; !slices.Equal(got, want) {
+		t.Errorf("DocTerms(1) = %q, want %q", got, want)
+	}
+}
+
+// sortedIDs returns n distinct sorted IDs below limit.
+func sortedIDs(rng *rand.Rand, n, limit int) []int {
+	m := make(map[int]bool, n)
+	for len(m) < n {
+		m[rng.IntN(limit)] = true
+	}
+	return slices.Sorted(maps.Keys(m))
+}
+
+// linearIntersection is intersection without galloping, for checking it.
+func linearIntersection(a, b []int) []int {
+	r := []int{}
+	var i, j int
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] < b[j]:
+			i++
+		case a[i] > b[j]:
+			j++
+		default:
+			r = append(r, a[i])
+			i++
+			j++
+		}
+	}
+	return r
+}
+
+func TestIntersection(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b []int
+	}{
+		{"both empty", nil, nil},
+		{"one empty", nil, []int{1, 2, 3}},
+		{"single match", []int{5}, []int{5}},
+		{"single miss", []int{5}, []int{6}},
+		{"single in long", []int{40}, sortedIDs(rand.New(rand.NewPCG(1, 1)), 1000, 2000)},
+		{"before long", []int{-1}, []int{0, 1, 2}},
+		{"after long", []int{3}, []int{0, 1, 2}},
+		{"first and last of long", []int{0, 99}, sortedIDs(rand.New(rand.NewPCG(1, 1)), 100, 100)},
+	}
+	rng := rand.New(rand.NewPCG(3, 4))
+	for i := range 200 {
+		// Alternate between lists of about equal length, which are
+		// walked, and skewed ones, which are galloped through.
+		n, m := rng.IntN(100), rng.IntN(100)
+		if i%2 == 1 {
+			m = n*gallopRatio + 1 + rng.IntN(5000)
+		}
+		limit := max(n, m) * (1 + rng.IntN(4))
+		tests = append(tests, struct {
+			name string
+			a, b []int
+		}{fmt.Sprintf("random %d (%d and %d)", i, n, m), sortedIDs(rng, n, limit+1), sortedIDs(rng, m, limit+1)})
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want := linearIntersection(tt.a, tt.b)
+			if got := intersection(tt.a, tt.b); !slices.Equal(got, want) {
+				t.Errorf("intersection() = %v, want %v", got, want)
+			}
+			if got := intersection(tt.b, tt.a); !slices.Equal(got, want) {
+				t.Errorf("intersection() with the lists swapped = %v, want %v", got, want)
+			}
+			short, long := tt.a, tt.b
+			if len(short) > len(long) {
+				short, long = long, short
+			}
+			if got, _ := gallop(short, long); !slices.Equal(got, want) {
+				t.Errorf("gallop() = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestSearchAny(t *testing.T) {
+	idx := NewIndex(Config{})
+	idx.Add([]Document{
+		{ID: 0, Text: "cat"},
+		{ID: 1, Text: "cat and dog"},
+		{ID: 2, Title: "Dog", Text: "a loyal pet"},
+		{ID: 3, Text: "bird"},
+	})
+	tests := []struct {
+		query string
+		want  []int
+	}{
+		{"cat dog", []int{0, 1, 2}},
+		{"cat", []int{0, 1}},
+		{"dog fish", []int{1, 2}},
+		{"the cat", []int{0, 1}},
+		{"fish", nil},
+		{"the", nil},
+		{"", nil},
+	}
+	for _, tt := range tests {
+		if got := idx.SearchAny(tt.query); !slices.Equal(got, tt.want) {
+			t.Errorf("SearchAny(%q) = %v, want %v", tt.query, got, tt.want)
+		}
+	}
+	// SearchAny ignores the default operator, where Search follows it.
+	if got, want := idx.Search("cat dog"), []int{1}; !slices.Equal(got, want) {
+		t.Errorf("Search(cat dog) = %v, want %v", got, want)
+	}
+}
+
+func TestExclusion(t *testing.T) {
+	idx := NewIndex(Config{})
+	idx.Add([]Document{
+		{ID: 0, Text: "a house cat"},
+		{ID: 1, Text: "a wild cat"},
+		{ID: 2, Title: "Wild", Text: "a cat in the hills"},
+		{ID: 3, Text: "a cat", Tags: []string{"wild"}},
+		{ID: 4, Text: "wild dogs"},
+	})
+	tests := []struct {
+		query string
+		want  []int
+	}{
+		// A document matching the excluded word in any field is dropped.
+		{"cat -wild", []int{0}},
+		// It is analyzed as each field would, so stemmed but for the tags.
+		{"cat -wilds", []int{0, 3}},
+		{"cat -house -wild", nil},
+		{"cat -fish", []int{0, 1, 2, 3}},
+		{"cat -the", []int{0, 1, 2, 3}},
+		// With nothing else to match, exclusions apply to every document.
+		{"-cat", []int{4}},
+	}
+	for _, tt := range tests {
+		if got := idx.Search(tt.query); !slices.Equal(got, tt.want) {
+			t.Errorf("Search(%q) = %v, want %v", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestFuzzySearch(t *testing.T) {
+	docs := []Document{
+		{ID: 0, Text: "a glazed donut"},
+		{ID: 1, Text: "a toasted bagel"},
+		{ID: 2, Text: "crispy bacon"},
+	}
+	tests := []struct {
+		name      string
+		fuzziness float64
+		query     string
+		want      []int
+	}{
+		{"exact without ~", 0, "donutt", nil},
+		{"default distance", 0, "dnout~", []int{0}},
+		{"within distance", 0, "donutt~1", []int{0}},
+		{"beyond distance", 0, "dnout~1", nil},
+		{"two edits", 0, "bagle~2", []int{1}},
+		{"nearest only", 0, "bacel~1", []int{1}},
+		{"both within", 0, "bacel~2", []int{1, 2}},
+		{"with other words", 0, "glazed donutt~1", []int{0}},
+		{"with other words missing", 0, "toasted donutt~1", nil},
+		{"fuzziness set", 1, "donutt", []int{0}},
+		{"fuzziness set, beyond it", 1, "dnout", nil},
+		{"~N overrides fuzziness", 1, "dnout~2", []int{0}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			idx := NewIndex(Config{Fuzziness: tt.fuzziness})
+			idx.Add(docs)
+			if got := idx.Search(tt.query); !slices.Equal(got, tt.want) {
+				t.Errorf("Search(%q) = %v, want %v", tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTFIDF(t *testing.T) {
+	idx := NewIndex(Config{Similarity: TFIDF})
+	idx.Add([]Document{
+		{ID: 0, Text: "cat cat dog"},
+		{ID: 1, Text: "cat dog bird fish"},
+		{ID: 2, Text: "dog"},
+		{ID: 3, Text: "bird"},
+	})
+	// sqrt(tf) * idf² / sqrt(length), summed over terms, where the IDF of
+	// a term in df of the n documents is 1 + ln(n / (df + 1)).
+	catIDF, dogIDF := 1+math.Log(4.0/3), 1+math.Log(4.0/4)
+	tests := []struct {
+		query string
+		want  []Result
+	}{
+		{"cat", []Result{
+			{ID: 0, Score: math.Sqrt(2) * catIDF * catIDF / math.Sqrt(3)},
+			{ID: 1, Score: catIDF * catIDF / 2},
+		}},
+		{"cat dog", []Result{
+			{ID: 0, Score: (math.Sqrt(2)*catIDF*catIDF + dogIDF*dogIDF) / math.Sqrt(3)},
+			{ID: 1, Score: (catIDF*catIDF + dogIDF*dogIDF) / 2},
+		}},
+	}
+	for _, tt := range tests {
+		got := idx.Rank(tt.query, nil)
+		if len(got) != len(tt.want) {
+			t.Fatalf("Rank(%q) = %v, want %v", tt.query, got, tt.want)
+		}
+		for i, r := range got {
+			if w := tt.want[i]; r.ID != w.ID || math.Abs(r.Score-w.Score) > 1e-9 {
+				t.Errorf("Rank(%q)[%d] = %d scoring %v, want %d scoring %v", tt.query, i, r.ID, r.Score, w.ID, w.Score)
+			}
+		}
+	}
+}
+
+func TestBM25Default(t *testing.T) {
+	docs := []Document{
+		{ID: 0, Text: "cat"},
+		{ID: 1, Text: "cat dog bird fish"},
+		{ID: 2, Text: "dog"},
+	}
+	// idf * tf * (k1 + 1) / (tf + k1 * (1 - b + b * length / average))
+	idf := math.Log(1 + (3-2+0.5)/(2+0.5))
+	bm25 := func(p BM25Params, length float64) float64 {
+		return idf * (p.K1 + 1) / (1 + p.K1*(1-p.B+p.B*length/2))
+	}
+	tests := []struct {
+		name string
+		cfg  Config
+		// want are the scores of documents 0 and 1 for "cat".
+		want [2]float64
+	}{
+		{"standard", Config{}, [2]float64{bm25(standardBM25, 1), bm25(standardBM25, 4)}},
+		{"no length normalization", Config{BM25Default: BM25Params{K1: 2, B: 0}},
+			[2]float64{bm25(BM25Params{K1: 2}, 1), bm25(BM25Params{K1: 2}, 4)}},
+		// BM25 still tunes the fields it names.
+		{"field overrides default", Config{BM25Default: BM25Params{K1: 2, B: 0}, BM25: map[string]BM25Params{"text": {K1: 1, B: 1}}},
+			[2]float64{bm25(BM25Params{K1: 1, B: 1}, 1), bm25(BM25Params{K1: 1, B: 1}, 4)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			idx := NewIndex(tt.cfg)
+			idx.Add(docs)
+			scores := make(map[int]float64)
+			for _, r := range idx.Rank("cat", nil) {
+				scores[r.ID] = r.Score
+			}
+			for id, want := range tt.want {
+				if math.Abs(scores[id]-want) > 1e-9 {
+					t.Errorf("document %d scores %v, want %v", id, scores[id], want)
+				}
+			}
+		})
+	}
+
+	// Without BM25Default the title keeps its gentler b; with it, it
+	// doesn't.
+	for _, cfg := range []Config{{}, {BM25Default: standardBM25}} {
+		idx := NewIndex(cfg)
+		want := BM25Params{K1: 1.2, B: 0.3}
+		if cfg.BM25Default != (BM25Params{}) {
+			want = cfg.BM25Default
+		}
+		if got := idx.params("title"); got != want {
+			t.Errorf("with BM25Default %v, title parameters = %v, want %v", cfg.BM25Default, got, want)
+		}
+	}
+}
+
+// rankDocs returns documents scoring variously for "cat", many of them
+// tied.
+func rankDocs() []Document {
+	var docs []Document
+	for i := range 50 {
+		text := strings.Repeat("cat ", 1+i%4) + strings.Repeat("dog ", i%7)
+		docs = append(docs, Document{ID: i, Text: text, Date: time.Date(2024, 1, 1+i%5, 0, 0, 0, 0, time.UTC)})
+	}
+	return docs
+}
+
+func TestRankTop(t *testing.T) {
+	// Recency is measured from a fixed time so scores don't drift between
+	// calls.
+	composite := DefaultCompositeScoring
+	composite.Now = time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name string
+		cfg  Config
+	}{
+		{"bm25", Config{}},
+		{"composite", Config{Scoring: &composite}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			idx := NewIndex(tt.cfg)
+			idx.Add(rankDocs())
+			all := idx.Rank("cat", nil)
+			if len(all) != 50 {
+				t.Fatalf("Rank(cat) = %d results, want 50", len(all))
+			}
+			for _, k := range []int{1, 2, 7, 49, 50, 51, 100} {
+				want := all[:min(k, len(all))]
+				if got := idx.RankTop("cat", k); !slices.EqualFunc(got, want, sameResult) {
+					t.Errorf("RankTop(cat, %d) = %v, want %v", k, got, want)
+				}
+			}
+			for _, k := range []int{0, -1} {
+				if got := idx.RankTop("cat", k); len(got) != 0 {
+					t.Errorf("RankTop(cat, %d) = %v, want none", k, got)
+				}
+			}
+			if got := idx.RankTop("fish", 10); len(got) != 0 {
+				t.Errorf("RankTop(fish, 10) = %v, want none", got)
+			}
+		})
+	}
+}
+
+// sameResult reports whether a and b are the same document with the same
+// score.
+func sameResult(a, b Result) bool {
+	return a.ID == b.ID && a.Score == b.Score
+}
+
+func TestRankPage(t *testing.T) {
+	idx := NewIndex(Config{})
+	idx.Add(rankDocs())
+	all := idx.Rank("cat", nil)
+
+	// The pages put together are the ranking.
+	var pages []Result
+	for from := 0; from < len(all); from += 7 {
+		page := idx.RankPage("cat", from, 7)
+		if page.Total != len(all) {
+			t.Errorf("RankPage(cat, %d, 7).Total = %d, want %d", from, page.Total, len(all))
+		}
+		pages = append(pages, page.Items...)
+	}
+	if !slices.EqualFunc(pages, all, sameResult) {
+		t.Errorf("RankPage(cat) pages = %v, want %v", pages, all)
+	}
+
+	tests := []struct {
+		name       string
+		from, size int
+		want       int
+	}{
+		{"last page", 49, 10, 1},
+		{"at the end", 50, 10, 0},
+		{"past the end", 100, 10, 0},
+		{"no size", 10, 0, 0},
+		{"negative from", -5, 3, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page := idx.RankPage("cat", tt.from, tt.size)
+			if len(page.Items) != tt.want {
+				t.Errorf("RankPage(cat, %d, %d) = %d results, want %d", tt.from, tt.size, len(page.Items), tt.want)
+			}
+			if page.Total != len(all) {
+				t.Errorf("RankPage(cat, %d, %d).Total = %d, want %d", tt.from, tt.size, page.Total, len(all))
+			}
+		})
+	}
+
+	if page := idx.RankPage("fish", 0, 10); len(page.Items) != 0 || page.Total != 0 {
+		t.Errorf("RankPage(fish, 0, 10) = %v, want an empty page", page)
+	}
+}
+
+func TestSearchResults(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2024, 3, d, 0, 0, 0, 0, time.UTC) }
+	idx := NewIndex(Config{})
+	idx.Add([]Document{
+		{ID: 4, Title: "Cats", URL: "https://example.com/cats", Text: "a cat sat", Date: day(4)},
+		{ID: 1, Title: "Dogs", URL: "https://example.com/dogs", Text: "a dog ran", Date: day(1)},
+		{ID: 2, Title: "Pets", URL: "https://example.com/pets", Text: "a cat and a dog", Date: day(2)},
+	})
+
+	tests := []struct {
+		query string
+		want  []Result
+	}{
+		{"cat", []Result{
+			{ID: 2, Title: "Pets", URL: "https://example.com/pets", Date: day(2)},
+			{ID: 4, Title: "Cats", URL: "https://example.com/cats", Date: day(4)},
+		}},
+		{"dog", []Result{
+			{ID: 1, Title: "Dogs", URL: "https://example.com/dogs", Date: day(1)},
+			{ID: 2, Title: "Pets", URL: "https://example.com/pets", Date: day(2)},
+		}},
+		{"fish", []Result{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			got := idx.SearchResults(tt.query)
+			if !slices.EqualFunc(got, tt.want, func(a, b Result) bool {
+				return a.ID == b.ID && a.Score == b.Score && a.Title == b.Title && a.URL == b.URL && a.Date.Equal(b.Date)
+			}) {
+				t.Errorf("SearchResults(%q) = %v, want %v", tt.query, got, tt.want)
+			}
+			// They are the documents Search finds, in the same order.
+			ids := idx.Search(tt.query)
+			if len(ids) != len(got) {
+				t.Fatalf("Search(%q) = %v, but SearchResults has %d results", tt.query, ids, len(got))
+			}
+			for i, id := range ids {
+				if got[i].ID != id {
+					t.Errorf("SearchResults(%q)[%d].ID = %d, want %d", tt.query, i, got[i].ID, id)
+				}
+			}
+		})
+	}
+}
+
+func TestAppend(t *testing.T) {
+	idx := NewIndex(Config{})
+	if got := idx.NextID(); got != 0 {
+		t.Errorf("NextID() of an empty index = %d, want 0", got)
+	}
+	idx.Add([]Document{{ID: 3, Text: "cat"}, {ID: 7, Text: "dog"}})
+	if got := idx.NextID(); got != 8 {
+		t.Errorf("NextID() = %d, want 8", got)
+	}
+	// Deleted documents keep their IDs from being reused.
+	idx.Delete(7)
+	if got := idx.NextID(); got != 8 {
+		t.Errorf("NextID() after Delete(7) = %d, want 8", got)
+	}
+
+	// A later run loads the index and appends to it.
+	path := filepath.Join(t.TempDir(), "index")
+	if err := idx.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	loaded := NewIndex(Config{})
+	if err := loaded.Load(path); err != nil {
+		t.Fatal(err)
+	}
+	docs := []Document{{ID: 0, Text: "cat and dog"}, {ID: 3, Text: "dog"}}
+	loaded.Append(docs)
+	if got, want := []int{docs[0].ID, docs[1].ID}, []int{8, 9}; !slices.Equal(got, want) {
+		t.Errorf("Append() numbered the documents %v, want %v", got, want)
+	}
+	if got := loaded.NextID(); got != 10 {
+		t.Errorf("NextID() after Append = %d, want 10", got)
+	}
+	for query, want := range map[string][]int{"cat": {3, 8}, "dog": {8, 9}} {
+		if got := loaded.Search(query); !slices.Equal(got, want) {
+			t.Errorf("Search(%s) after Append = %v, want %v", query, got, want)
+		}
+	}
+}
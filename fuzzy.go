@@ -0,0 +1,117 @@
+package fulltextsearch
+
+import (
+	"sort"
+	"strings"
+)
+
+// SubstitutionCost returns the cost of an edit that replaces a with b.
+// Insertions and deletions always cost 1.
+type SubstitutionCost func(a, b rune) float64
+
+// UniformCost makes EditDistance plain Levenshtein distance.
+func UniformCost(a, b rune) float64 {
+	if a == b {
+		return 0
+	}
+	return 1
+}
+
+var qwertyRows = []string{"1234567890", "qwertyuiop", "asdfghjkl", "zxcvbnm"}
+
+// qwertyNeighbors maps each key to the keys touching it on a QWERTY layout.
+var qwertyNeighbors = func() map[rune]string {
+	at := make(map[rune][2]int)
+	for r, row := range qwertyRows {
+		for c, k := range row {
+			at[k] = [2]int{r, c}
+		}
+	}
+	m := make(map[rune]string)
+	for k, p := range at {
+		var b strings.Builder
+		for n, q := range at {
+			// Rows are staggered, so a key touches the one above and to
+			// its right as well as the one below and to its left.
+			dr, dc := q[0]-p[0], q[1]-p[1]
+			if n != k && ((dr == 0 && (dc == 1 || dc == -1)) ||
+				(dr == -1 && (dc == 0 || dc == 1)) ||
+				(dr == 1 && (dc == 0 || dc == -1))) {
+				b.WriteRune(n)
+			}
+		}
+		m[k] = b.String()
+	}
+	return m
+}()
+
+// KeyboardCost charges half as much for substituting a neighboring key,
+// since those are the most common typos.
+func KeyboardCost(a, b rune) float64 {
+	if a == b {
+		return 0
+	}
+	if strings.ContainsRune(qwertyNeighbors[a], b) {
+		return 0.5
+	}
+	return 1
+}
+
+// EditDistance is the weighted Levenshtein distance between a and b.
+func EditDistance(a, b string, sub SubstitutionCost) float64 {
+	if sub == nil {
+		sub = UniformCost
+	}
+	s, t := []rune(a), []rune(b)
+	prev := make([]float64, len(t)+1)
+	cur := make([]float64, len(t)+1)
+	for j := range prev {
+		prev[j] = float64(j)
+	}
+	for i := 1; i <= len(s); i++ {
+		cur[0] = float64(i)
+		for j := 1; j <= len(t); j++ {
+			cur[j] = min(
+				prev[j]+1,
+				cur[j-1]+1,
+				prev[j-1]+sub(s[i-1], t[j-1]),
+			)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(t)]
+}
+
+// Correction is an index term close to a misspelled word.
+type Correction struct {
+	Term     string
+	Distance float64
+	Docs     int
+}
+
+// Corrections returns the terms in the text field within maxDist of the
+// analyzed word, nearest first and then most common first, using the
+// index's substitution costs.
+func (idx *Index) Corrections(word string, maxDist float64) []Correction {
+	f, ok := idx.Fields["text"]
+	terms := idx.analyzer.AnalyzeQuery(word)
+	if !ok || len(terms) != 1 {
+		return nil
+	}
+	var r []Correction
+	for term, ids := range f.Postings.all() {
+		if d := EditDistance(terms[0], term, idx.editCost); d <= maxDist {
+			r = append(r, Correction{Term: term, Distance: d, Docs: len(ids)})
+		}
+	}
+	sort.Slice(r, func(i, j int) bool {
+		if r[i].Distance != r[j].Distance {
+			return r[i].Distance < r[j].Distance
+		}
+		if r[i].Docs != r[j].Docs {
+			return r[i].Docs > r[j].Docs
+		}
+		return r[i].Term < r[j].Term
+	})
+	return r
+}
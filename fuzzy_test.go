@@ -0,0 +1,55 @@
+package fulltextsearch
+
+import "testing"
+
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		a, b string
+		sub  SubstitutionCost
+		want float64
+	}{
+		{"cat", "cat", nil, 0},
+		{"cat", "cst", nil, 1},
+		{"cat", "cst", KeyboardCost, 0.5},
+		{"cat", "cut", KeyboardCost, 1},
+		{"cat", "cats", KeyboardCost, 1},
+		{"kitten", "sitting", UniformCost, 3},
+		{"", "abc", nil, 3},
+	}
+	for _, tt := range tests {
+		if got := EditDistance(tt.a, tt.b, tt.sub); got != tt.want {
+			t.Errorf("EditDistance(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestCorrectionsPreferAdjacentKeys(t *testing.T) {
+	docs := []Document{
+		{ID: 0, Text: "cat"},
+		{ID: 1, Text: "cut"},
+		{ID: 2, Text: "cut"},
+	}
+	tests := []struct {
+		name string
+		cost SubstitutionCost
+		want string
+	}{
+		// both are one substitution away, so the commoner word wins
+		{"uniform", nil, "cut"},
+		// s is next to a on the keyboard but not to u
+		{"keyboard", KeyboardCost, "cat"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			idx := NewIndex(Config{EditCost: tt.cost})
+			idx.Add(docs)
+			got := idx.Corrections("cst", 1)
+			if len(got) != 2 {
+				t.Fatalf("Corrections(cst) = %v, want cat and cut", got)
+			}
+			if got[0].Term != tt.want {
+				t.Errorf("Corrections(cst) = %v, want %s first", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,170 @@
+package fulltextsearch
+
+import "strings"
+
+// The Snowball module this package uses has no German stemmer, so this is
+// the Snowball German algorithm, from
+// https://snowballstem.org/algorithms/german/stemmer.html.
+
+func isGermanVowel(r rune) bool {
+	return strings.ContainsRune("aeiouyäöü", r)
+}
+
+// germanStem returns the Snowball stem of a lowercase German word. Like the
+// other Snowball stemmers it takes whether to stem stopwords, but German
+// stopwords are stemmed regardless.
+func germanStem(word string, _ bool) string {
+	w := []rune(strings.ReplaceAll(word, "ß", "ss"))
+	// u and y between vowels are consonants, marked in upper case until
+	// the end.
+	for i := 1; i+1 < len(w); i++ {
+		if !isGermanVowel(w[i-1]) || !isGermanVowel(w[i+1]) {
+			continue
+		}
+		switch w[i] {
+		case 'u':
+			w[i] = 'U'
+		case 'y':
+			w[i] = 'Y'
+		}
+	}
+	p1, p2 := germanRegions(w)
+	w = germanStep1(w, p1)
+	w = germanStep2(w, p1)
+	w = germanStep3(w, p1, p2)
+	for i, r := range w {
+		switch r {
+		case 'U', 'ü':
+			w[i] = 'u'
+		case 'Y':
+			w[i] = 'y'
+		case 'ä':
+			w[i] = 'a'
+		case 'ö':
+			w[i] = 'o'
+		}
+	}
+	return string(w)
+}
+
+// germanRegions returns the starts of the regions R1 and R2: R1 begins
+// after the first non-vowel following a vowel, but no sooner than the
+// fourth letter, and R2 is the same again within R1.
+func germanRegions(w []rune) (p1, p2 int) {
+	p1, p2 = len(w), len(w)
+	if len(w) < 3 {
+		return p1, p2
+	}
+	i := afterVowelConsonant(w, 0)
+	if i == len(w) {
+		return p1, p2
+	}
+	p1 = max(i, 3)
+	p2 = afterVowelConsonant(w, i)
+	return p1, p2
+}
+
+// afterVowelConsonant returns the position after the first non-vowel that
+// follows a vowel in w from start on, or len(w) if there is none.
+func afterVowelConsonant(w []rune, start int) int {
+	for i := start + 1; i < len(w); i++ {
+		if isGermanVowel(w[i-1]) && !isGermanVowel(w[i]) {
+			return i + 1
+		}
+	}
+	return len(w)
+}
+
+// longestSuffix returns the longest of suffixes that w ends with, or "".
+func longestSuffix(w []rune, suffixes ...string) string {
+	best := ""
+	for _, s := range suffixes {
+		if len([]rune(s)) > len([]rune(best)) && hasRuneSuffix(w, s) {
+			best = s
+		}
+	}
+	return best
+}
+
+func hasRuneSuffix(w []rune, s string) bool {
+	r := []rune(s)
+	return len(w) >= len(r) && string(w[len(w)-len(r):]) == s
+}
+
+// inRegion reports whether suffix s of w lies within the region from p on.
+func inRegion(w []rune, s string, p int) bool {
+	return len(w)-len([]rune(s)) >= p
+}
+
+func trimRunes(w []rune, s string) []rune {
+	return w[:len(w)-len([]rune(s))]
+}
+
+// germanStep1 removes inflectional endings such as -en and -es.
+func germanStep1(w []rune, p1 int) []rune {
+	s := longestSuffix(w, "em", "ern", "er", "e", "en", "es", "s")
+	if s == "" || !inRegion(w, s, p1) {
+		return w
+	}
+	switch s {
+	case "em", "ern", "er":
+		return trimRunes(w, s)
+	case "e", "en", "es":
+		w = trimRunes(w, s)
+		if hasRuneSuffix(w, "niss") {
+			w = w[:len(w)-1]
+		}
+		return w
+	default: // s
+		if len(w) >= 2 && strings.ContainsRune("bdfghklmnrt", w[len(w)-2]) {
+			return w[:len(w)-1]
+		}
+		return w
+	}
+}
+
+// germanStep2 removes further endings such as -est and -st.
+func germanStep2(w []rune, p1 int) []rune {
+	s := longestSuffix(w, "en", "er", "est", "st")
+	if s == "" || !inRegion(w, s, p1) {
+		return w
+	}
+	if s != "st" {
+		return trimRunes(w, s)
+	}
+	// st follows a valid ending itself preceded by at least 3 letters.
+	if j := len(w) - 3; j >= 3 && strings.ContainsRune("bdfghklmnt", w[j]) {
+		return trimRunes(w, s)
+	}
+	return w
+}
+
+// germanStep3 removes derivational suffixes such as -ung and -keit.
+func germanStep3(w []rune, p1, p2 int) []rune {
+	s := longestSuffix(w, "end", "ung", "ig", "ik", "isch", "lich", "heit", "keit")
+	if s == "" || !inRegion(w, s, p2) {
+		return w
+	}
+	switch s {
+	case "end", "ung":
+		w = trimRunes(w, s)
+		if hasRuneSuffix(w, "ig") && inRegion(w, "ig", p2) && !hasRuneSuffix(w, "eig") {
+			w = trimRunes(w, "ig")
+		}
+	case "ig", "ik", "isch":
+		if !hasRuneSuffix(trimRunes(w, s), "e") {
+			w = trimRunes(w, s)
+		}
+	case "lich", "heit":
+		w = trimRunes(w, s)
+		if t := longestSuffix(w, "er", "en"); t != "" && inRegion(w, t, p1) {
+			w = trimRunes(w, t)
+		}
+	case "keit":
+		w = trimRunes(w, s)
+		if t := longestSuffix(w, "lich", "ig"); t != "" && inRegion(w, t, p2) {
+			w = trimRunes(w, t)
+		}
+	}
+	return w
+}
@@ -0,0 +1,45 @@
+package fulltextsearch
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestGermanStem(t *testing.T) {
+	tests := []struct {
+		word, want string
+	}{
+		{"aufeinanderfolgenden", "aufeinanderfolg"},
+		{"aufeinanderfolgte", "aufeinanderfolgt"},
+		{"kategorie", "kategori"},
+		{"kategorien", "kategori"},
+		{"häuser", "haus"},
+		{"haus", "haus"},
+		{"straße", "strass"},
+		{"ergebnisse", "ergebnis"},
+		{"zeitung", "zeitung"},
+		{"bedeutung", "bedeut"},
+		{"freundlichkeit", "freundlich"},
+		{"abenteuerlich", "abenteu"},
+		{"bauer", "bau"},
+		{"zu", "zu"},
+	}
+	for _, tt := range tests {
+		if got := germanStem(tt.word, false); got != tt.want {
+			t.Errorf("germanStem(%q) = %q, want %q", tt.word, got, tt.want)
+		}
+	}
+}
+
+func TestGermanAnalyzer(t *testing.T) {
+	idx := NewIndex(Config{Analyzer: Analyzer{Language: "de"}})
+	idx.Add([]Document{
+		{ID: 0, Text: "Die Häuser der Stadt"},
+		{ID: 1, Text: "Ein Bauer auf dem Feld"},
+	})
+	for query, want := range map[string][]int{"Haus": {0}, "Bauern": {1}} {
+		if got := idx.Search(query); !slices.Equal(got, want) {
+			t.Errorf("Search(%q) = %v, want %v", query, got, want)
+		}
+	}
+}
@@ -0,0 +1,10 @@
+module github.com/InterruptSpeed/fulltextsearch
+
+go 1.26.0
+
+require (
+	github.com/kljensen/snowball v0.10.0
+	github.com/ledongthuc/pdf v0.0.0-20260907135840-6c8c28e0e8a0
+	golang.org/x/net v0.59.0
+	golang.org/x/text v0.42.0
+)
@@ -0,0 +1,64 @@
+package fulltextsearch
+
+import (
+	"html"
+	"html/template"
+	"strings"
+)
+
+// HighlightHTML returns text escaped for embedding in HTML, with each token
+// matching a query term wrapped in <mark>. Marks are inserted around the
+// escaped token, so markup in the source can never reach the page.
+func (a Analyzer) HighlightHTML(text, query string) template.HTML {
+	spans := tokenizeSpans(text)
+	var b strings.Builder
+	last := 0
+	for _, i := range a.matchingSpans(spans, query) {
+		sp := spans[i]
+		b.WriteString(html.EscapeString(text[last:sp.Start]))
+		b.WriteString("<mark>")
+		b.WriteString(html.EscapeString(sp.Text))
+		b.WriteString("</mark>")
+		last = sp.End
+	}
+	b.WriteString(html.EscapeString(text[last:]))
+	return template.HTML(b.String())
+}
+
+// TermRange locates one query term match in a document's text, as byte
+// offsets, for clients that do their own highlighting.
+type TermRange struct {
+	Term  string `json:"term"`
+	Start int    `json:"start"`
+	End   int    `json:"end"`
+}
+
+// MatchRanges returns where each query term matches in text. Term is the
+// analyzed query term the token matched.
+func (a Analyzer) MatchRanges(text, query string) []TermRange {
+	terms := make(map[string]struct{})
+	for _, term := range a.AnalyzeQuery(query) {
+		terms[term] = struct{}{}
+	}
+	var r []TermRange
+	for _, sp := range tokenizeSpans(text) {
+		for _, term := range a.Analyze(sp.Text) {
+			if _, ok := terms[term]; ok {
+				r = append(r, TermRange{Term: term, Start: sp.Start, End: sp.End})
+				break
+			}
+		}
+	}
+	return r
+}
+
+// AddMatches fills in Matches for each result from its stored text.
+func (idx *Index) AddMatches(results []Result, query string) error {
+	if idx.store == nil {
+		return ErrNoStore
+	}
+	for i := range results {
+		results[i].Matches = idx.analyzer.MatchRanges(idx.store.get(results[i].ID).Text, query)
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package fulltextsearch
+
+import (
+	"html/template"
+	"slices"
+	"testing"
+)
+
+func TestHighlightHTML(t *testing.T) {
+	tests := []struct {
+		text, query string
+		want        template.HTML
+	}{
+		{"cats & dogs", "cat", "<mark>cats</mark> &amp; dogs"},
+		{`<script>alert("cat")</script>`, "cat",
+			`&lt;script&gt;alert(&#34;<mark>cat</mark>&#34;)&lt;/script&gt;`},
+		{`<script>alert(1)</script>`, "script",
+			`&lt;<mark>script</mark>&gt;alert(1)&lt;/<mark>script</mark>&gt;`},
+		{"<b>no match</b>", "cat", "&lt;b&gt;no match&lt;/b&gt;"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			if got := (Analyzer{}).HighlightHTML(tt.text, tt.query); got != tt.want {
+				t.Errorf("HighlightHTML(%q, %q) = %s, want %s", tt.text, tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatchRanges(t *testing.T) {
+	// offsets are in bytes, and é takes two
+	text := "Cats chase mice; the café's cat naps."
+	tests := []struct {
+		query string
+		want  []TermRange
+	}{
+		{"cat", []TermRange{{"cat", 0, 4}, {"cat", 29, 32}}},
+		{"chasing naps", []TermRange{{"chase", 5, 10}, {"nap", 33, 37}}},
+		{"dog", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			got := Analyzer{}.MatchRanges(text, tt.query)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("MatchRanges(%q) = %v, want %v", tt.query, got, tt.want)
+			}
+			for _, r := range got {
+				if a := (Analyzer{}).Analyze(text[r.Start:r.End]); !slices.Contains(a, r.Term) {
+					t.Errorf("text[%d:%d] = %q, which doesn't analyze to %q", r.Start, r.End, text[r.Start:r.End], r.Term)
+				}
+			}
+		})
+	}
+
+	idx := NewIndex(Config{StoreDocuments: true})
+	idx.Add([]Document{{ID: 0, Text: text}})
+	results := idx.Rank("cat", nil)
+	if err := idx.AddMatches(results, "cat"); err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || len(results[0].Matches) != 2 {
+		t.Fatalf("AddMatches() = %+v, want one result with two matches", results)
+	}
+}
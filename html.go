@@ -0,0 +1,128 @@
+package fulltextsearch
+
+import (
+	"io"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// descriptionField is the extra field a page's meta description is
+// indexed as.
+const descriptionField = "description"
+
+// hiddenElements hold no text a reader of the page would see.
+var hiddenElements = map[atom.Atom]bool{
+	atom.Head:     true,
+	atom.Script:   true,
+	atom.Style:    true,
+	atom.Noscript: true,
+	atom.Template: true,
+	atom.Svg:      true,
+	atom.Iframe:   true,
+}
+
+// blockElements break the text of a page into lines, where inline ones
+// like <b> run on within their line.
+var blockElements = map[atom.Atom]bool{
+	atom.Address: true, atom.Article: true, atom.Aside: true,
+	atom.Blockquote: true, atom.Br: true, atom.Dd: true, atom.Div: true,
+	atom.Dl: true, atom.Dt: true, atom.Figcaption: true, atom.Figure: true,
+	atom.Footer: true, atom.Form: true, atom.H1: true, atom.H2: true,
+	atom.H3: true, atom.H4: true, atom.H5: true, atom.H6: true,
+	atom.Header: true, atom.Hr: true, atom.Li: true, atom.Main: true,
+	atom.Nav: true, atom.Ol: true, atom.P: true, atom.Pre: true,
+	atom.Section: true, atom.Table: true, atom.Td: true, atom.Th: true,
+	atom.Tr: true, atom.Ul: true,
+}
+
+// ParseHTML reads a web page from r as a document: the <title> is the
+// title, the visible text of the body the text, one line to a paragraph or
+// other block, and the meta description, if any, the "description" extra
+// field. Scripts, styles and the like are dropped. Malformed pages are
+// parsed as a browser would. The page is taken to be UTF-8.
+func ParseHTML(r io.Reader) (Document, error) {
+	root, err := html.Parse(r)
+	if err != nil {
+		return Document{}, err
+	}
+	var doc Document
+	var text strings.Builder
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		switch {
+		case n.Type == html.TextNode:
+			text.WriteString(n.Data)
+			return
+		case n.Type == html.ElementNode && n.DataAtom == atom.Title:
+			if doc.Title == "" {
+				doc.Title = collapseSpace(nodeText(n))
+			}
+			return
+		case n.Type == html.ElementNode && n.DataAtom == atom.Meta:
+			if strings.EqualFold(attr(n, "name"), "description") {
+				doc.Extra = map[string]string{descriptionField: collapseSpace(attr(n, "content"))}
+			}
+		}
+		if n.Type == html.ElementNode && hiddenElements[n.DataAtom] {
+			// The head is only searched for the title and metadata.
+			if n.DataAtom == atom.Head {
+				for c := n.FirstChild; c != nil; c = c.NextSibling {
+					if c.Type == html.ElementNode {
+						walk(c)
+					}
+				}
+			}
+			return
+		}
+		block := n.Type == html.ElementNode && blockElements[n.DataAtom]
+		if block {
+			text.WriteByte('\n')
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+		if block {
+			text.WriteByte('\n')
+		}
+	}
+	walk(root)
+
+	var lines []string
+	for _, line := range strings.Split(text.String(), "\n") {
+		if line = collapseSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	doc.Text = strings.Join(lines, "\n")
+	return doc, nil
+}
+
+// nodeText returns all the text under n.
+func nodeText(n *html.Node) string {
+	var b strings.Builder
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.TextNode {
+			b.WriteString(c.Data)
+		} else {
+			b.WriteString(nodeText(c))
+		}
+	}
+	return b.String()
+}
+
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+// collapseSpace trims s and turns each run of white space in it into a
+// single space.
+func collapseSpace(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
@@ -0,0 +1,73 @@
+package fulltextsearch
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestParseHTML(t *testing.T) {
+	tests := []struct {
+		name        string
+		page        string
+		title       string
+		text        string
+		description string
+	}{
+		{
+			name: "page",
+			page: `<!DOCTYPE html><html><head>
+<title> Glazed
+  Donuts </title>
+<meta name="Description" content="How to glaze  a donut.">
+<style>p { color: red }</style>
+</head><body>
+<h1>Donuts</h1>
+<p>A <b>glazed</b> donut &amp; a <i>bagel</i>.</p>
+<script>alert("hidden")</script><noscript>Enable scripts</noscript>
+<ul><li>flour</li><li>sugar</li></ul>
+</body></html>`,
+			title:       "Glazed Donuts",
+			text:        "Donuts\nA glazed donut & a bagel.\nflour\nsugar",
+			description: "How to glaze a donut.",
+		},
+		{
+			name:  "malformed",
+			page:  `<title>Bagels</title><p>Toasted<p>with <b>cream cheese`,
+			title: "Bagels",
+			text:  "Toasted\nwith cream cheese",
+		},
+		{
+			name: "fragment",
+			page: `A glass plate`,
+			text: "A glass plate",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := ParseHTML(strings.NewReader(tt.page))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if doc.Title != tt.title {
+				t.Errorf("title = %q, want %q", doc.Title, tt.title)
+			}
+			if doc.Text != tt.text {
+				t.Errorf("text = %q, want %q", doc.Text, tt.text)
+			}
+			if got := doc.Extra[descriptionField]; got != tt.description {
+				t.Errorf("description = %q, want %q", got, tt.description)
+			}
+		})
+	}
+}
+
+func TestParseHTMLReadError(t *testing.T) {
+	broken := errors.New("broken")
+	r := io.MultiReader(strings.NewReader("<p>A glazed"), iotest.ErrReader(broken))
+	if _, err := ParseHTML(r); !errors.Is(err, broken) {
+		t.Errorf("ParseHTML() = %v, want %v", err, broken)
+	}
+}
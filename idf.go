@@ -0,0 +1,58 @@
+package fulltextsearch
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// idfCache holds the IDF of every term in every field. It is computed in
+// one pass on the first ranked search after the index last changed, since
+// recomputing IDFs for common terms on every query adds up.
+type idfCache struct {
+	mu   sync.Mutex // serializes rebuilds
+	idfs atomic.Pointer[map[string]map[string]float64]
+}
+
+func (c *idfCache) reset() {
+	c.idfs.Store(nil)
+}
+
+// idf returns the IDF of term in the named field for the index's
+// similarity. BM25 IDFs come from the cache when idx.cacheIDF is set; TF-IDF
+// ones are cheap enough not to bother.
+func (idx *Index) idf(name, term string) float64 {
+	f := idx.Fields[name]
+	if idx.similarity == TFIDF {
+		return f.classicIDF(term)
+	}
+	if !idx.cacheIDF {
+		return f.idf(term)
+	}
+	c := idx.idfs
+	m := c.idfs.Load()
+	if m == nil {
+		c.mu.Lock()
+		if m = c.idfs.Load(); m == nil {
+			m = idx.buildIDF()
+			c.idfs.Store(m)
+		}
+		c.mu.Unlock()
+	}
+	if v, ok := (*m)[name][term]; ok {
+		return v
+	}
+	// Terms missing from the field still get an IDF, for a df of zero.
+	return f.idf(term)
+}
+
+func (idx *Index) buildIDF() *map[string]map[string]float64 {
+	m := make(map[string]map[string]float64, len(idx.Fields))
+	for name, f := range idx.Fields {
+		idfs := make(map[string]float64, f.Postings.len())
+		for term := range f.Postings.keys() {
+			idfs[term] = f.idf(term)
+		}
+		m[name] = idfs
+	}
+	return &m
+}
@@ -0,0 +1,70 @@
+package fulltextsearch
+
+import (
+	"math/rand/v2"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// syntheticDocs returns n documents of words drawn from a vocabulary of a
+// few thousand, the first few of which are very common, as in real text.
+func syntheticDocs(n int) []Document {
+	rng := rand.New(rand.NewPCG(1, 2))
+	vocab := make([]string, 5000)
+	for i := range vocab {
+		vocab[i] = "w" + strconv.Itoa(i)
+	}
+	docs := make([]Document, n)
+	for i := range docs {
+		words := make([]string, 40)
+		for j := range words {
+			words[j] = vocab[int(rng.ExpFloat64()*200)%len(vocab)]
+		}
+		docs[i] = Document{ID: i, Text: strings.Join(words, " ")}
+	}
+	return docs
+}
+
+func TestIDFCache(t *testing.T) {
+	docs := syntheticDocs(200)
+	cached := NewIndex(Config{})
+	uncached := NewIndex(Config{DisableIDFCache: true})
+	query := strings.Fields(docs[0].Text)[0] + " " + strings.Fields(docs[0].Text)[1]
+	for _, batch := range [][]Document{docs[:100], docs[100:]} {
+		// the second batch changes every IDF, which the cache must notice
+		cached.Add(batch)
+		uncached.Add(batch)
+		want := uncached.Rank(query, ByID{})
+		got := cached.Rank(query, ByID{})
+		if len(got) == 0 || len(got) != len(want) {
+			t.Fatalf("Rank(%s) = %d results with the cache, %d without", query, len(got), len(want))
+		}
+		for i := range got {
+			if got[i].ID != want[i].ID || got[i].Score != want[i].Score {
+				t.Errorf("Rank(%s)[%d] = %+v with the cache, %+v without", query, i, got[i], want[i])
+			}
+		}
+	}
+}
+
+func BenchmarkRank(b *testing.B) {
+	docs := syntheticDocs(20000)
+	for _, bm := range []struct {
+		name string
+		cfg  Config
+	}{
+		{"cached IDF", Config{}},
+		{"uncached IDF", Config{DisableIDFCache: true}},
+	} {
+		b.Run(bm.name, func(b *testing.B) {
+			idx := NewIndex(bm.cfg)
+			idx.Add(docs)
+			idx.Rank(strings.Fields(docs[0].Text)[0], nil) // builds the cache, if there is one
+			for i := 0; b.Loop(); i++ {
+				words := strings.Fields(docs[i%len(docs)].Text)
+				idx.Rank(words[0]+" "+words[1], nil)
+			}
+		})
+	}
+}
@@ -0,0 +1,217 @@
+package fulltextsearch
+
+import (
+	"bufio"
+	"compress/gzip"
+	"crypto/sha1"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FieldMapping names the source fields each document field is loaded from,
+// for indexing documents that don't follow the abstract dump's schema.
+// Document fields whose source name is empty are left unset.
+type FieldMapping struct {
+	// ID is the field holding the document ID, a whole number. Without
+	// one, documents are numbered in the order they are read.
+	ID    string
+	Title string
+	URL   string
+	Text  string
+	// Date is parsed as RFC 3339 or as a plain 2006-01-02 date.
+	Date string
+	// Tags is a list, or a string of comma-separated tags.
+	Tags string
+	// Extra maps source fields to the names of extra document fields.
+	Extra map[string]string
+}
+
+// DefaultMapping loads each document field from the source field of the
+// same name in lower case.
+var DefaultMapping = FieldMapping{
+	ID:    "id",
+	Title: "title",
+	URL:   "url",
+	Text:  "text",
+	Date:  "date",
+	Tags:  "tags",
+}
+
+// LoadJSON reads the documents of the JSON array in the file at path,
+// mapping their fields according to m.
+func LoadJSON(path string, m FieldMapping) ([]Document, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var docs []Document
+	err = StreamJSON(f, m, func(doc Document) error {
+		docs = append(docs, doc)
+		return nil
+	})
+	return docs, err
+}
+
+// StreamJSON decodes a JSON array of objects from r one element at a time,
+// calling fn with each as a document mapped according to m. It stops at
+// the first error fn returns and returns it.
+func StreamJSON(r io.Reader, m FieldMapping, fn func(Document) error) error {
+	decoder := json.NewDecoder(r)
+	decoder.UseNumber()
+	if tok, err := decoder.Token(); err != nil {
+		return err
+	} else if tok != json.Delim('[') {
+		return fmt.Errorf("expected a JSON array of documents, found %v", tok)
+	}
+	for n := 0; decoder.More(); n++ {
+		var fields map[string]any
+		if err := decoder.Decode(&fields); err != nil {
+			return fmt.Errorf("document %d: %w", n, err)
+		}
+		doc, err := m.document(fields, n)
+		if err != nil {
+			return fmt.Errorf("document %d: %w", n, err)
+		}
+		if err := fn(doc); err != nil {
+			return err
+		}
+	}
+	_, err := decoder.Token()
+	return err
+}
+
+// StreamNDJSON decodes the newline-delimited JSON at path, or standard
+// input if path is "-", like StreamNDJSONReader.
+func StreamNDJSON(path string, m FieldMapping, fn func(Document) error) error {
+	if path == "-" {
+		return StreamNDJSONReader(os.Stdin, m, fn)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return StreamNDJSONReader(f, m, fn)
+}
+
+// StreamNDJSONReader decodes newline-delimited JSON from r, one object per
+// line, calling fn with each as a document mapped according to m, so
+// documents can be piped in from any other tool. Gzipped input is
+// detected and decompressed. It stops at the first error fn returns and
+// returns it.
+func StreamNDJSONReader(r io.Reader, m FieldMapping, fn func(Document) error) error {
+	br := bufio.NewReader(r)
+	if magic, _ := br.Peek(len(gzipMagic)); string(magic) == gzipMagic {
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return err
+		}
+		defer gz.Close()
+		r = gz
+	} else {
+		r = br
+	}
+	decoder := json.NewDecoder(r)
+	decoder.UseNumber()
+	for n := 0; ; n++ {
+		var fields map[string]any
+		if err := decoder.Decode(&fields); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("document %d: %w", n, err)
+		}
+		doc, err := m.document(fields, n)
+		if err != nil {
+			return fmt.Errorf("document %d: %w", n, err)
+		}
+		if err := fn(doc); err != nil {
+			return err
+		}
+	}
+}
+
+// document maps the source fields of the nth document read.
+func (m FieldMapping) document(fields map[string]any, n int) (Document, error) {
+	doc := Document{
+		ID:    n,
+		Title: fieldString(fields[m.Title]),
+		URL:   fieldString(fields[m.URL]),
+		Text:  fieldString(fields[m.Text]),
+	}
+	if v, ok := fields[m.ID]; ok && m.ID != "" {
+		id, err := strconv.Atoi(fieldString(v))
+		if err != nil {
+			return doc, fmt.Errorf("field %s: invalid ID %v", m.ID, v)
+		}
+		doc.ID = id
+	}
+	if s := fieldString(fields[m.Date]); s != "" {
+		date, err := parseDate(s)
+		if err != nil {
+			return doc, fmt.Errorf("field %s: %w", m.Date, err)
+		}
+		doc.Date = date
+	}
+	switch tags := fields[m.Tags].(type) {
+	case []any:
+		for _, tag := range tags {
+			doc.Tags = append(doc.Tags, fieldString(tag))
+		}
+	case string:
+		for _, tag := range strings.Split(tags, ",") {
+			if tag = strings.TrimSpace(tag); tag != "" {
+				doc.Tags = append(doc.Tags, tag)
+			}
+		}
+	}
+	for from, to := range m.Extra {
+		if s := fieldString(fields[from]); s != "" {
+			if doc.Extra == nil {
+				doc.Extra = make(map[string]string)
+			}
+			doc.Extra[to] = s
+		}
+	}
+	if doc.URL != "" {
+		sum := sha1.Sum([]byte(doc.URL))
+		doc.URLSHA1 = sum[:]
+	}
+	return doc, nil
+}
+
+// fieldString returns a source field value as text. Lists are joined one
+// item to a line, and missing values are empty.
+func fieldString(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case []any:
+		items := make([]string, len(v))
+		for i, item := range v {
+			items[i] = fieldString(item)
+		}
+		return strings.Join(items, "\n")
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// dateLayouts are the layouts dates in source fields are parsed with.
+var dateLayouts = []string{time.RFC3339, time.DateOnly}
+
+func parseDate(s string) (time.Time, error) {
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid date %q", s)
+}
@@ -0,0 +1,150 @@
+package fulltextsearch
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadJSON(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "docs.json")
+	content := `[
+		{"id": 7, "title": "Donuts", "url": "https://example.com/donuts", "text": "A glazed donut.", "date": "2024-03-01", "tags": ["sweet", "fried"], "author": "Homer"},
+		{"title": "Bagels", "body": "ignored", "text": ["A toasted bagel.", "With cream cheese."], "date": "2024-03-02T10:00:00Z", "tags": "savory, baked"}
+	]`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	m := DefaultMapping
+	m.Extra = map[string]string{"author": "by"}
+	docs, err := LoadJSON(path, m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Document{
+		{ID: 7, Title: "Donuts", URL: "https://example.com/donuts", Text: "A glazed donut.",
+			Date: time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), Tags: []string{"sweet", "fried"}, Extra: map[string]string{"by": "Homer"}},
+		{ID: 1, Title: "Bagels", Text: "A toasted bagel.\nWith cream cheese.",
+			Date: time.Date(2024, 3, 2, 10, 0, 0, 0, time.UTC), Tags: []string{"savory", "baked"}},
+	}
+	if len(docs) != len(want) {
+		t.Fatalf("LoadJSON() = %d documents, want %d", len(docs), len(want))
+	}
+	for i, doc := range docs {
+		w := want[i]
+		if doc.ID != w.ID || doc.Title != w.Title || doc.URL != w.URL || doc.Text != w.Text ||
+			!doc.Date.Equal(w.Date) || !slices.Equal(doc.Tags, w.Tags) || doc.Extra["by"] != w.Extra["by"] {
+			t.Errorf("document %d = %+v, want %+v", i, doc, w)
+		}
+		if (doc.URL != "") != (len(doc.URLSHA1) > 0) {
+			t.Errorf("document %d has URL %q but URLSHA1 %x", i, doc.URL, doc.URLSHA1)
+		}
+	}
+}
+
+func TestStreamJSONMalformed(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		// docs is how many documents are read before the error.
+		docs int
+	}{
+		{"not an array", `{"title": "Donuts"}`, 0},
+		{"bad element", `[{"title": "Donuts"}, {"title": ]`, 1},
+		{"truncated", `[{"title": "Donuts"}, {"title": "Bag`, 1},
+		{"unclosed array", `[{"title": "Donuts"}`, 1},
+		{"invalid ID", `[{"id": "seven"}]`, 0},
+		{"invalid date", `[{"date": "March 1st"}]`, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var docs int
+			err := StreamJSON(strings.NewReader(tt.input), DefaultMapping, func(Document) error {
+				docs++
+				return nil
+			})
+			if err == nil {
+				t.Error("StreamJSON() error = nil, want an error")
+			}
+			if docs != tt.docs {
+				t.Errorf("StreamJSON() read %d documents before failing, want %d", docs, tt.docs)
+			}
+		})
+	}
+}
+
+func TestStreamNDJSON(t *testing.T) {
+	const input = `{"title": "Donuts", "text": "A glazed donut."}
+
+{"title": "Bagels", "text": "A toasted bagel."}
+`
+	var gz bytes.Buffer
+	w := gzip.NewWriter(&gz)
+	io.WriteString(w, input)
+	w.Close()
+	tests := []struct {
+		name  string
+		input []byte
+	}{
+		{"plain", []byte(input)},
+		{"gzipped", gz.Bytes()},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "docs.ndjson")
+			if err := os.WriteFile(path, tt.input, 0644); err != nil {
+				t.Fatal(err)
+			}
+			var titles []string
+			err := StreamNDJSON(path, DefaultMapping, func(doc Document) error {
+				if doc.ID != len(titles) {
+					t.Errorf("document %q ID = %d, want %d", doc.Title, doc.ID, len(titles))
+				}
+				titles = append(titles, doc.Title)
+				return nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := []string{"Donuts", "Bagels"}; !slices.Equal(titles, want) {
+				t.Errorf("titles = %q, want %q", titles, want)
+			}
+		})
+	}
+}
+
+func TestStreamNDJSONMalformed(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		// docs is how many documents are read before the error.
+		docs int
+	}{
+		{"bad line", "{\"title\": \"Donuts\"}\n{\"title\": Bagels}\n{\"title\": \"Plates\"}\n", 1},
+		{"truncated line", "{\"title\": \"Donuts\"}\n{\"title\": \"Bag", 1},
+		{"not an object", "{\"title\": \"Donuts\"}\n[1, 2]\n", 1},
+		{"invalid date", "{\"date\": \"yesterday\"}\n", 0},
+		{"truncated gzip", "\x1f\x8b\x08\x00", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var docs int
+			err := StreamNDJSONReader(strings.NewReader(tt.input), DefaultMapping, func(Document) error {
+				docs++
+				return nil
+			})
+			if err == nil {
+				t.Error("StreamNDJSONReader() error = nil, want an error")
+			}
+			if docs != tt.docs {
+				t.Errorf("StreamNDJSONReader() read %d documents before failing, want %d", docs, tt.docs)
+			}
+		})
+	}
+}
@@ -0,0 +1,75 @@
+package fulltextsearch
+
+import (
+	"maps"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// langField is the exact keyword field holding each document's language,
+// so queries can filter on it with lang:fr.
+const langField = "lang"
+
+// minLanguageHits is how many stopwords text must contain before
+// DetectLanguage trusts the language they come from.
+const minLanguageHits = 2
+
+// DetectLanguage guesses the ISO 639-1 code of the language text is written
+// in, or returns "" if it can't tell. Text mostly in Cyrillic is taken to be
+// Russian; otherwise the language is the one whose StopwordLists entry
+// matches the most words, since function words are both frequent and
+// distinctive.
+func DetectLanguage(text string) string {
+	var letters, cyrillic int
+	for _, r := range text {
+		if unicode.IsLetter(r) {
+			letters++
+			if unicode.Is(unicode.Cyrillic, r) {
+				cyrillic++
+			}
+		}
+	}
+	if letters > 0 && cyrillic*2 > letters {
+		return "ru"
+	}
+
+	tokens := lowercaseFilter(tokenize(text))
+	best, bestHits := "", 0
+	for _, lang := range slices.Sorted(maps.Keys(StopwordLists)) {
+		hits := 0
+		for _, token := range tokens {
+			if _, ok := StopwordLists[lang][token]; ok {
+				hits++
+			}
+		}
+		if hits > bestHits {
+			best, bestHits = lang, hits
+		}
+	}
+	if bestHits < minLanguageHits {
+		return ""
+	}
+	return best
+}
+
+// analyzerFor returns the analyzer for doc: the index's own, switched to
+// doc's language and that language's stopwords if it has a different one.
+func (idx *Index) analyzerFor(doc Document) Analyzer {
+	a := idx.analyzer
+	lang := a.Language
+	if lang == "" {
+		lang = "en"
+	}
+	if doc.Language == "" || doc.Language == lang {
+		return a
+	}
+	a.Language = doc.Language
+	a.Stopwords = nil
+	return a
+}
+
+// languageText is the part of doc that its language is detected from.
+func (doc Document) languageText() string {
+	return strings.Join([]string{doc.Title, doc.Text}, "\n")
+}
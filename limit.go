@@ -0,0 +1,69 @@
+package fulltextsearch
+
+import (
+	"errors"
+	"sync"
+)
+
+var ErrSaturated = errors.New("too many concurrent searches")
+
+// SearchLimiter bounds the number of searches running at once. It is meant
+// to be shared by everything that fans searches out across goroutines, so
+// a burst of requests can't exhaust the process.
+type SearchLimiter struct {
+	slots chan struct{}
+	// Queue makes callers wait for a free slot. Otherwise a search is
+	// rejected with ErrSaturated when every slot is taken.
+	Queue bool
+}
+
+func NewSearchLimiter(limit int, queue bool) *SearchLimiter {
+	if limit < 1 {
+		limit = 1
+	}
+	return &SearchLimiter{slots: make(chan struct{}, limit), Queue: queue}
+}
+
+// Do runs fn once a slot is free.
+func (l *SearchLimiter) Do(fn func()) error {
+	if l.Queue {
+		l.slots <- struct{}{}
+	} else {
+		select {
+		case l.slots <- struct{}{}:
+		default:
+			return ErrSaturated
+		}
+	}
+	defer func() { <-l.slots }()
+	fn()
+	return nil
+}
+
+// SearchBatch runs queries against idx in parallel on as many goroutines
+// as the limiter has slots, so a batch larger than the limit is never
+// rejected for want of slots it holds itself. Searches run elsewhere can
+// still take the slots, and without Queue the queries that find none are
+// rejected. It returns the errors of every query that didn't run, joined,
+// with results for those that did.
+func (l *SearchLimiter) SearchBatch(idx *Index, queries []string) ([][]int, error) {
+	r := make([][]int, len(queries))
+	errs := make([]error, len(queries))
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for range min(cap(l.slots), len(queries)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				errs[i] = l.Do(func() { r[i] = idx.Search(queries[i]) })
+			}
+		}()
+	}
+	for i := range queries {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+	return r, errors.Join(errs...)
+}
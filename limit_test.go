@@ -0,0 +1,86 @@
+package fulltextsearch
+
+import (
+	"errors"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSearchLimiterBurst(t *testing.T) {
+	tests := []struct {
+		name  string
+		limit int
+		queue bool
+	}{
+		{"queueing", 3, true},
+		{"rejecting", 3, false},
+		{"single slot", 1, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := NewSearchLimiter(tt.limit, tt.queue)
+			var running, peak atomic.Int32
+			var ran, rejected atomic.Int32
+			var wg sync.WaitGroup
+			for range 50 {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					err := l.Do(func() {
+						n := running.Add(1)
+						for {
+							p := peak.Load()
+							if n <= p || peak.CompareAndSwap(p, n) {
+								break
+							}
+						}
+						time.Sleep(time.Millisecond)
+						running.Add(-1)
+						ran.Add(1)
+					})
+					if errors.Is(err, ErrSaturated) {
+						rejected.Add(1)
+					} else if err != nil {
+						t.Error(err)
+					}
+				}()
+			}
+			wg.Wait()
+
+			if got := peak.Load(); got > int32(tt.limit) {
+				t.Errorf("%d searches ran at once, limit %d", got, tt.limit)
+			}
+			if got := ran.Load() + rejected.Load(); got != 50 {
+				t.Errorf("%d searches ran or were rejected, want 50", got)
+			}
+			if tt.queue && rejected.Load() > 0 {
+				t.Errorf("%d searches rejected while queueing", rejected.Load())
+			}
+		})
+	}
+}
+
+func TestSearchBatchLargerThanLimit(t *testing.T) {
+	idx := NewIndex(Config{})
+	idx.Add([]Document{{ID: 0, Text: "donut"}, {ID: 1, Text: "glass plate"}})
+	queries := make([]string, 20)
+	for i := range queries {
+		queries[i] = "donut"
+		if i%2 == 1 {
+			queries[i] = "plate"
+		}
+	}
+
+	r, err := NewSearchLimiter(2, false).SearchBatch(idx, queries)
+	if err != nil {
+		t.Fatalf("SearchBatch() = %v", err)
+	}
+	for i, ids := range r {
+		if want := []int{i % 2}; !slices.Equal(ids, want) {
+			t.Errorf("query %d: %v, want %v", i, ids, want)
+		}
+	}
+}
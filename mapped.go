@@ -0,0 +1,323 @@
+package fulltextsearch
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"slices"
+	"sort"
+)
+
+// MappedIndex is a read-only index searched straight from a file written by
+// SaveMapped, which is memory-mapped rather than decoded. Opening one is
+// instant whatever its size, and only the pages holding the terms searches
+// look up are ever read, so memory use follows the working set and the
+// operating system is free to evict the rest.
+//
+// The file holds each field's terms in order, their posting lists, packed
+// as in Save, and a table of where each term and posting list starts,
+// which lookups binary search. A gob-encoded header describing the fields
+// comes after them, and the file ends with the header's offset and length
+// and mappedMagic:
+//
+//	field 1: terms | posting lists | table of (term, postings) offsets
+//	...
+//	field n: terms | posting lists | table of (term, postings) offsets
+//	header | header offset | header length | mappedMagic
+//
+// Offsets are from the start of the file, as little-endian uint64s. Each
+// table has an extra entry at the end marking where the last term and
+// posting list end.
+//
+// A MappedIndex matches documents like Search, with exact field values,
+// words scoped to a field and excluded words, but phrases match as their
+// separate words, and queries with other filters, or with nothing but
+// excluded words, match nothing; rank results with a loaded Index.
+type MappedIndex struct {
+	// idx holds the configuration and header, for analyzing queries and
+	// dropping deleted documents, and an empty field for each mapped one,
+	// for parsing queries, but no postings.
+	idx    *Index
+	data   []byte
+	fields map[string]mappedField
+	docs   int
+}
+
+type mappedHeader struct {
+	// Analyzer is the fingerprint of the analyzer the index was built
+	// with, as in Save's header.
+	Analyzer        [8]byte
+	DefaultOperator Operator
+	FieldAnalyzers  map[string]string
+	Fields          []mappedField
+	Docs            int
+	Deleted         []int
+}
+
+type mappedField struct {
+	Name  string
+	Terms int
+	Table int64
+}
+
+const (
+	mappedMagic = "FTSMMAP2"
+	// mappedTrailerSize is the size of the header offset and length and the
+	// magic ending the file.
+	mappedTrailerSize = 16 + len(mappedMagic)
+	// mappedEntrySize is the size of a term table entry.
+	mappedEntrySize = 16
+)
+
+var errNotMapped = errors.New("not a mapped index file")
+
+// SaveMapped writes the index to path in the format OpenMapped reads. Only
+// the posting lists are written, as a MappedIndex just matches documents,
+// and any doc store is left out.
+func (idx *Index) SaveMapped(path string) error {
+	return writeFileAtomic(path, func(w io.Writer) error {
+		bw := bufio.NewWriter(w)
+		ow := &offsetWriter{w: bw}
+		h := mappedHeader{
+			Analyzer:        idx.analyzer.fingerprint(),
+			DefaultOperator: idx.DefaultOperator,
+			FieldAnalyzers:  idx.FieldAnalyzers,
+			Docs:            len(idx.IDs),
+			Deleted:         idx.Deleted,
+		}
+		var b []byte
+		for _, name := range slices.Sorted(maps.Keys(idx.Fields)) {
+			f := idx.Fields[name]
+			terms := slices.Sorted(f.Postings.keys())
+			table := make([]byte, (len(terms)+1)*mappedEntrySize)
+			for i, term := range terms {
+				binary.LittleEndian.PutUint64(table[i*mappedEntrySize:], uint64(ow.n))
+				io.WriteString(ow, term)
+			}
+			binary.LittleEndian.PutUint64(table[len(terms)*mappedEntrySize:], uint64(ow.n))
+			for i, term := range terms {
+				ids := f.Postings.get(term)
+				b = binary.AppendUvarint(b[:0], uint64(len(ids)))
+				b = appendGaps(b, ids)
+				binary.LittleEndian.PutUint64(table[i*mappedEntrySize+8:], uint64(ow.n))
+				ow.Write(b)
+			}
+			binary.LittleEndian.PutUint64(table[len(terms)*mappedEntrySize+8:], uint64(ow.n))
+			h.Fields = append(h.Fields, mappedField{Name: name, Terms: len(terms), Table: ow.n})
+			ow.Write(table)
+		}
+		headerOffset := ow.n
+		if err := gob.NewEncoder(ow).Encode(h); err != nil {
+			return err
+		}
+		trailer := binary.LittleEndian.AppendUint64(nil, uint64(headerOffset))
+		trailer = binary.LittleEndian.AppendUint64(trailer, uint64(ow.n-headerOffset))
+		ow.Write(append(trailer, mappedMagic...))
+		if ow.err != nil {
+			return ow.err
+		}
+		return bw.Flush()
+	})
+}
+
+// offsetWriter counts the bytes written through it, remembering the first
+// error so callers can check once at the end.
+type offsetWriter struct {
+	w   io.Writer
+	n   int64
+	err error
+}
+
+func (w *offsetWriter) Write(p []byte) (int, error) {
+	if w.err != nil {
+		return 0, w.err
+	}
+	n, err := w.w.Write(p)
+	w.n += int64(n)
+	w.err = err
+	return n, err
+}
+
+// OpenMapped maps the index file at path, written by SaveMapped. Queries
+// are analyzed according to cfg, which as for Load must have the analyzer
+// the index was built with, or OpenMapped fails with ErrAnalyzerMismatch.
+// Close the index to unmap the file.
+func OpenMapped(path string, cfg Config) (*MappedIndex, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	// The mapping outlives the file descriptor.
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	size := int(info.Size())
+	if size < mappedTrailerSize || int64(size) != info.Size() {
+		return nil, fmt.Errorf("opening %s: %w", path, errNotMapped)
+	}
+	data, err := mmap(f, size)
+	if err != nil {
+		return nil, err
+	}
+	m := &MappedIndex{idx: NewIndex(cfg), data: data}
+	if err := m.readHeader(); err != nil {
+		munmap(data)
+		return nil, fmt.Errorf("opening %s: %w", path, err)
+	}
+	return m, nil
+}
+
+// readHeader decodes the header and checks that the term tables it points
+// to are within the file.
+func (m *MappedIndex) readHeader() error {
+	trailer := m.data[len(m.data)-mappedTrailerSize:]
+	if string(trailer[16:]) != mappedMagic {
+		return errNotMapped
+	}
+	offset := binary.LittleEndian.Uint64(trailer)
+	length := binary.LittleEndian.Uint64(trailer[8:])
+	end := uint64(len(m.data) - mappedTrailerSize)
+	if offset > end || length > end-offset {
+		return errNotMapped
+	}
+	var h mappedHeader
+	if err := gob.NewDecoder(bytes.NewReader(m.data[offset : offset+length])).Decode(&h); err != nil {
+		return err
+	}
+	if h.Analyzer != m.idx.analyzer.fingerprint() {
+		return fmt.Errorf("%w; rebuild the index or open it with the analyzer it was built with", ErrAnalyzerMismatch)
+	}
+	for name, n := range h.FieldAnalyzers {
+		if _, ok := Analyzers[n]; !ok {
+			return fmt.Errorf("field %s: unknown analyzer %q", name, n)
+		}
+	}
+	m.fields = make(map[string]mappedField, len(h.Fields))
+	for _, f := range h.Fields {
+		if f.Terms < 0 || f.Table < 0 || uint64(f.Table)+uint64(f.Terms+1)*mappedEntrySize > offset {
+			return fmt.Errorf("field %s: %w", f.Name, errCorruptPostings)
+		}
+		m.fields[f.Name] = f
+		m.idx.Fields[f.Name] = newField()
+	}
+	m.idx.DefaultOperator = h.DefaultOperator
+	m.idx.FieldAnalyzers = h.FieldAnalyzers
+	m.idx.Deleted = h.Deleted
+	m.docs = h.Docs
+	return nil
+}
+
+// Close unmaps the index file. The index must not be used afterwards.
+func (m *MappedIndex) Close() error {
+	data := m.data
+	m.data, m.fields = nil, nil
+	return munmap(data)
+}
+
+func (m *MappedIndex) DocCount() int {
+	return m.docs
+}
+
+func (m *MappedIndex) Search(text string) []int {
+	text, filters := m.idx.parseFilters(text)
+	if len(filters.exists) > 0 || len(filters.expanded) > 0 {
+		return nil
+	}
+	// With no words to match, the first exact or scoped filter picks the
+	// documents for the rest to narrow, there being no list of them all.
+	var ids []int
+	all := len(m.idx.queryTerms("text", text)) == 0
+	if !all {
+		ids = m.idx.matchWords(text, m.idx.DefaultOperator, nil, m.matchField)
+	}
+	narrow := func(postings []int) {
+		if all {
+			ids, all = postings, false
+		} else {
+			ids = intersection(ids, postings)
+		}
+	}
+	for _, t := range filters.exact {
+		postings, _ := m.postings(t.field, t.value)
+		narrow(postings)
+	}
+	for _, t := range filters.scoped {
+		narrow(m.matchField(t.field, t.word))
+	}
+	if all {
+		return nil
+	}
+	for _, word := range filters.excluded {
+		for name := range m.fields {
+			for _, term := range m.idx.queryTerms(name, word) {
+				postings, _ := m.postings(name, term)
+				ids = difference(ids, postings)
+			}
+		}
+	}
+	return m.idx.live(ids)
+}
+
+// matchField returns the documents whose named field contains every term
+// of the query word.
+func (m *MappedIndex) matchField(name, word string) []int {
+	var r []int
+	for i, term := range m.idx.queryTerms(name, word) {
+		ids, ok := m.postings(name, term)
+		if !ok {
+			return nil
+		}
+		if i == 0 {
+			r = ids
+		} else {
+			r = intersection(r, ids)
+		}
+	}
+	return r
+}
+
+// postings looks up the posting list of term in the named field, reporting
+// whether the term is there.
+func (m *MappedIndex) postings(name, term string) ([]int, bool) {
+	f, ok := m.fields[name]
+	if !ok {
+		return nil, false
+	}
+	key := []byte(term)
+	i, found := sort.Find(f.Terms, func(i int) int {
+		from, to := m.entry(f, i, 0), m.entry(f, i+1, 0)
+		return bytes.Compare(key, m.slice(from, to))
+	})
+	if !found {
+		return nil, false
+	}
+	r := uvarintReader{b: m.slice(m.entry(f, i, 8), m.entry(f, i+1, 8))}
+	ids := r.gaps(r.count())
+	if r.err != nil {
+		return nil, false
+	}
+	return ids, true
+}
+
+// entry reads the term (half 0) or postings (half 8) offset of entry i in
+// the term table of f.
+func (m *MappedIndex) entry(f mappedField, i, half int) uint64 {
+	return binary.LittleEndian.Uint64(m.data[f.Table+int64(i*mappedEntrySize+half):])
+}
+
+// slice returns the mapped bytes from offset from to offset to, or nil if
+// the offsets are corrupt.
+func (m *MappedIndex) slice(from, to uint64) []byte {
+	if from > to || to > uint64(len(m.data)) {
+		return nil
+	}
+	return m.data[from:to]
+}
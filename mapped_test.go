@@ -0,0 +1,104 @@
+package fulltextsearch
+
+import (
+	"errors"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestMappedIndex(t *testing.T) {
+	docs := []Document{
+		{ID: 0, Title: "Donuts", Text: "a glazed donut with sprinkles", Tags: []string{"sweet"}},
+		{ID: 1, Title: "Bagels", Text: "a toasted bagel with cream cheese", Tags: []string{"savory"}},
+		{ID: 2, Title: "Plates", Text: "a glass plate for donuts and bagels"},
+		{ID: 3, Text: "glazed pottery, a plate and a bowl"},
+		{ID: 7, Text: "running donuts"},
+	}
+	queries := []string{
+		"donut",
+		"glazed donuts",
+		"bagel -cream",
+		"plate",
+		"title:donuts",
+		"tag:sweet",
+		"tag:sweet glazed",
+		"title:plates glass",
+		"-glazed plate",
+		"the",
+		"nothing",
+		"run",
+	}
+	tests := []struct {
+		name string
+		cfg  Config
+		op   Operator
+	}{
+		{"default", Config{}, And},
+		{"light stemming, or", Config{Analyzer: Analyzer{StemStrength: StemLight}}, Or},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			idx := NewIndex(tt.cfg)
+			idx.DefaultOperator = tt.op
+			idx.Add(docs)
+			idx.Delete(7)
+			path := filepath.Join(t.TempDir(), "idx.mmap")
+			if err := idx.SaveMapped(path); err != nil {
+				t.Fatal(err)
+			}
+			m, err := OpenMapped(path, tt.cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer m.Close()
+			if got, want := m.DocCount(), idx.DocCount(); got != want {
+				t.Errorf("DocCount() = %d, want %d", got, want)
+			}
+			for _, q := range queries {
+				if got, want := m.Search(q), idx.Search(q); !slices.Equal(got, want) {
+					t.Errorf("Search(%q) = %v, want %v", q, got, want)
+				}
+			}
+			// Filters needing more than posting lists aren't supported.
+			for _, q := range []string{"_exists_:title donut", "donu*", "-cream"} {
+				if got := m.Search(q); len(got) != 0 {
+					t.Errorf("Search(%q) = %v, want none", q, got)
+				}
+			}
+		})
+	}
+}
+
+func TestOpenMappedErrors(t *testing.T) {
+	idx := NewIndex(Config{})
+	idx.Add([]Document{{ID: 0, Text: "running donuts"}})
+	dir := t.TempDir()
+	mapped, saved := filepath.Join(dir, "idx.mmap"), filepath.Join(dir, "idx")
+	if err := idx.SaveMapped(mapped); err != nil {
+		t.Fatal(err)
+	}
+	if err := idx.Save(saved); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name string
+		path string
+		cfg  Config
+		want error
+	}{
+		{"another analyzer", mapped, Config{Analyzer: Analyzer{StemStrength: StemNone}}, ErrAnalyzerMismatch},
+		{"saved, not mapped", saved, Config{}, errNotMapped},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := OpenMapped(tt.path, tt.cfg)
+			if err == nil {
+				m.Close()
+			}
+			if !errors.Is(err, tt.want) {
+				t.Errorf("OpenMapped() = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
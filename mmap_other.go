@@ -0,0 +1,20 @@
+//go:build !unix
+
+package fulltextsearch
+
+import (
+	"io"
+	"os"
+)
+
+// mmap reads the whole of f into memory where memory mapping isn't
+// available, which keeps MappedIndex working without the instant start.
+func mmap(f *os.File, size int) ([]byte, error) {
+	b := make([]byte, size)
+	_, err := io.ReadFull(f, b)
+	return b, err
+}
+
+func munmap(b []byte) error {
+	return nil
+}
@@ -0,0 +1,17 @@
+//go:build unix
+
+package fulltextsearch
+
+import (
+	"os"
+	"syscall"
+)
+
+// mmap maps the whole of f read-only into memory.
+func mmap(f *os.File, size int) ([]byte, error) {
+	return syscall.Mmap(int(f.Fd()), 0, size, syscall.PROT_READ, syscall.MAP_SHARED)
+}
+
+func munmap(b []byte) error {
+	return syscall.Munmap(b)
+}
@@ -0,0 +1,177 @@
+package fulltextsearch
+
+import (
+	"crypto/sha1"
+	"runtime"
+	"sync"
+	"time"
+)
+
+// AddParallel is Add with the analysis spread over workers goroutines, or
+// GOMAXPROCS if workers isn't positive. Each worker indexes a share of docs
+// into a partial index of its own, and the partial indexes are then merged
+// into idx.
+func (idx *Index) AddParallel(docs []Document, workers int) {
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	workers = min(workers, len(docs))
+	if workers <= 1 || repeatsID(docs) {
+		// Partial indexes can't replace each other's documents.
+		idx.Add(docs)
+		return
+	}
+	for _, doc := range docs {
+		if idx.has(doc.ID) || idx.isDeleted(doc.ID) {
+			idx.Remove(doc.ID)
+		}
+	}
+
+	parts := make([]*Index, workers)
+	var wg sync.WaitGroup
+	for w := range parts {
+		share := docs[w*len(docs)/workers : (w+1)*len(docs)/workers]
+		parts[w] = idx.partial()
+		wg.Add(1)
+		go func(p *Index) {
+			defer wg.Done()
+			p.Add(share)
+		}(parts[w])
+	}
+	wg.Wait()
+	for _, p := range parts {
+		idx.merge(p)
+	}
+}
+
+// repeatsID reports whether two of docs have the same ID.
+func repeatsID(docs []Document) bool {
+	seen := make(map[int]struct{}, len(docs))
+	for _, doc := range docs {
+		if _, ok := seen[doc.ID]; ok {
+			return true
+		}
+		seen[doc.ID] = struct{}{}
+	}
+	return false
+}
+
+// partial returns an empty index configured like idx, for a worker to
+// index into.
+func (idx *Index) partial() *Index {
+	p := *idx
+	p.IDs = nil
+	p.Deleted = nil
+	p.Fields = make(map[string]*field)
+	p.Dates = cowMap[int, time.Time]{}
+	p.Present = make(map[string][]int)
+	p.Hashes = cowMap[int, [sha1.Size]byte]{}
+	p.Boosts = cowMap[int, float64]{}
+	p.Titles = cowMap[int, string]{}
+	p.URLs = cowMap[int, string]{}
+	p.TitleWords = cowMap[string, int]{}
+	p.Keys = cowMap[string, int]{}
+	if idx.Forward != nil {
+		p.Forward = new(cowMap[int, []string])
+	}
+	if idx.store != nil {
+		p.store = new(cowMap[int, Document])
+	}
+	p.storeFile = storeFile{}
+	p.positions = new(positionCache)
+	p.idfs = new(idfCache)
+	p.dict = new(termDict)
+	p.titleDict = new(termDict)
+	p.bitmaps = nil
+	return &p
+}
+
+// merge adds the documents of the partial index p to idx. p's documents
+// must not already be in idx.
+func (idx *Index) merge(p *Index) {
+	idx.positions.reset()
+	idx.idfs.reset()
+	idx.dict.reset()
+	idx.titleDict.reset()
+	idx.bitmaps.reset()
+	idx.IDs = union(idx.IDs, p.IDs)
+	for name, ids := range p.Present {
+		idx.Present[name] = union(idx.Present[name], ids)
+	}
+	idx.Dates.setAll(&p.Dates)
+	idx.Hashes.setAll(&p.Hashes)
+	idx.Boosts.setAll(&p.Boosts)
+	idx.Titles.setAll(&p.Titles)
+	idx.URLs.setAll(&p.URLs)
+	idx.Keys.setAll(&p.Keys)
+	for w, n := range p.TitleWords.all() {
+		idx.TitleWords.set(w, idx.TitleWords.get(w)+n)
+	}
+	if idx.Forward != nil {
+		idx.Forward.setAll(p.Forward)
+	}
+	if idx.store != nil {
+		idx.store.setAll(p.store)
+		idx.storeFile.pending = append(idx.storeFile.pending, p.storeFile.pending...)
+	}
+	for name, pf := range p.Fields {
+		idx.field(name).merge(pf)
+	}
+}
+
+// merge adds the postings of o, which has none of f's documents, to f.
+func (f *field) merge(o *field) {
+	for term, ids := range o.Postings.all() {
+		cur := f.Postings.get(term)
+		if len(cur) == 0 || cur[len(cur)-1] < ids[0] {
+			// The usual case, as documents are mostly added in ID order.
+			f.Postings.set(term, append(cur, ids...))
+			f.Freqs.set(term, append(f.Freqs.get(term), o.Freqs.get(term)...))
+			if f.Positions != nil {
+				f.Positions.set(term, append(f.Positions.get(term), o.Positions.get(term)...))
+			}
+			continue
+		}
+		f.mergeTerm(term, o, ids)
+	}
+	f.Lengths.setAll(&o.Lengths)
+	f.TotalLength += o.TotalLength
+}
+
+// mergeTerm interleaves o's postings for term with f's, keeping the
+// parallel frequencies and positions in step.
+func (f *field) mergeTerm(term string, o *field, ids []int) {
+	a, b := f.Postings.get(term), ids
+	af, bf := f.Freqs.get(term), o.Freqs.get(term)
+	var ap, bp [][]int
+	if f.Positions != nil {
+		ap, bp = f.Positions.get(term), o.Positions.get(term)
+	}
+	n := len(a) + len(b)
+	r, rf := make([]int, 0, n), make([]int, 0, n)
+	var rp [][]int
+	if f.Positions != nil {
+		rp = make([][]int, 0, n)
+	}
+	var i, j int
+	for i < len(a) || j < len(b) {
+		if j == len(b) || (i < len(a) && a[i] < b[j]) {
+			r, rf = append(r, a[i]), append(rf, af[i])
+			if rp != nil {
+				rp = append(rp, ap[i])
+			}
+			i++
+		} else {
+			r, rf = append(r, b[j]), append(rf, bf[j])
+			if rp != nil {
+				rp = append(rp, bp[j])
+			}
+			j++
+		}
+	}
+	f.Postings.set(term, r)
+	f.Freqs.set(term, rf)
+	if rp != nil {
+		f.Positions.set(term, rp)
+	}
+}
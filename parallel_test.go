@@ -0,0 +1,103 @@
+package fulltextsearch
+
+import (
+	"math/rand/v2"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// randomDocs returns n documents of random words from a small vocabulary,
+// numbered from first in a random order.
+func randomDocs(r *rand.Rand, first, n int) []Document {
+	vocab := strings.Fields("cat dog bird fish running ran runs the a of red green blue")
+	words := func(k int) string {
+		w := make([]string, k)
+		for i := range w {
+			w[i] = vocab[r.IntN(len(vocab))]
+		}
+		return strings.Join(w, " ")
+	}
+	docs := make([]Document, n)
+	for i, id := range r.Perm(n) {
+		docs[i] = Document{
+			ID:    first + id,
+			Title: words(1 + r.IntN(3)),
+			Text:  words(r.IntN(20)),
+			Tags:  []string{vocab[r.IntN(3)]},
+			Date:  time.Date(2024, 1, 1+r.IntN(28), 0, 0, 0, 0, time.UTC),
+		}
+	}
+	return docs
+}
+
+// sameIndex compares the postings and document data of two indexes.
+func sameIndex(t *testing.T, got, want *Index) {
+	t.Helper()
+	if !slices.Equal(got.IDs, want.IDs) {
+		t.Errorf("IDs = %v, want %v", got.IDs, want.IDs)
+	}
+	if len(got.Fields) != len(want.Fields) {
+		t.Errorf("%d fields, want %d", len(got.Fields), len(want.Fields))
+	}
+	for name, wf := range want.Fields {
+		gf, ok := got.Fields[name]
+		if !ok {
+			t.Errorf("field %s missing", name)
+			continue
+		}
+		if !reflect.DeepEqual(gf.Postings.toMap(), wf.Postings.toMap()) {
+			t.Errorf("field %s postings = %v, want %v", name, gf.Postings.toMap(), wf.Postings.toMap())
+		}
+		if !reflect.DeepEqual(gf.Freqs.toMap(), wf.Freqs.toMap()) {
+			t.Errorf("field %s frequencies = %v, want %v", name, gf.Freqs.toMap(), wf.Freqs.toMap())
+		}
+		if !reflect.DeepEqual(gf.Positions.toMap(), wf.Positions.toMap()) {
+			t.Errorf("field %s positions differ", name)
+		}
+		if !reflect.DeepEqual(gf.Lengths.toMap(), wf.Lengths.toMap()) || gf.TotalLength != wf.TotalLength {
+			t.Errorf("field %s lengths differ", name)
+		}
+	}
+	if !reflect.DeepEqual(got.Present, want.Present) {
+		t.Errorf("Present = %v, want %v", got.Present, want.Present)
+	}
+	if !reflect.DeepEqual(got.Titles.toMap(), want.Titles.toMap()) {
+		t.Errorf("Titles = %v, want %v", got.Titles.toMap(), want.Titles.toMap())
+	}
+	if !reflect.DeepEqual(got.Dates.toMap(), want.Dates.toMap()) {
+		t.Errorf("Dates = %v, want %v", got.Dates.toMap(), want.Dates.toMap())
+	}
+	if !reflect.DeepEqual(got.TitleWords.toMap(), want.TitleWords.toMap()) {
+		t.Errorf("TitleWords = %v, want %v", got.TitleWords.toMap(), want.TitleWords.toMap())
+	}
+}
+
+func TestAddParallel(t *testing.T) {
+	r := rand.New(rand.NewPCG(1, 2))
+	first := randomDocs(r, 0, 200)
+	// The second batch replaces some documents and adds others.
+	second := randomDocs(r, 150, 100)
+
+	for _, workers := range []int{0, 1, 2, 3, 8, 500} {
+		serial := NewIndex(Config{})
+		serial.Add(slices.Clone(first))
+		serial.Add(slices.Clone(second))
+
+		parallel := NewIndex(Config{})
+		parallel.AddParallel(slices.Clone(first), workers)
+		parallel.AddParallel(slices.Clone(second), workers)
+
+		sameIndex(t, parallel, serial)
+		if len(serial.Search("tag:bird")) == 0 {
+			t.Fatal("no document is tagged bird")
+		}
+		for _, query := range []string{"cat", "running dog", `"red fish"`, "tag:bird"} {
+			if got, want := parallel.Search(query), serial.Search(query); !slices.Equal(got, want) {
+				t.Errorf("with %d workers, Search(%s) = %v, want %v", workers, query, got, want)
+			}
+		}
+	}
+}
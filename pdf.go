@@ -0,0 +1,79 @@
+package fulltextsearch
+
+import (
+	"crypto/sha1"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/ledongthuc/pdf"
+)
+
+// LoadPDF reads the text of the PDF file at path as documents; see
+// StreamPDF.
+func LoadPDF(path string, perPage bool) ([]Document, error) {
+	var docs []Document
+	err := StreamPDF(path, perPage, func(doc Document) error {
+		docs = append(docs, doc)
+		return nil
+	})
+	return docs, err
+}
+
+// StreamPDF extracts the text of the PDF file at path, calling fn with a
+// document for the whole file or, if perPage is set, one for each page
+// with text, numbered from 0. The title is the one in the file's metadata,
+// or else the file name, and the URL is the path, with a #page=N fragment
+// for pages, which PDF viewers open at that page. Scanned pages without a
+// text layer yield no text. It stops at the first error fn returns and
+// returns it.
+func StreamPDF(path string, perPage bool, fn func(Document) error) error {
+	f, r, err := pdf.Open(path)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	url := filepath.ToSlash(path)
+	title := collapseSpace(r.Trailer().Key("Info").Key("Title").Text())
+	if title == "" {
+		title = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+	doc := Document{Title: title, URL: url, Date: info.ModTime()}
+
+	var pages []string
+	for i := 1; i <= r.NumPage(); i++ {
+		text, err := r.Page(i).GetPlainText(nil)
+		if err != nil {
+			return fmt.Errorf("%s: page %d: %w", path, i, err)
+		}
+		text = strings.TrimSpace(text)
+		if !perPage {
+			pages = append(pages, text)
+			continue
+		}
+		if text == "" {
+			continue
+		}
+		page := doc
+		page.URL = fmt.Sprintf("%s#page=%d", url, i)
+		page.Text = text
+		sum := sha1.Sum([]byte(page.URL))
+		page.URLSHA1 = sum[:]
+		if err := fn(page); err != nil {
+			return err
+		}
+		doc.ID++
+	}
+	if perPage {
+		return nil
+	}
+	doc.Text = strings.Join(pages, "\n")
+	sum := sha1.Sum([]byte(doc.URL))
+	doc.URLSHA1 = sum[:]
+	return fn(doc)
+}
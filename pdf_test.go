@@ -0,0 +1,127 @@
+package fulltextsearch
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// writePDF writes a minimal PDF to path with a page for each of pages,
+// showing its text in Helvetica, and title in its metadata if non-empty.
+func writePDF(t *testing.T, path, title string, pages []string) {
+	t.Helper()
+	var objects []string
+	kids := make([]string, len(pages))
+	for i, text := range pages {
+		page, content := 4+2*i, 5+2*i
+		kids[i] = fmt.Sprintf("%d 0 R", page)
+		stream := fmt.Sprintf("BT /F1 12 Tf 72 720 Td (%s) Tj ET", text)
+		objects = append(objects,
+			fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 612 792] /Resources << /Font << /F1 3 0 R >> >> /Contents %d 0 R >>", content),
+			fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(stream), stream))
+	}
+	objects = append([]string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(pages)),
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica >>",
+	}, objects...)
+	info := ""
+	if title != "" {
+		objects = append(objects, fmt.Sprintf("<< /Title (%s) >>", title))
+		info = fmt.Sprintf(" /Info %d 0 R", len(objects))
+	}
+
+	var b bytes.Buffer
+	b.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, obj := range objects {
+		offsets[i] = b.Len()
+		fmt.Fprintf(&b, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+	xref := b.Len()
+	fmt.Fprintf(&b, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&b, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&b, "trailer\n<< /Size %d /Root 1 0 R%s >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, info, xref)
+	if err := os.WriteFile(path, b.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoadPDF(t *testing.T) {
+	dir := t.TempDir()
+	titled, untitled := filepath.Join(dir, "donuts.pdf"), filepath.Join(dir, "bagels.pdf")
+	writePDF(t, titled, "Glazed Donuts", []string{"A glazed donut", "", "With sprinkles"})
+	writePDF(t, untitled, "", []string{"A toasted bagel"})
+	tests := []struct {
+		name    string
+		path    string
+		perPage bool
+		title   string
+		urls    []string
+		texts   []string
+	}{
+		{"whole file", titled, false, "Glazed Donuts",
+			[]string{titled}, []string{"A glazed donut\n\nWith sprinkles"}},
+		{"per page", titled, true, "Glazed Donuts",
+			[]string{titled + "#page=1", titled + "#page=3"}, []string{"A glazed donut", "With sprinkles"}},
+		{"untitled", untitled, false, "bagels",
+			[]string{untitled}, []string{"A toasted bagel"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			docs, err := LoadPDF(tt.path, tt.perPage)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var urls, texts []string
+			for i, doc := range docs {
+				if doc.ID != i || doc.Title != tt.title {
+					t.Errorf("document %d has ID %d and title %q, want %d and %q", i, doc.ID, doc.Title, i, tt.title)
+				}
+				urls = append(urls, doc.URL)
+				texts = append(texts, doc.Text)
+			}
+			if !slices.Equal(urls, tt.urls) {
+				t.Errorf("URLs = %q, want %q", urls, tt.urls)
+			}
+			if !slices.Equal(texts, tt.texts) {
+				t.Errorf("texts = %q, want %q", texts, tt.texts)
+			}
+		})
+	}
+}
+
+func TestLoadPDFMalformed(t *testing.T) {
+	dir := t.TempDir()
+	good := filepath.Join(dir, "good.pdf")
+	writePDF(t, good, "", []string{"A glazed donut"})
+	content, err := os.ReadFile(good)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name    string
+		content []byte
+	}{
+		{"not a PDF", []byte("A glazed donut\n")},
+		{"truncated", content[:len(content)/2]},
+		{"empty", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, "bad.pdf")
+			if err := os.WriteFile(path, tt.content, 0644); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := LoadPDF(path, false); err == nil {
+				t.Error("LoadPDF() error = nil, want an error")
+			}
+		})
+	}
+}
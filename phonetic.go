@@ -0,0 +1,73 @@
+package fulltextsearch
+
+import (
+	"strings"
+	"unicode"
+)
+
+var soundexCodes = map[rune]byte{
+	'b': '1', 'f': '1', 'p': '1', 'v': '1',
+	'c': '2', 'g': '2', 'j': '2', 'k': '2', 'q': '2', 's': '2', 'x': '2', 'z': '2',
+	'd': '3', 't': '3',
+	'l': '4',
+	'm': '5', 'n': '5',
+	'r': '6',
+}
+
+// Soundex returns the American Soundex code of word, e.g. "S530" for both
+// "smith" and "smyth". Words that don't start with an ASCII letter have no
+// code and are returned as they are.
+func Soundex(word string) string {
+	word = strings.ToLower(word)
+	if word == "" || word[0] < 'a' || word[0] > 'z' {
+		return word
+	}
+	code := []byte{byte(unicode.ToUpper(rune(word[0])))}
+	last := soundexCodes[rune(word[0])]
+	for _, r := range word[1:] {
+		if len(code) == 4 {
+			break
+		}
+		switch r {
+		case 'h', 'w':
+			// Ignored entirely; letters either side still collapse.
+			continue
+		case 'a', 'e', 'i', 'o', 'u', 'y':
+			last = 0
+			continue
+		}
+		c, ok := soundexCodes[r]
+		if !ok {
+			continue
+		}
+		if c != last {
+			code = append(code, c)
+		}
+		last = c
+	}
+	for len(code) < 4 {
+		code = append(code, '0')
+	}
+	return string(code)
+}
+
+// PhoneticFilter maps tokens to their Soundex codes so spelling variants of
+// a name index to the same term. Codes are upper case and can't collide with
+// the lowercased literal tokens.
+type PhoneticFilter struct {
+	// Keep indexes the literal token as well as its code, at the same
+	// position, so phrases still match. Queries are always reduced to
+	// codes alone so variants still match.
+	Keep bool
+}
+
+func (p PhoneticFilter) filter(tokens []string) []string {
+	r := make([]string, 0, len(tokens))
+	for _, token := range tokens {
+		if p.Keep {
+			r = append(r, token)
+		}
+		r = append(r, Soundex(token))
+	}
+	return r
+}
@@ -0,0 +1,57 @@
+package fulltextsearch
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestSoundex(t *testing.T) {
+	tests := []struct {
+		word, want string
+	}{
+		{"Robert", "R163"},
+		{"Rupert", "R163"},
+		{"Smith", "S530"},
+		{"Smyth", "S530"},
+		{"Ashcraft", "A261"},
+		{"Tymczak", "T522"},
+		{"Pfister", "P236"},
+		{"Lee", "L000"},
+		{"42", "42"},
+	}
+	for _, tt := range tests {
+		if got := Soundex(tt.word); got != tt.want {
+			t.Errorf("Soundex(%q) = %q, want %q", tt.word, got, tt.want)
+		}
+	}
+}
+
+func TestPhoneticMatching(t *testing.T) {
+	docs := []Document{
+		{ID: 0, Text: "John Smith wrote it"},
+		{ID: 1, Text: "a letter from Smyth John"},
+		{ID: 2, Text: "Jones and Brown"},
+	}
+	tests := []struct {
+		name  string
+		keep  bool
+		query string
+		want  []int
+	}{
+		{"variant spelling", false, "smyth", []int{0, 1}},
+		{"variant spelling, keeping tokens", true, "smyth", []int{0, 1}},
+		{"phrase", false, `"jon smyth"`, []int{0}},
+		{"phrase, keeping tokens", true, `"jon smyth"`, []int{0}},
+		{"phrase in the other order, keeping tokens", true, `"smith john"`, []int{1}},
+		{"sloppy phrase, keeping tokens", true, `"john wrote"~1`, []int{0}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			idx := NewIndex(Config{Analyzer: Analyzer{Phonetic: &PhoneticFilter{Keep: tt.keep}}})
+			idx.Add(docs)
+			if got := idx.Search(tt.query); !slices.Equal(got, tt.want) {
+				t.Errorf("Search(%s) = %v, want %v", tt.query, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,241 @@
+package fulltextsearch
+
+import (
+	"errors"
+	"math"
+	"slices"
+	"sort"
+	"sync"
+)
+
+// positions maps each term to the documents it occurs in and, for each, its
+// offsets in the document's analyzed text.
+type positions map[string]map[int][]int
+
+// at returns the offsets of term in document id.
+func (p positions) at(term string, id int) []int {
+	return p[term][id]
+}
+
+// positionsAt looks up the offsets of a term in a document.
+type positionsAt func(term string, id int) []int
+
+// at returns the offsets of term in document id, from the positional
+// postings.
+func (f *field) at(term string, id int) []int {
+	ids := f.Postings.get(term)
+	i := sort.SearchInts(ids, id)
+	if i == len(ids) || ids[i] != id {
+		return nil
+	}
+	return f.Positions.get(term)[i]
+}
+
+// positionCache holds positions built lazily from the doc store, for indexes
+// without positional postings, so the memory is only spent once a phrase
+// query actually needs it.
+type positionCache struct {
+	mu  sync.Mutex
+	pos positions
+}
+
+func (c *positionCache) reset() {
+	c.mu.Lock()
+	c.pos = nil
+	c.mu.Unlock()
+}
+
+var ErrNoStore = errors.New("index has no document store")
+
+// textPositions returns the positions of terms in the text field. They come
+// from the positional postings if the field has them, or are otherwise built
+// from the doc store on first use.
+func (idx *Index) textPositions() (positionsAt, error) {
+	if f, ok := idx.Fields["text"]; ok && f.Positions != nil {
+		return f.at, nil
+	}
+	c := idx.positions
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.pos != nil {
+		return c.pos.at, nil
+	}
+	if idx.store == nil {
+		return nil, ErrNoStore
+	}
+	pos := make(positions)
+	for id, doc := range idx.store.all() {
+		terms, at := idx.analyzerFor(doc).positioned(doc.Text)
+		for i, term := range terms {
+			docs, ok := pos[term]
+			if !ok {
+				docs = make(map[int][]int)
+				pos[term] = docs
+			}
+			p := i
+			if at != nil {
+				p = at[i]
+			}
+			docs[id] = append(docs[id], p)
+		}
+	}
+	c.pos = pos
+	return pos.at, nil
+}
+
+// Phrase returns the documents whose text contains the query terms next to
+// each other and in order.
+func (idx *Index) Phrase(text string) ([]int, error) {
+	terms := idx.analyzer.AnalyzeQuery(text)
+	candidates := idx.Search(text)
+	if len(terms) < 2 || len(candidates) == 0 {
+		return candidates, nil
+	}
+	at, err := idx.textPositions()
+	if err != nil {
+		return nil, err
+	}
+
+	var r []int
+	for _, id := range candidates {
+		if adjacent(at, terms, id) {
+			r = append(r, id)
+		}
+	}
+	return r, nil
+}
+
+// phraseFilter keeps the documents of ids whose text contains the phrase,
+// or with slop its terms close together. If the index has no positions to
+// check against, it returns ids unfiltered, with the error saying why.
+func (idx *Index) phraseFilter(ids []int, p phraseQuery) ([]int, error) {
+	terms := idx.analyzer.AnalyzeQuery(p.text)
+	if len(terms) < 2 {
+		return ids, nil
+	}
+	at, err := idx.textPositions()
+	if err != nil {
+		return ids, err
+	}
+	r := make([]int, 0, len(ids))
+	for _, id := range ids {
+		if p.slop == 0 && adjacent(at, terms, id) ||
+			p.slop > 0 && within(at, terms, id, p.slop) {
+			r = append(r, id)
+		}
+	}
+	return r, nil
+}
+
+// within reports whether every distinct one of terms occurs in document id
+// in one window of as many positions as there are terms, plus slop.
+func within(at positionsAt, terms []string, id, slop int) bool {
+	unique := slices.Compact(slices.Sorted(slices.Values(terms)))
+	return nearTerms(at, unique, id, len(unique)+slop) == len(unique)
+}
+
+// adjacent reports whether terms occur consecutively somewhere in document id.
+func adjacent(at positionsAt, terms []string, id int) bool {
+	for _, start := range at(terms[0], id) {
+		found := true
+		for k, term := range terms[1:] {
+			if !contains(at(term, id), start+k+1) {
+				found = false
+				break
+			}
+		}
+		if found {
+			return true
+		}
+	}
+	return false
+}
+
+func contains(sorted []int, n int) bool {
+	for _, m := range sorted {
+		if m >= n {
+			return m == n
+		}
+	}
+	return false
+}
+
+type PhraseOptions struct {
+	// Slop is how many extra positions the terms may spread over beyond
+	// the length of the phrase.
+	Slop int
+	// MinMatch is the fraction of phrase terms that must occur together
+	// for a document to match. Zero requires all of them.
+	MinMatch float64
+}
+
+// PhraseRank scores documents by the largest fraction of the phrase's terms
+// found within one window of len(terms)+Slop positions, in any order, and
+// returns those reaching MinMatch best first.
+func (idx *Index) PhraseRank(text string, opts PhraseOptions) ([]Result, error) {
+	terms := idx.analyzer.AnalyzeQuery(text)
+	if len(terms) == 0 {
+		return nil, nil
+	}
+	f, ok := idx.Fields["text"]
+	if !ok {
+		return nil, nil
+	}
+	at, err := idx.textPositions()
+	if err != nil {
+		return nil, err
+	}
+
+	need := len(terms)
+	if opts.MinMatch > 0 {
+		need = int(math.Ceil(opts.MinMatch * float64(len(terms))))
+	}
+	var candidates []int
+	for _, term := range terms {
+		candidates = union(candidates, f.Postings.get(term))
+	}
+	candidates = idx.live(candidates)
+
+	var r []Result
+	for _, id := range candidates {
+		n := nearTerms(at, terms, id, len(terms)+opts.Slop)
+		if n >= need {
+			res := idx.result(id)
+			res.Score = float64(n) / float64(len(terms))
+			r = append(r, res)
+		}
+	}
+	sortResults(r, ByScore{})
+	return r, nil
+}
+
+// nearTerms returns the most distinct terms found in document id within any
+// window of width positions.
+func nearTerms(at positionsAt, terms []string, id, width int) int {
+	type hit struct{ pos, term int }
+	var hits []hit
+	for i, term := range terms {
+		for _, p := range at(term, id) {
+			hits = append(hits, hit{p, i})
+		}
+	}
+	sort.Slice(hits, func(i, j int) bool { return hits[i].pos < hits[j].pos })
+
+	best := 0
+	seen := make(map[int]int)
+	start := 0
+	for _, h := range hits {
+		seen[h.term]++
+		for h.pos-hits[start].pos >= width {
+			t := hits[start].term
+			if seen[t]--; seen[t] == 0 {
+				delete(seen, t)
+			}
+			start++
+		}
+		if len(seen) > best {
+			best = len(seen)
+		}
+	}
+	return best
+}
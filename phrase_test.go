@@ -0,0 +1,147 @@
+package fulltextsearch
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestPhraseWithoutPositions(t *testing.T) {
+	docs := []Document{
+		{ID: 0, Text: "a glass plate"},
+		{ID: 1, Text: "a plate of glass"},
+	}
+	tests := []struct {
+		name          string
+		cfg           Config
+		want          []int
+		wantUnchecked []string
+	}{
+		{"positions", Config{}, []int{0}, nil},
+		{"lazy positions from the store", Config{LazyPositions: true, StoreDocuments: true}, []int{0}, nil},
+		{"neither", Config{LazyPositions: true}, []int{0, 1}, []string{"glass plate"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			idx := NewIndex(tt.cfg)
+			idx.Add(docs)
+			got, d := idx.SearchDiagnostics(`"glass plate"`)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("SearchDiagnostics() = %v, want %v", got, tt.want)
+			}
+			if !slices.Equal(d.UncheckedPhrases, tt.wantUnchecked) {
+				t.Errorf("UncheckedPhrases = %q, want %q", d.UncheckedPhrases, tt.wantUnchecked)
+			}
+		})
+	}
+}
+
+func TestPhraseSlop(t *testing.T) {
+	idx := NewIndex(Config{})
+	idx.Add([]Document{
+		{ID: 0, Text: "john smith wrote it"},
+		{ID: 1, Text: "john and jane smith wrote it"},
+		{ID: 2, Text: "wrote john"},
+	})
+	tests := []struct {
+		query string
+		want  []int
+	}{
+		{`"john wrote"`, nil},
+		{`"john wrote"~1`, []int{0, 2}},
+		{`"john wrote"~3`, []int{0, 1, 2}},
+		{`"wrote john"`, []int{2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			got := idx.Search(tt.query)
+			slices.Sort(got)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("Search(%s) = %v, want %v", tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLazyPositions(t *testing.T) {
+	idx := NewIndex(Config{LazyPositions: true, StoreDocuments: true})
+	idx.Add([]Document{
+		{ID: 0, Text: "a glass plate"},
+		{ID: 1, Text: "a plate of glass"},
+	})
+	if idx.Fields["text"].Positions != nil {
+		t.Fatal("text field has positions before any phrase query")
+	}
+	if idx.positions.pos != nil {
+		t.Fatal("positions built before any phrase query")
+	}
+
+	tests := []struct {
+		query string
+		want  []int
+	}{
+		{`"glass plate"`, []int{0}},
+		{`"plate of glass"`, []int{1}},
+		{`"glass plate"`, []int{0}},
+	}
+	for i, tt := range tests {
+		got, err := idx.Phrase(tt.query)
+		if err != nil {
+			t.Fatalf("Phrase(%s) error = %v", tt.query, err)
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("Phrase(%s) = %v, want %v", tt.query, got, tt.want)
+		}
+		if i == 0 {
+			// mark the built positions, to tell whether later queries
+			// build them again
+			idx.positions.pos["\x00built"] = nil
+		} else if _, ok := idx.positions.pos["\x00built"]; !ok {
+			t.Errorf("Phrase(%s) built the positions again", tt.query)
+		}
+	}
+
+	idx.Add([]Document{{ID: 2, Text: "the glass plate broke"}})
+	got, err := idx.Phrase(`"glass plate"`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []int{0, 2}; !slices.Equal(got, want) {
+		t.Errorf("Phrase(glass plate) after Add = %v, want %v", got, want)
+	}
+}
+
+func TestPhraseRankMinMatch(t *testing.T) {
+	filler := " " + strings.Repeat("filler ", 10)
+	idx := NewIndex(Config{})
+	idx.Add([]Document{
+		{ID: 0, Text: "the quick brown fox jumps far"},
+		{ID: 1, Text: "quick" + filler + "brown" + filler + "fox" + filler + "jumps" + filler + "high"},
+		{ID: 2, Text: "a quick brown fox jumps high"},
+	})
+	tests := []struct {
+		name string
+		opts PhraseOptions
+		want []int
+	}{
+		{"every term", PhraseOptions{}, []int{2}},
+		{"four of five", PhraseOptions{MinMatch: 0.8}, []int{2, 0}},
+		{"isolated terms too", PhraseOptions{MinMatch: 0.2}, []int{2, 0, 1}},
+		{"slop gathering the isolated terms", PhraseOptions{Slop: 50, MinMatch: 0.8}, []int{1, 2, 0}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := idx.PhraseRank("quick brown fox jumps high", tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []int
+			for _, res := range r {
+				got = append(got, res.ID)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("PhraseRank(%+v) = %v, want %v", tt.opts, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,134 @@
+package fulltextsearch
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// Progress reports how far AddDump has got.
+type Progress struct {
+	Docs int
+	// Terms counts the terms indexed across all fields.
+	Terms int
+	// BytesRead is how much of the compressed dump has been read, out of
+	// TotalBytes.
+	BytesRead, TotalBytes int64
+	Elapsed               time.Duration
+}
+
+// Fraction is the share of the dump read so far, from 0 to 1.
+func (p Progress) Fraction() float64 {
+	if p.TotalBytes <= 0 {
+		return 0
+	}
+	return float64(p.BytesRead) / float64(p.TotalBytes)
+}
+
+// ETA estimates the time left, assuming the rest of the dump indexes as
+// fast as what has been read so far. It is zero until anything is read.
+func (p Progress) ETA() time.Duration {
+	f := p.Fraction()
+	if f == 0 {
+		return 0
+	}
+	return time.Duration(float64(p.Elapsed) * (1 - f) / f)
+}
+
+func (p Progress) String() string {
+	return fmt.Sprintf("%d docs, %d terms, %.1f%% read, ETA %s",
+		p.Docs, p.Terms, 100*p.Fraction(), p.ETA().Round(time.Second))
+}
+
+// IndexOptions configures AddDump.
+type IndexOptions struct {
+	// BatchSize is how many documents are added at a time, defaulting to
+	// defaultBatchSize. Only one batch is held in memory.
+	BatchSize int
+	// Workers is how many goroutines analyze each batch, as for
+	// AddParallel. Zero means GOMAXPROCS and 1 indexes serially.
+	Workers int
+	// Progress, if non-nil, is called after each batch.
+	Progress func(Progress)
+	// Dump configures how the dump is read. Its zero value keeps every
+	// page; DefaultDumpOptions leaves out the non-article ones.
+	Dump DumpOptions
+}
+
+const defaultBatchSize = 10000
+
+// countingReader counts the bytes read through it.
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// AddDump streams the gzipped abstract dump at path into the index a batch
+// at a time, reporting progress after each batch.
+func (idx *Index) AddDump(path string, opts IndexOptions) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	cr := &countingReader{r: f}
+	gz, err := gzip.NewReader(cr)
+	if err != nil {
+		return err
+	}
+	defer gz.Close()
+
+	size := opts.BatchSize
+	if size <= 0 {
+		size = defaultBatchSize
+	}
+	start, startTerms := time.Now(), idx.termCount()
+	p := Progress{TotalBytes: info.Size()}
+	batch := make([]Document, 0, size)
+	flush := func() {
+		idx.AddParallel(batch, opts.Workers)
+		p.Docs += len(batch)
+		batch = batch[:0]
+		if opts.Progress != nil {
+			p.Terms = idx.termCount() - startTerms
+			p.BytesRead = cr.n
+			p.Elapsed = time.Since(start)
+			opts.Progress(p)
+		}
+	}
+	err = StreamDocumentsReader(gz, opts.Dump, func(doc Document) error {
+		batch = append(batch, doc)
+		if len(batch) == size {
+			flush()
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if len(batch) > 0 {
+		flush()
+	}
+	return nil
+}
+
+// termCount returns the number of terms indexed across all fields.
+func (idx *Index) termCount() int {
+	n := 0
+	for _, f := range idx.Fields {
+		n += f.TotalLength
+	}
+	return n
+}
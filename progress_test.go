@@ -0,0 +1,89 @@
+package fulltextsearch
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestProgress(t *testing.T) {
+	tests := []struct {
+		p        Progress
+		fraction float64
+		eta      time.Duration
+		str      string
+	}{
+		{Progress{}, 0, 0, "0 docs, 0 terms, 0.0% read, ETA 0s"},
+		{Progress{Docs: 10, Terms: 40, BytesRead: 25, TotalBytes: 100, Elapsed: time.Minute}, 0.25, 3 * time.Minute,
+			"10 docs, 40 terms, 25.0% read, ETA 3m0s"},
+		{Progress{Docs: 40, BytesRead: 100, TotalBytes: 100, Elapsed: time.Minute}, 1, 0, "40 docs, 0 terms, 100.0% read, ETA 0s"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.str, func(t *testing.T) {
+			if got := tt.p.Fraction(); got != tt.fraction {
+				t.Errorf("Fraction() = %v, want %v", got, tt.fraction)
+			}
+			if got := tt.p.ETA(); got != tt.eta {
+				t.Errorf("ETA() = %v, want %v", got, tt.eta)
+			}
+			if got := tt.p.String(); got != tt.str {
+				t.Errorf("String() = %q, want %q", got, tt.str)
+			}
+		})
+	}
+}
+
+func TestAddDump(t *testing.T) {
+	var b strings.Builder
+	b.WriteString("<feed>\n")
+	for i := range 25 {
+		fmt.Fprintf(&b, "<doc><title>Wikipedia: Page %d</title><url>https://example.com/%d</url><abstract>word%d and cats</abstract></doc>\n", i, i, i%3)
+	}
+	b.WriteString("<doc><title>Wikipedia: Category:Cats</title><url>https://example.com/c</url><abstract>cats</abstract></doc>\n</feed>")
+	path := writeDump(t, b.String())
+
+	idx := NewIndex(Config{})
+	var reports []Progress
+	err := idx.AddDump(path, IndexOptions{BatchSize: 10, Workers: 2, Dump: DefaultDumpOptions, Progress: func(p Progress) {
+		reports = append(reports, p)
+	}})
+	if err != nil {
+		t.Fatalf("AddDump() error = %v", err)
+	}
+
+	var docs []int
+	for i, p := range reports {
+		docs = append(docs, p.Docs)
+		if i > 0 && (p.Terms <= reports[i-1].Terms || p.BytesRead < reports[i-1].BytesRead) {
+			t.Errorf("report %d = %+v went backwards from %+v", i, p, reports[i-1])
+		}
+	}
+	if want := []int{10, 20, 25}; !slices.Equal(docs, want) {
+		t.Errorf("AddDump() reported docs %v, want %v", docs, want)
+	}
+	if last := reports[len(reports)-1]; last.Fraction() != 1 {
+		t.Errorf("last report read %d of %d bytes, want all of them", last.BytesRead, last.TotalBytes)
+	}
+
+	// The index is the same as adding the dump's documents in one go.
+	loaded, err := LoadDocuments(path, DefaultDumpOptions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := NewIndex(Config{})
+	want.Add(loaded)
+	if got := idx.DocCount(); got != 25 {
+		t.Errorf("DocCount() = %d, want 25", got)
+	}
+	for _, query := range []string{"cats", "word0", "word2", "page"} {
+		if got, want := idx.Search(query), want.Search(query); !slices.Equal(got, want) {
+			t.Errorf("Search(%s) = %v, want %v", query, got, want)
+		}
+	}
+
+	if err := NewIndex(Config{}).AddDump(path+".missing", IndexOptions{}); err == nil {
+		t.Error("AddDump(missing file) succeeded, want an error")
+	}
+}
@@ -0,0 +1,273 @@
+package fulltextsearch
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Query is a parsed boolean query, a tree of AND, OR and NOT over words.
+type Query interface {
+	// match returns the matching documents, with ok false if the query
+	// has nothing to search for, being only stopwords, so that it should
+	// be left out of whatever it is part of.
+	match(idx *Index) (ids []int, ok bool)
+	String() string
+}
+
+// wordQuery is a leaf of a boolean query, matched as Search would match it,
+// so it can be a phrase, wildcard, fuzzy word or field:value filter.
+type wordQuery string
+
+func (q wordQuery) match(idx *Index) ([]int, bool) {
+	text, filters := idx.parseFilters(string(q))
+	if filters.empty() && len(idx.analyzer.AnalyzeQuery(text)) == 0 {
+		return nil, false
+	}
+	return idx.match(string(q), And, nil), true
+}
+
+func (q wordQuery) String() string {
+	return string(q)
+}
+
+type andQuery []Query
+
+func (q andQuery) match(idx *Index) ([]int, bool) {
+	var r []int
+	started := false
+	for _, sub := range q {
+		if started && len(r) == 0 {
+			break
+		}
+		not, isNot := sub.(notQuery)
+		if started && isNot {
+			// Subtract rather than intersect with the complement.
+			if ids, ok := not.q.match(idx); ok {
+				r = difference(r, ids)
+			}
+			continue
+		}
+		ids, ok := sub.match(idx)
+		if !ok {
+			continue
+		}
+		if started {
+			r = intersection(r, ids)
+		} else {
+			r, started = ids, true
+		}
+	}
+	return r, started
+}
+
+func (q andQuery) String() string {
+	return joinQueries(q, " AND ")
+}
+
+type orQuery []Query
+
+func (q orQuery) match(idx *Index) ([]int, bool) {
+	var r []int
+	matched := false
+	for _, sub := range q {
+		if ids, ok := sub.match(idx); ok {
+			r, matched = union(r, ids), true
+		}
+	}
+	return r, matched
+}
+
+func (q orQuery) String() string {
+	return joinQueries(q, " OR ")
+}
+
+type notQuery struct {
+	q Query
+}
+
+func (q notQuery) match(idx *Index) ([]int, bool) {
+	ids, ok := q.q.match(idx)
+	if !ok {
+		return nil, false
+	}
+	return difference(idx.IDs, ids), true
+}
+
+func (q notQuery) String() string {
+	return "NOT " + q.q.String()
+}
+
+func joinQueries(qs []Query, sep string) string {
+	s := make([]string, len(qs))
+	for i, q := range qs {
+		s[i] = q.String()
+	}
+	return "(" + strings.Join(s, sep) + ")"
+}
+
+// queryTokens splits a boolean query into parentheses, quoted phrases with
+// any ~N suffix, and words. A quoted value straight after a field name, as
+// in tag:"science fiction", stays part of its word.
+func queryTokens(text string) []string {
+	var r []string
+	for i := 0; i < len(text); {
+		c := text[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '(' || c == ')':
+			r = append(r, text[i:i+1])
+			i++
+		case c == '"':
+			end := phraseEnd(text, i)
+			r = append(r, text[i:end])
+			i = end
+		default:
+			end := i
+			for end < len(text) && !strings.ContainsRune(" \t\n\r()\"", rune(text[end])) {
+				end++
+			}
+			if end < len(text) && text[end] == '"' && text[end-1] == ':' {
+				end = phraseEnd(text, end)
+			}
+			r = append(r, text[i:end])
+			i = end
+		}
+	}
+	return r
+}
+
+// phraseEnd returns where the quoted phrase starting at text[i], with any
+// ~N suffix, ends. An unclosed phrase runs to the end of text.
+func phraseEnd(text string, i int) int {
+	end := strings.IndexByte(text[i+1:], '"')
+	if end < 0 {
+		return len(text)
+	}
+	end += i + 2
+	for end < len(text) && (text[end] == '~' || '0' <= text[end] && text[end] <= '9') {
+		end++
+	}
+	return end
+}
+
+// queryParser is a recursive descent parser for the grammar
+//
+//	or   = and { "OR" and }
+//	and  = not { ["AND"] not }
+//	not  = "NOT" not | "(" or ")" | word
+//
+// Operators must be upper case; adjacent words are implicitly ANDed.
+type queryParser struct {
+	tokens []string
+	pos    int
+}
+
+// ParseQuery parses a boolean query such as (cat OR dog) AND NOT domestic.
+func ParseQuery(text string) (Query, error) {
+	p := &queryParser{tokens: queryTokens(text)}
+	if len(p.tokens) == 0 {
+		return nil, fmt.Errorf("empty query")
+	}
+	q, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("unexpected %q in query", p.tokens[p.pos])
+	}
+	return q, nil
+}
+
+func (p *queryParser) peek() string {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return ""
+}
+
+func (p *queryParser) or() (Query, error) {
+	var r orQuery
+	for {
+		q, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		r = append(r, q)
+		if p.peek() != "OR" {
+			break
+		}
+		p.pos++
+	}
+	if len(r) == 1 {
+		return r[0], nil
+	}
+	return r, nil
+}
+
+func (p *queryParser) and() (Query, error) {
+	var r andQuery
+	for {
+		q, err := p.not()
+		if err != nil {
+			return nil, err
+		}
+		r = append(r, q)
+		next := p.peek()
+		if next == "AND" {
+			p.pos++
+		} else if next == "" || next == "OR" || next == ")" {
+			break
+		}
+	}
+	if len(r) == 1 {
+		return r[0], nil
+	}
+	return r, nil
+}
+
+func (p *queryParser) not() (Query, error) {
+	switch tok := p.peek(); tok {
+	case "":
+		return nil, fmt.Errorf("query ends with an operator")
+	case "NOT":
+		p.pos++
+		q, err := p.not()
+		if err != nil {
+			return nil, err
+		}
+		return notQuery{q}, nil
+	case "(":
+		p.pos++
+		q, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek() != ")" {
+			return nil, fmt.Errorf("missing ) in query")
+		}
+		p.pos++
+		return q, nil
+	case ")", "AND", "OR":
+		return nil, fmt.Errorf("unexpected %q in query", tok)
+	default:
+		p.pos++
+		return wordQuery(tok), nil
+	}
+}
+
+// SearchQuery returns the documents matching the boolean query q.
+// Stopwords are left out, so cat AND the matches what cat does.
+func (idx *Index) SearchQuery(q Query) []int {
+	ids, _ := q.match(idx)
+	return idx.live(ids)
+}
+
+// SearchBoolean parses text as a boolean query and runs it.
+func (idx *Index) SearchBoolean(text string) ([]int, error) {
+	q, err := ParseQuery(text)
+	if err != nil {
+		return nil, err
+	}
+	return idx.SearchQuery(q), nil
+}
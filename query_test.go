@@ -0,0 +1,94 @@
+package fulltextsearch
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestParseQuery(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  string
+	}{
+		{"implicit and", "cat dog", "(cat AND dog)"},
+		{"and binds tighter than or", "cat OR dog AND bird", "(cat OR (dog AND bird))"},
+		{"parentheses", "(cat OR dog) AND bird", "((cat OR dog) AND bird)"},
+		{"not", "cat AND NOT dog", "(cat AND NOT dog)"},
+		{"double not", "NOT NOT cat", "NOT NOT cat"},
+		{"phrase", `"red panda" OR cat`, `("red panda" OR cat)`},
+		{"phrase with slop", `"red panda"~2`, `"red panda"~2`},
+		{"quoted value", `tag:"science fiction" cat`, `(tag:"science fiction" AND cat)`},
+		{"lower case operators are words", "cat or dog", "(cat AND or AND dog)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, err := ParseQuery(tt.query)
+			if err != nil {
+				t.Fatalf("ParseQuery(%q) error: %v", tt.query, err)
+			}
+			if got := q.String(); got != tt.want {
+				t.Errorf("ParseQuery(%q) = %s, want %s", tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseQueryMalformed(t *testing.T) {
+	for _, query := range []string{
+		"",
+		"   ",
+		"cat AND",
+		"OR cat",
+		"cat OR OR dog",
+		"NOT",
+		"(cat OR dog",
+		"cat)",
+		"()",
+		"cat AND )",
+	} {
+		if q, err := ParseQuery(query); err == nil {
+			t.Errorf("ParseQuery(%q) = %v, want an error", query, q)
+		}
+	}
+}
+
+func TestSearchBoolean(t *testing.T) {
+	idx := NewIndex(Config{})
+	idx.Add([]Document{
+		{ID: 0, Title: "Cats", Text: "the cat sat on the mat"},
+		{ID: 1, Title: "Dogs", Text: "the dog chased the cat"},
+		{ID: 2, Title: "Red panda", Text: "a red panda in a tree", Tags: []string{"red panda"}},
+		{ID: 3, Title: "Birds", Text: "a red bird and a panda"},
+	})
+	tests := []struct {
+		query string
+		want  []int
+	}{
+		{"cat AND dog", []int{1}},
+		{"cat OR panda", []int{0, 1, 2, 3}},
+		{"cat AND NOT dog", []int{0}},
+		{"NOT cat", []int{2, 3}},
+		{"(cat OR bird) AND NOT dog", []int{0, 3}},
+		{"cat OR bird AND red", []int{0, 1, 3}},
+		{`"red panda"`, []int{2}},
+		{`tag:"red panda"`, []int{2}},
+		{`panda AND NOT tag:"red panda"`, []int{3}},
+		// Stopwords are neutral rather than matching nothing.
+		{"cat AND the", []int{0, 1}},
+		{"the OR dog", []int{1}},
+		{"cat AND NOT the", []int{0, 1}},
+		{"the", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			got, err := idx.SearchBoolean(tt.query)
+			if err != nil {
+				t.Fatalf("SearchBoolean(%q) error: %v", tt.query, err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("SearchBoolean(%q) = %v, want %v", tt.query, got, tt.want)
+			}
+		})
+	}
+}
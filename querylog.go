@@ -0,0 +1,85 @@
+package fulltextsearch
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"os"
+	"sync/atomic"
+	"time"
+)
+
+// QueryRecord is one line of the query log.
+type QueryRecord struct {
+	Time    time.Time     `json:"time"`
+	Query   string        `json:"query"`
+	Results int           `json:"results"`
+	Latency time.Duration `json:"latency_ns"`
+}
+
+// QueryLog appends a JSON line per search to a writer for later analysis.
+// Records are written by a background goroutine so logging never slows a
+// search down; if the writer falls too far behind, records are dropped.
+type QueryLog struct {
+	records chan QueryRecord
+	done    chan struct{}
+	w       io.Writer
+	dropped atomic.Int64
+}
+
+const queryLogBuffer = 1024
+
+func NewQueryLog(w io.Writer) *QueryLog {
+	l := &QueryLog{
+		records: make(chan QueryRecord, queryLogBuffer),
+		done:    make(chan struct{}),
+		w:       w,
+	}
+	go l.run()
+	return l
+}
+
+// OpenQueryLog logs queries to the file at path, appending if it exists.
+func OpenQueryLog(path string) (*QueryLog, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return NewQueryLog(f), nil
+}
+
+func (l *QueryLog) run() {
+	defer close(l.done)
+	enc := json.NewEncoder(l.w)
+	for rec := range l.records {
+		if err := enc.Encode(rec); err != nil {
+			log.Printf("query log: %v", err)
+		}
+	}
+}
+
+// record queues a query for logging. It is a no-op on a nil log.
+func (l *QueryLog) record(query string, results int, latency time.Duration) {
+	if l == nil {
+		return
+	}
+	rec := QueryRecord{Time: time.Now(), Query: query, Results: results, Latency: latency}
+	select {
+	case l.records <- rec:
+	default:
+		l.dropped.Add(1)
+	}
+}
+
+// Close writes out any queued records and closes the writer if it can be.
+func (l *QueryLog) Close() error {
+	close(l.records)
+	<-l.done
+	if n := l.dropped.Load(); n > 0 {
+		log.Printf("query log: dropped %d records", n)
+	}
+	if c, ok := l.w.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package fulltextsearch
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestQueryLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "queries.log")
+	ql, err := OpenQueryLog(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	idx := NewIndex(Config{QueryLog: ql})
+	idx.Add([]Document{
+		{ID: 0, Text: "cat"},
+		{ID: 1, Text: "cat and dog"},
+	})
+	idx.Search("cat")
+	idx.Rank("dog", nil)
+	idx.Search("bird")
+	if err := ql.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []QueryRecord{
+		{Query: "cat", Results: 2},
+		{Query: "dog", Results: 1},
+		{Query: "bird", Results: 0},
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var got []QueryRecord
+	for s := bufio.NewScanner(f); s.Scan(); {
+		var rec QueryRecord
+		if err := json.Unmarshal(s.Bytes(), &rec); err != nil {
+			t.Fatalf("record %q: %v", s.Text(), err)
+		}
+		if rec.Time.IsZero() {
+			t.Errorf("record %q has no time", s.Text())
+		}
+		got = append(got, rec)
+	}
+	if len(got) != len(want) {
+		t.Fatalf("logged %d records, want %d", len(got), len(want))
+	}
+	for i := range got {
+		if got[i].Query != want[i].Query || got[i].Results != want[i].Results {
+			t.Errorf("record %d = %q with %d results, want %q with %d", i, got[i].Query, got[i].Results, want[i].Query, want[i].Results)
+		}
+	}
+}
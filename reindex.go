@@ -0,0 +1,68 @@
+package fulltextsearch
+
+import (
+	"crypto/sha1"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strconv"
+)
+
+// contentHash fingerprints everything about doc that gets indexed, so an
+// unchanged document can be recognised on reindexing.
+func (doc Document) contentHash() [sha1.Size]byte {
+	h := sha1.New()
+	for _, s := range []string{doc.URL, doc.Title, doc.Text, doc.Date.String(), strconv.FormatFloat(doc.Boost, 'g', -1, 64), doc.Language} {
+		io.WriteString(h, s)
+		h.Write([]byte{0})
+	}
+	for _, l := range doc.Links {
+		io.WriteString(h, l.Anchor)
+		h.Write([]byte{0})
+	}
+	for _, tag := range doc.Tags {
+		io.WriteString(h, tag)
+		h.Write([]byte{1})
+	}
+	for _, name := range slices.Sorted(maps.Keys(doc.Extra)) {
+		io.WriteString(h, name)
+		h.Write([]byte{0})
+		io.WriteString(h, doc.Extra[name])
+		h.Write([]byte{0})
+	}
+	var sum [sha1.Size]byte
+	h.Sum(sum[:0])
+	return sum
+}
+
+// Reindex adds docs to the index, skipping any whose content hash matches
+// the version already indexed under the same ID and replacing the rest. It
+// returns how many documents were (re)indexed and how many were skipped.
+func (idx *Index) Reindex(docs []Document) (indexed, skipped int) {
+	changed := make([]Document, 0, len(docs))
+	for _, doc := range docs {
+		old, ok := idx.Hashes.lookup(doc.ID)
+		if ok && old == doc.contentHash() {
+			skipped++
+			continue
+		}
+		if ok {
+			idx.Remove(doc.ID)
+		}
+		changed = append(changed, doc)
+	}
+	idx.Add(changed)
+	return len(changed), skipped
+}
+
+// Update replaces the document with doc's ID by doc, removing the postings
+// of the old version before indexing the new one. Unlike Add, it fails if
+// there is no such document.
+func (idx *Index) Update(doc Document) error {
+	if !idx.has(doc.ID) {
+		return fmt.Errorf("document %d: %w", doc.ID, ErrUnknownID)
+	}
+	idx.Add([]Document{doc})
+	return nil
+}
@@ -0,0 +1,64 @@
+package fulltextsearch
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestReindex(t *testing.T) {
+	idx := NewIndex(Config{})
+	idx.Add([]Document{
+		{ID: 0, Title: "Donut", Text: "a ring of dough"},
+		{ID: 1, Title: "Plate", Text: "a glass plate"},
+	})
+	indexed, skipped := idx.Reindex([]Document{
+		{ID: 0, Title: "Donut", Text: "a ring of dough"},
+		{ID: 1, Title: "Plate", Text: "a china plate"},
+		{ID: 2, Title: "Cup", Text: "a glass cup"},
+	})
+	if indexed != 2 || skipped != 1 {
+		t.Errorf("Reindex() = %d indexed, %d skipped, want 2, 1", indexed, skipped)
+	}
+	tests := []struct {
+		query string
+		want  []int
+	}{
+		{"dough", []int{0}},
+		{"china", []int{1}},
+		{"glass", []int{2}},
+	}
+	for _, tt := range tests {
+		if got := idx.Search(tt.query); !slices.Equal(got, tt.want) {
+			t.Errorf("Search(%s) = %v, want %v", tt.query, got, tt.want)
+		}
+	}
+	if got := idx.DocCount(); got != 3 {
+		t.Errorf("DocCount() = %d, want 3", got)
+	}
+}
+
+func TestContentHash(t *testing.T) {
+	doc := Document{ID: 0, Title: "Donut", Text: "dough", Tags: []string{"food"}}
+	tests := []struct {
+		name   string
+		change func(*Document)
+		same   bool
+	}{
+		{"unchanged", func(*Document) {}, true},
+		{"another ID", func(d *Document) { d.ID = 7 }, true},
+		{"text", func(d *Document) { d.Text = "batter" }, false},
+		{"title", func(d *Document) { d.Title = "Doughnut" }, false},
+		{"tags", func(d *Document) { d.Tags = []string{"snack"} }, false},
+		{"extra field", func(d *Document) { d.Extra = map[string]string{"author": "Ann"} }, false},
+		{"boost", func(d *Document) { d.Boost = 2 }, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			changed := doc
+			tt.change(&changed)
+			if got := changed.contentHash() == doc.contentHash(); got != tt.same {
+				t.Errorf("contentHash() unchanged = %v, want %v", got, tt.same)
+			}
+		})
+	}
+}
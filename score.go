@@ -0,0 +1,275 @@
+package fulltextsearch
+
+import (
+	"math"
+	"slices"
+	"sort"
+	"time"
+)
+
+// Result is a ranked search hit.
+type Result struct {
+	ID    int       `json:"id"`
+	Score float64   `json:"score"`
+	Date  time.Time `json:"date,omitzero"`
+
+	// Title and URL are filled in unless Resolve projects them away. The
+	// stored fields, including Text, can be filled in by Resolve.
+	Title string `json:"title,omitempty"`
+	URL   string `json:"url,omitempty"`
+	Text  string `json:"text,omitempty"`
+
+	// Matches, if requested, locates the query terms in the document text.
+	Matches []TermRange `json:"matches,omitempty"`
+	// Snippets, if requested, are the best passages of the document text.
+	Snippets []string `json:"snippets,omitempty"`
+}
+
+// BM25Params are the BM25 tuning parameters for one field. K1 controls how
+// quickly repeated occurrences of a term stop adding to the score, and B how
+// strongly a long field is penalized relative to the field's average length,
+// from 0 for not at all to 1 for fully.
+type BM25Params struct {
+	K1 float64
+	B  float64
+}
+
+var standardBM25 = BM25Params{K1: 1.2, B: 0.75}
+
+// defaultBM25 returns the per-field parameters new indexes start with.
+// Titles are short and similar in length, so they get a gentler B.
+func defaultBM25() map[string]BM25Params {
+	return map[string]BM25Params{
+		"text":  standardBM25,
+		"title": {K1: 1.2, B: 0.3},
+	}
+}
+
+func (idx *Index) params(name string) BM25Params {
+	if p, ok := idx.bm25[name]; ok {
+		return p
+	}
+	return idx.bm25Default
+}
+
+// idf returns the inverse document frequency of term within f.
+func (f *field) idf(term string) float64 {
+	n := float64(f.Lengths.len())
+	df := float64(len(f.Postings.get(term)))
+	return math.Log(1 + (n-df+0.5)/(df+0.5))
+}
+
+// Similarity is the model ranked searches score documents with.
+type Similarity int
+
+const (
+	BM25  Similarity = iota // Okapi BM25, tuned per field by BM25Params
+	TFIDF                   // classic TF-IDF with length normalization
+)
+
+// classicIDF is the TF-IDF inverse document frequency of term within f.
+func (f *field) classicIDF(term string) float64 {
+	n := float64(f.Lengths.len())
+	df := float64(len(f.Postings.get(term)))
+	return 1 + math.Log(n/(df+1))
+}
+
+// tfidf scores document id within field f by the square root of each term's
+// frequency times its IDF squared, normalized by the square root of the
+// field's length so matches in short fields count for more. idfs holds the
+// classic IDF of each of terms in f.
+func (f *field) tfidf(terms []string, idfs []float64, id int) float64 {
+	n := f.Lengths.get(id)
+	if n == 0 {
+		return 0
+	}
+	var score float64
+	for i, term := range terms {
+		tf := float64(f.freq(term, id))
+		score += math.Sqrt(tf) * idfs[i] * idfs[i]
+	}
+	return score / math.Sqrt(float64(n))
+}
+
+// score scores document id within the named field f by the index's
+// similarity.
+func (idx *Index) score(name string, f *field, terms []string, idfs []float64, id int) float64 {
+	if idx.similarity == TFIDF {
+		return f.tfidf(terms, idfs, id)
+	}
+	return f.bm25(terms, idfs, id, idx.params(name))
+}
+
+// bm25 scores document id within field f. idfs holds the IDF of each of
+// terms in f.
+func (f *field) bm25(terms []string, idfs []float64, id int, p BM25Params) float64 {
+	norm := 1 - p.B
+	if avg := f.avgLength(); avg > 0 {
+		norm += p.B * float64(f.Lengths.get(id)) / avg
+	}
+	var score float64
+	for i, term := range terms {
+		tf := float64(f.freq(term, id))
+		if tf == 0 {
+			continue
+		}
+		score += idfs[i] * tf * (p.K1 + 1) / (tf + p.K1*norm)
+	}
+	return score
+}
+
+// Rank returns the documents in which every query term (or with Or, any
+// query term) occurs in at least one of the default fields, as for Search,
+// scored by the index's similarity in each field, weighted by field and
+// summed, and ordered by s, or by score if s is nil.
+func (idx *Index) Rank(query string, s Sorter) []Result {
+	r, _ := idx.rankBefore(query, s, time.Time{}, idx.DefaultOperator)
+	return r
+}
+
+// RankWithin is Rank with a time budget. If scoring runs past it, RankWithin
+// stops and returns the results scored so far, reporting them as partial.
+func (idx *Index) RankWithin(query string, s Sorter, timeout time.Duration) (r []Result, partial bool) {
+	return idx.rankBefore(query, s, time.Now().Add(timeout), idx.DefaultOperator)
+}
+
+// deadlineCheckEvery is how many documents are scored between checks of
+// the deadline, to keep the clock out of the inner loop.
+const deadlineCheckEvery = 64
+
+// result returns an unscored result for document id.
+func (idx *Index) result(id int) Result {
+	return Result{ID: id, Date: idx.Dates.get(id), Title: idx.Titles.get(id), URL: idx.URLs.get(id)}
+}
+
+// SearchResults is Search returning presentable results, in ID order and
+// unscored, rather than bare IDs.
+func (idx *Index) SearchResults(text string) []Result {
+	ids := idx.Search(text)
+	r := make([]Result, len(ids))
+	for i, id := range ids {
+		r[i] = idx.result(id)
+	}
+	return r
+}
+
+// queryScorer scores documents against one parsed query.
+type queryScorer struct {
+	idx   *Index
+	terms map[string][]string  // field name -> analyzed query terms
+	idfs  map[string][]float64 // field name -> IDF of each of terms
+}
+
+func (sc queryScorer) result(id int) Result {
+	res := sc.idx.result(id)
+	for name, f := range sc.idx.Fields {
+		res.Score += sc.idx.weight(name) * sc.idx.score(name, f, sc.terms[name], sc.idfs[name], id)
+	}
+	return res
+}
+
+// prepareRank parses query and returns the documents matching it, combining
+// terms with op, and a scorer for them. ok is false if there is nothing to
+// search for.
+func (idx *Index) prepareRank(query string, op Operator) (ids []int, sc queryScorer, ok bool) {
+	query, filters := idx.parseFilters(query)
+	terms := idx.analyzer.AnalyzeQuery(query)
+	if len(terms) == 0 && filters.empty() {
+		return nil, sc, false
+	}
+	ids = idx.IDs
+	if len(terms) > 0 {
+		ids = idx.matchTerms(query, op, nil)
+	}
+	ids = idx.live(idx.filter(ids, filters, nil))
+
+	// Each field is scored on the query as its own analyzer reads it.
+	sc = queryScorer{
+		idx:   idx,
+		terms: make(map[string][]string, len(idx.Fields)),
+		idfs:  make(map[string][]float64, len(idx.Fields)),
+	}
+	for name := range idx.Fields {
+		fieldTerms := slices.Clip(terms)
+		if _, ok := idx.FieldAnalyzers[name]; ok {
+			fieldTerms = idx.queryTerms(name, query)
+		}
+		for _, t := range filters.scoped {
+			if t.field == name {
+				fieldTerms = append(fieldTerms, idx.queryTerms(t.field, t.word)...)
+			}
+		}
+		for _, e := range filters.expanded {
+			// Score the terms that wildcard and fuzzy words matched like
+			// any others.
+			fieldTerms = append(fieldTerms, e.Terms...)
+		}
+		sc.terms[name] = fieldTerms
+		sc.idfs[name] = make([]float64, len(fieldTerms))
+		for i, term := range fieldTerms {
+			sc.idfs[name][i] = idx.idf(name, term)
+		}
+	}
+	return ids, sc, true
+}
+
+// rankBefore ranks, combining terms with op and stopping at deadline unless
+// it is zero.
+func (idx *Index) rankBefore(query string, s Sorter, deadline time.Time, op Operator) (r []Result, partial bool) {
+	start := time.Now()
+	defer func(query string) {
+		idx.queries.record(query, len(r), time.Since(start))
+	}(query)
+
+	ids, sc, ok := idx.prepareRank(query, op)
+	if !ok {
+		return nil, false
+	}
+	r = make([]Result, 0, len(ids))
+	for i, id := range ids {
+		if !deadline.IsZero() && i > 0 && i%deadlineCheckEvery == 0 && time.Now().After(deadline) {
+			partial = true
+			break
+		}
+		r = append(r, sc.result(id))
+	}
+	if idx.scoring != nil {
+		idx.scoring.rescore(r, &idx.Boosts)
+	}
+	if s == nil {
+		s = ByScore{}
+	}
+	sortResults(r, s)
+	return r, partial
+}
+
+// Tier is a group of ranked results of similar quality.
+type Tier struct {
+	// MatchesAll is set for the tier of documents matching every term.
+	MatchesAll bool
+	Results    []Result
+}
+
+// RankTiers ranks documents matching any query term, and splits them into
+// a tier of those matching every term followed by a tier of those matching
+// only some. Each tier is ordered by score; empty tiers are left out.
+func (idx *Index) RankTiers(query string) []Tier {
+	r, _ := idx.rankBefore(query, ByScore{}, time.Time{}, Or)
+	text, _ := idx.parseFilters(query)
+	all := idx.matchTerms(text, And, nil)
+
+	var tiers []Tier
+	for _, matchesAll := range []bool{true, false} {
+		t := Tier{MatchesAll: matchesAll}
+		for _, res := range r {
+			i := sort.SearchInts(all, res.ID)
+			if (i < len(all) && all[i] == res.ID) == matchesAll {
+				t.Results = append(t.Results, res)
+			}
+		}
+		if len(t.Results) > 0 {
+			tiers = append(tiers, t)
+		}
+	}
+	return tiers
+}
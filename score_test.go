@@ -0,0 +1,121 @@
+package fulltextsearch
+
+import (
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestTitleLengthNormalization(t *testing.T) {
+	docs := []Document{
+		{ID: 0, Title: "Otter", Text: "a river animal"},
+		{ID: 1, Title: "Otter of the rivers and lakes of northern Europe", Text: "a river animal"},
+		{ID: 2, Title: "Badger", Text: "a burrowing animal"},
+	}
+	// ratio returns how many times the short title's score the long
+	// title's is.
+	ratio := func(cfg Config) float64 {
+		idx := NewIndex(cfg)
+		idx.Add(docs)
+		scores := make(map[int]float64)
+		for _, r := range idx.Rank("otter", nil) {
+			scores[r.ID] = r.Score
+		}
+		if len(scores) != 2 {
+			t.Fatalf("Rank(otter) scored %v, want documents 0 and 1", scores)
+		}
+		return scores[1] / scores[0]
+	}
+	gentle := ratio(Config{})
+	full := ratio(Config{BM25: map[string]BM25Params{"title": standardBM25}})
+	if gentle >= 1 {
+		t.Errorf("long title scored %.2f times the short one, want less", gentle)
+	}
+	if gentle <= full {
+		t.Errorf("long title scored %.2f times the short one with the title's b, want more than %.2f with the text's", gentle, full)
+	}
+}
+
+func TestRankWithin(t *testing.T) {
+	docs := make([]Document, 10*deadlineCheckEvery)
+	for i := range docs {
+		docs[i] = Document{ID: i, Text: "cat number " + strconv.Itoa(i)}
+	}
+	idx := NewIndex(Config{})
+	idx.Add(docs)
+	tests := []struct {
+		name        string
+		timeout     time.Duration
+		wantPartial bool
+	}{
+		{"ample", time.Hour, false},
+		{"tiny", time.Nanosecond, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, partial := idx.RankWithin("cat", nil, tt.timeout)
+			if partial != tt.wantPartial {
+				t.Errorf("RankWithin(%v) partial = %v, want %v", tt.timeout, partial, tt.wantPartial)
+			}
+			if partial && (len(r) == 0 || len(r) >= len(docs)) {
+				t.Errorf("RankWithin(%v) = %d results, want a nonempty subset of %d", tt.timeout, len(r), len(docs))
+			}
+			if !partial && len(r) != len(docs) {
+				t.Errorf("RankWithin(%v) = %d results, want %d", tt.timeout, len(r), len(docs))
+			}
+		})
+	}
+}
+
+func TestRankTiers(t *testing.T) {
+	idx := NewIndex(Config{})
+	idx.Add([]Document{
+		{ID: 0, Text: "volcano"},
+		{ID: 1, Text: "volcano eruption in iceland"},
+		{ID: 2, Text: "eruption eruption"},
+		{ID: 3, Text: "glacier"},
+	})
+	tests := []struct {
+		query string
+		want  [][]int // IDs of each tier, the all-terms tier first if any
+		all   []bool
+	}{
+		{"volcano eruption", [][]int{{1}, {2, 0}}, []bool{true, false}},
+		{"volcano", [][]int{{0, 1}}, []bool{true}},
+		{"volcano glacier lava", [][]int{{3, 0, 1}}, []bool{false}},
+		{"lava", nil, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			tiers := idx.RankTiers(tt.query)
+			if len(tiers) != len(tt.want) {
+				t.Fatalf("RankTiers(%s) = %d tiers, want %d", tt.query, len(tiers), len(tt.want))
+			}
+			for i, tier := range tiers {
+				var got []int
+				for _, r := range tier.Results {
+					got = append(got, r.ID)
+				}
+				if !slices.Equal(got, tt.want[i]) || tier.MatchesAll != tt.all[i] {
+					t.Errorf("tier %d = %v (matches all %v), want %v (%v)", i, got, tier.MatchesAll, tt.want[i], tt.all[i])
+				}
+			}
+		})
+	}
+}
+
+func TestScopedTermScoresOnlyItsField(t *testing.T) {
+	idx := NewIndex(Config{})
+	idx.Add([]Document{
+		{ID: 0, Title: "Anarchism", Text: "history ideas"},
+		{ID: 1, Title: "Anarchism", Text: "history anarchism"},
+	})
+	r := idx.Rank("title:anarchism history", nil)
+	if len(r) != 2 {
+		t.Fatalf("Rank() = %v, want documents 0 and 1", r)
+	}
+	if r[0].Score != r[1].Score {
+		t.Errorf("Rank() = %v, want equal scores: anarchism in the text shouldn't count for title:anarchism", r)
+	}
+}
@@ -0,0 +1,452 @@
+package fulltextsearch
+
+import (
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+)
+
+// SegmentSet is an index made of immutable segments, Lucene-style. Added
+// documents are buffered in memory and flushed to a new segment file once
+// there are enough of them, so indexing a huge dump never holds more than
+// one buffer's worth of documents in memory, and existing segments are
+// never rewritten, which makes the set easy to snapshot and back up. When
+// too many segments build up, the smallest are merged into one in the
+// background. Searches query every segment and merge the results;
+// documents are expected to have distinct IDs across segments.
+//
+// A manifest in the directory names the live segments. It is replaced
+// atomically whenever they change, so a merge cut short by a crash leaves
+// either the old segments or the merged one live, never both.
+//
+// A SegmentSet is safe for concurrent use.
+type SegmentSet struct {
+	dir string
+	cfg Config // without the query log, which the set records to itself
+	// queries, if non-nil, logs every search and ranked search once,
+	// however many segments it queries.
+	queries *QueryLog
+
+	// FlushDocs is how many added documents are buffered before they are
+	// flushed to a new segment, defaulting to defaultFlushDocs.
+	FlushDocs int
+	// MergeFactor is how many segments may build up before the smallest of
+	// them are merged, defaulting to defaultMergeFactor.
+	MergeFactor int
+
+	mu       sync.RWMutex // guards the fields below and the segments' indexes
+	segments []*segment
+	buffer   *Index // documents not yet flushed; nil when empty
+	next     int    // number of the next segment file
+	merging  bool
+	merges   sync.WaitGroup
+	mergeErr error
+}
+
+// segment is one segment file and its loaded index. Deletions are recorded
+// as tombstones in a companion file, leaving the segment itself untouched.
+type segment struct {
+	path string
+	idx  *Index
+}
+
+const (
+	segmentPattern = "segment-*.idx"
+	segmentFormat  = "segment-%06d.idx"
+	// manifestName names the file listing the live segments.
+	manifestName = "segments"
+	// deletesSuffix is appended to a segment's path to name the file
+	// holding its tombstones.
+	deletesSuffix = ".del"
+
+	defaultFlushDocs   = 100000
+	defaultMergeFactor = 10
+)
+
+// OpenSegments loads the segments in dir named by its manifest, oldest
+// first, or every segment if it has no manifest yet. Segment files the
+// manifest doesn't name, left by a merge that didn't finish, are ignored.
+func OpenSegments(dir string, cfg Config) (*SegmentSet, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, segmentPattern))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths) // names are zero padded, so this is creation order
+
+	s := &SegmentSet{dir: dir, cfg: cfg, queries: cfg.QueryLog}
+	s.cfg.QueryLog = nil
+	for _, path := range paths {
+		var n int
+		if _, err := fmt.Sscanf(filepath.Base(path), segmentFormat, &n); err == nil {
+			s.next = max(s.next, n+1)
+		}
+	}
+	live, err := s.loadManifest()
+	if os.IsNotExist(err) {
+		live = paths
+	} else if err != nil {
+		return nil, err
+	}
+	for _, path := range live {
+		seg := NewIndex(s.cfg)
+		if err := seg.Load(path); err != nil {
+			return nil, fmt.Errorf("loading segment %s: %w", path, err)
+		}
+		if err := loadDeletes(seg, path); err != nil {
+			return nil, fmt.Errorf("loading segment %s: %w", path, err)
+		}
+		s.segments = append(s.segments, &segment{path: path, idx: seg})
+	}
+	return s, nil
+}
+
+// loadManifest returns the paths of the segments the manifest names.
+func (s *SegmentSet) loadManifest() ([]string, error) {
+	f, err := os.Open(filepath.Join(s.dir, manifestName))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var names []string
+	if err := gob.NewDecoder(f).Decode(&names); err != nil {
+		return nil, fmt.Errorf("loading segment manifest: %w", err)
+	}
+	paths := make([]string, len(names))
+	for i, name := range names {
+		paths[i] = filepath.Join(s.dir, name)
+	}
+	return paths, nil
+}
+
+// saveManifest replaces the manifest with one naming the current segments.
+// s.mu must be held.
+func (s *SegmentSet) saveManifest() error {
+	names := make([]string, len(s.segments))
+	for i, seg := range s.segments {
+		names[i] = filepath.Base(seg.path)
+	}
+	return writeFileAtomic(filepath.Join(s.dir, manifestName), func(w io.Writer) error {
+		return gob.NewEncoder(w).Encode(names)
+	})
+}
+
+// AddSegment indexes docs into a new segment and writes it to disk, without
+// going through the buffer.
+func (s *SegmentSet) AddSegment(docs []Document) error {
+	seg := NewIndex(s.cfg)
+	seg.Add(docs)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.write(seg); err != nil {
+		return err
+	}
+	s.maybeMerge()
+	return nil
+}
+
+// Add buffers docs, flushing the buffer to a new segment once it holds
+// FlushDocs documents.
+func (s *SegmentSet) Add(docs []Document) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.add(docs)
+}
+
+func (s *SegmentSet) add(docs []Document) error {
+	if s.buffer == nil {
+		s.buffer = NewIndex(s.cfg)
+	}
+	s.buffer.Add(docs)
+	flushDocs := s.FlushDocs
+	if flushDocs <= 0 {
+		flushDocs = defaultFlushDocs
+	}
+	if s.buffer.DocCount() < flushDocs {
+		return nil
+	}
+	return s.flush()
+}
+
+// Flush writes any buffered documents to a new segment.
+func (s *SegmentSet) Flush() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.flush()
+}
+
+func (s *SegmentSet) flush() error {
+	if s.buffer == nil {
+		return nil
+	}
+	if err := s.write(s.buffer); err != nil {
+		return err
+	}
+	s.buffer = nil
+	s.maybeMerge()
+	return nil
+}
+
+// write saves idx as the next segment and adds it to the set.
+func (s *SegmentSet) write(idx *Index) error {
+	path, err := s.reserve()
+	if err != nil {
+		return err
+	}
+	if err := idx.Save(path); err != nil {
+		return err
+	}
+	s.segments = append(s.segments, &segment{path: path, idx: idx})
+	if err := s.saveManifest(); err != nil {
+		s.segments = s.segments[:len(s.segments)-1]
+		removeSegment(path)
+		return err
+	}
+	return nil
+}
+
+// removeSegment removes the segment at path and its companion files.
+func removeSegment(path string) error {
+	var errs []error
+	for _, name := range []string{path, path + storeSuffix, path + deletesSuffix} {
+		if err := os.Remove(name); err != nil && !os.IsNotExist(err) {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// reserve returns the path of the next segment. s.mu must be held.
+func (s *SegmentSet) reserve() (string, error) {
+	if err := os.MkdirAll(s.dir, 0755); err != nil {
+		return "", err
+	}
+	path := filepath.Join(s.dir, fmt.Sprintf(segmentFormat, s.next))
+	if _, err := os.Stat(path); err == nil {
+		return "", fmt.Errorf("segment %s already exists", path)
+	}
+	s.next++
+	return path, nil
+}
+
+// Delete deletes document id, wherever it is. A flushed segment only gets
+// a tombstone, and the document is dropped for good when the segment is
+// next merged.
+func (s *SegmentSet) Delete(id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.delete(id)
+}
+
+func (s *SegmentSet) delete(id int) error {
+	if s.buffer != nil {
+		s.buffer.Delete(id)
+	}
+	for _, seg := range s.segments {
+		if _, ok := slices.BinarySearch(seg.idx.IDs, id); !ok {
+			continue
+		}
+		seg.idx.Delete(id)
+		if err := saveDeletes(seg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Update replaces the document with doc's ID by doc.
+func (s *SegmentSet) Update(doc Document) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.delete(doc.ID); err != nil {
+		return err
+	}
+	return s.add([]Document{doc})
+}
+
+// saveDeletes writes seg's tombstones beside it.
+func saveDeletes(seg *segment) error {
+	return writeFileAtomic(seg.path+deletesSuffix, func(w io.Writer) error {
+		return gob.NewEncoder(w).Encode(seg.idx.Deleted)
+	})
+}
+
+// loadDeletes applies the tombstones saved beside the segment at path.
+func loadDeletes(idx *Index, path string) error {
+	f, err := os.Open(path + deletesSuffix)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	defer f.Close()
+	var deleted []int
+	if err := gob.NewDecoder(f).Decode(&deleted); err != nil {
+		return fmt.Errorf("loading deletes: %w", err)
+	}
+	for _, id := range deleted {
+		idx.Delete(id)
+	}
+	return nil
+}
+
+// maybeMerge starts merging the smallest segments in the background if
+// there are more than MergeFactor and no merge is running. s.mu must be
+// held.
+func (s *SegmentSet) maybeMerge() {
+	factor := s.MergeFactor
+	if factor < 2 {
+		factor = defaultMergeFactor
+	}
+	if s.merging || len(s.segments) <= factor {
+		return
+	}
+	victims := slices.Clone(s.segments)
+	slices.SortStableFunc(victims, func(a, b *segment) int {
+		return len(a.idx.IDs) - len(b.idx.IDs)
+	})
+	victims = victims[:factor]
+
+	// Merge copies of the segments, so searches can go on reading them.
+	// Deletions made while the merge runs are caught up with afterwards.
+	copies := make([]*Index, len(victims))
+	for i, seg := range victims {
+		copies[i] = seg.idx.clone()
+	}
+	s.merging = true
+	s.merges.Add(1)
+	go s.merge(victims, copies)
+}
+
+// merge replaces victims by a single segment built from copies of them.
+// Only swapping the merged segment in holds the lock.
+func (s *SegmentSet) merge(victims []*segment, copies []*Index) {
+	defer s.merges.Done()
+
+	merged := NewIndex(s.cfg)
+	deleted := make([][]int, len(copies))
+	for i, c := range copies {
+		deleted[i] = c.Deleted
+		c.Compact()
+		merged.merge(c)
+	}
+	s.mu.Lock()
+	path, err := s.reserve()
+	s.mu.Unlock()
+	if err == nil {
+		err = merged.Save(path)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.merging = false
+	if err != nil {
+		s.mergeErr = errors.Join(s.mergeErr, err)
+		return
+	}
+	seg := &segment{path: path, idx: merged}
+	for i, v := range victims {
+		for _, id := range difference(v.idx.Deleted, deleted[i]) {
+			merged.Delete(id)
+		}
+	}
+	if len(merged.Deleted) > 0 {
+		if err := saveDeletes(seg); err != nil {
+			s.mergeErr = errors.Join(s.mergeErr, err, removeSegment(path))
+			return
+		}
+	}
+	old := s.segments
+	s.segments = slices.DeleteFunc(slices.Clone(s.segments), func(seg *segment) bool {
+		return slices.Contains(victims, seg)
+	})
+	s.segments = append(s.segments, seg)
+	// Until the new manifest is in place, the old one still names the
+	// victims, so they can only be removed after it.
+	if err := s.saveManifest(); err != nil {
+		s.segments = old
+		s.mergeErr = errors.Join(s.mergeErr, err, removeSegment(path))
+		return
+	}
+	for _, v := range victims {
+		s.mergeErr = errors.Join(s.mergeErr, removeSegment(v.path))
+	}
+	s.maybeMerge()
+}
+
+// Close flushes the buffer and waits for merges to finish, returning any
+// error a background merge ran into.
+func (s *SegmentSet) Close() error {
+	s.mu.Lock()
+	err := s.flush()
+	s.mu.Unlock()
+	s.merges.Wait()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return errors.Join(err, s.mergeErr)
+}
+
+// indexes returns the segments' indexes and the buffer, if any. s.mu must
+// be held.
+func (s *SegmentSet) indexes() []*Index {
+	r := make([]*Index, 0, len(s.segments)+1)
+	for _, seg := range s.segments {
+		r = append(r, seg.idx)
+	}
+	if s.buffer != nil {
+		r = append(r, s.buffer)
+	}
+	return r
+}
+
+func (s *SegmentSet) DocCount() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	n := 0
+	for _, idx := range s.indexes() {
+		n += idx.DocCount()
+	}
+	return n
+}
+
+func (s *SegmentSet) Search(text string) (r []int) {
+	start := time.Now()
+	defer func() {
+		s.queries.record(text, len(r), time.Since(start))
+	}()
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, idx := range s.indexes() {
+		r = union(r, idx.Search(text))
+	}
+	return r
+}
+
+// Rank merges the ranked results of each segment. Scores use each segment's
+// own term statistics, so they are only approximately comparable.
+func (s *SegmentSet) Rank(query string, sorter Sorter) (r []Result) {
+	start := time.Now()
+	defer func() {
+		s.queries.record(query, len(r), time.Since(start))
+	}()
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, idx := range s.indexes() {
+		r = append(r, idx.Rank(query, nil)...)
+	}
+	if sorter == nil {
+		sorter = ByScore{}
+	}
+	sortResults(r, sorter)
+	return r
+}
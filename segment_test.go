@@ -0,0 +1,200 @@
+package fulltextsearch
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestSegmentSetSecondSegment(t *testing.T) {
+	dir := t.TempDir()
+	s, err := OpenSegments(dir, Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.AddSegment([]Document{{ID: 0, Text: "glass plate"}, {ID: 1, Text: "donut"}}); err != nil {
+		t.Fatal(err)
+	}
+	paths, _ := filepath.Glob(filepath.Join(dir, segmentPattern))
+	if len(paths) != 1 {
+		t.Fatalf("segments after the first AddSegment = %q, want one", paths)
+	}
+	first, err := os.ReadFile(paths[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.AddSegment([]Document{{ID: 2, Text: "glass donut"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if after, err := os.ReadFile(paths[0]); err != nil || !bytes.Equal(after, first) {
+		t.Errorf("first segment rewritten by the second AddSegment (err %v)", err)
+	}
+
+	reopened, err := OpenSegments(dir, Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		query string
+		want  []int
+	}{
+		{"glass", []int{0, 2}},
+		{"donut", []int{1, 2}},
+		{"glass donut", []int{2}},
+	}
+	for _, set := range []*SegmentSet{s, reopened} {
+		for _, tt := range tests {
+			if got := set.Search(tt.query); !slices.Equal(got, tt.want) {
+				t.Errorf("Search(%s) = %v, want %v", tt.query, got, tt.want)
+			}
+		}
+	}
+	if got := reopened.DocCount(); got != 3 {
+		t.Errorf("DocCount() = %d, want 3", got)
+	}
+}
+
+func TestSegmentSetFlush(t *testing.T) {
+	dir := t.TempDir()
+	s, err := OpenSegments(dir, Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.FlushDocs = 2
+	segments := func() int {
+		paths, _ := filepath.Glob(filepath.Join(dir, segmentPattern))
+		return len(paths)
+	}
+
+	if err := s.Add([]Document{{ID: 0, Text: "donut"}}); err != nil {
+		t.Fatal(err)
+	}
+	if n := segments(); n != 0 {
+		t.Errorf("segments after buffering one document = %d, want 0", n)
+	}
+	if got, want := s.Search("donut"), []int{0}; !slices.Equal(got, want) {
+		t.Errorf("Search() of the buffer = %v, want %v", got, want)
+	}
+	if err := s.Add([]Document{{ID: 1, Text: "donut"}}); err != nil {
+		t.Fatal(err)
+	}
+	if n := segments(); n != 1 {
+		t.Errorf("segments after filling the buffer = %d, want 1", n)
+	}
+	if err := s.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if n := segments(); n != 1 {
+		t.Errorf("segments after flushing an empty buffer = %d, want 1", n)
+	}
+	if err := s.Add([]Document{{ID: 2, Text: "donut"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if n := segments(); n != 2 {
+		t.Errorf("segments after Close = %d, want 2", n)
+	}
+
+	reopened, err := OpenSegments(dir, Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := reopened.Search("donut"), []int{0, 1, 2}; !slices.Equal(got, want) {
+		t.Errorf("Search() after reopening = %v, want %v", got, want)
+	}
+}
+
+func TestSegmentSetMerge(t *testing.T) {
+	dir := t.TempDir()
+	s, err := OpenSegments(dir, Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.MergeFactor = 2
+	for id := range 3 {
+		if err := s.AddSegment([]Document{{ID: id, Text: "donut"}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.Delete(0); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	paths, _ := filepath.Glob(filepath.Join(dir, segmentPattern))
+	live, err := s.loadManifest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(live) != 2 || !slices.Equal(paths, live) {
+		t.Errorf("segment files = %q, manifest = %q, want the same two", paths, live)
+	}
+	if got, want := s.Search("donut"), []int{1, 2}; !slices.Equal(got, want) {
+		t.Errorf("Search() = %v, want %v", got, want)
+	}
+
+	// A segment written by a merge that never got into the manifest is
+	// left out.
+	data, err := os.ReadFile(live[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	stray := filepath.Join(dir, "segment-999999.idx")
+	if err := os.WriteFile(stray, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	reopened, err := OpenSegments(dir, Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := reopened.Search("donut"), []int{1, 2}; !slices.Equal(got, want) {
+		t.Errorf("Search() after reopening = %v, want %v", got, want)
+	}
+	if got := reopened.DocCount(); got != 2 {
+		t.Errorf("DocCount() after reopening = %d, want 2", got)
+	}
+}
+
+func TestSegmentSetQueryLog(t *testing.T) {
+	var buf bytes.Buffer
+	ql := NewQueryLog(&buf)
+	s, err := OpenSegments(t.TempDir(), Config{QueryLog: ql})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for id := range 2 {
+		if err := s.AddSegment([]Document{{ID: id, Text: "donut"}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	s.Add([]Document{{ID: 2, Text: "donut"}})
+	s.Search("donut")
+	s.Rank("donut", nil)
+	if err := ql.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	var got []QueryRecord
+	for sc := bufio.NewScanner(&buf); sc.Scan(); {
+		var rec QueryRecord
+		if err := json.Unmarshal(sc.Bytes(), &rec); err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, rec)
+	}
+	if len(got) != 2 || got[0].Results != 3 || got[1].Results != 3 {
+		t.Errorf("query log = %+v, want one record of 3 results per call", got)
+	}
+}
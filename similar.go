@@ -0,0 +1,130 @@
+package fulltextsearch
+
+import "sort"
+
+// defaultMoreLikeThisTerms is how many of a document's most distinctive
+// terms MoreLikeThis searches with, unless Config.MoreLikeThisTerms is set.
+const defaultMoreLikeThisTerms = 10
+
+// termFreqs returns how often each term occurs in the text of document id.
+// It uses the forward index if there is one, reanalyzes the stored document
+// if there is a store, and otherwise has to look the document up in every
+// posting list.
+func (idx *Index) termFreqs(id int) map[string]int {
+	r := make(map[string]int)
+	if idx.Forward != nil {
+		for _, term := range idx.Forward.get(id) {
+			r[term]++
+		}
+		return r
+	}
+	if idx.store != nil {
+		doc, ok := idx.store.lookup(id)
+		if ok {
+			for _, term := range idx.analyzerFor(doc).Analyze(doc.Text) {
+				r[term]++
+			}
+		}
+		return r
+	}
+	f, ok := idx.Fields["text"]
+	if !ok {
+		return r
+	}
+	for term := range f.Postings.keys() {
+		if n := f.freq(term, id); n > 0 {
+			r[term] = n
+		}
+	}
+	return r
+}
+
+// MoreLikeThis returns up to n documents similar to document docID, found
+// by searching for its highest TF-IDF terms. docID itself is excluded.
+func (idx *Index) MoreLikeThis(docID int, n int) []Result {
+	f, ok := idx.Fields["text"]
+	if !ok {
+		return nil
+	}
+	freqs := idx.termFreqs(docID)
+	terms := make([]string, 0, len(freqs))
+	weight := make(map[string]float64, len(freqs))
+	for term, tf := range freqs {
+		terms = append(terms, term)
+		weight[term] = float64(tf) * idx.idf("text", term)
+	}
+	sort.Slice(terms, func(i, j int) bool {
+		if weight[terms[i]] != weight[terms[j]] {
+			return weight[terms[i]] > weight[terms[j]]
+		}
+		return terms[i] < terms[j]
+	})
+	max := idx.mltTerms
+	if max <= 0 {
+		max = defaultMoreLikeThisTerms
+	}
+	if len(terms) > max {
+		terms = terms[:max]
+	}
+
+	var candidates []int
+	idfs := make([]float64, len(terms))
+	for i, term := range terms {
+		candidates = union(candidates, f.Postings.get(term))
+		idfs[i] = idx.idf("text", term)
+	}
+	r := make([]Result, 0, len(candidates))
+	for _, id := range candidates {
+		if id == docID || idx.isDeleted(id) {
+			continue
+		}
+		res := idx.result(id)
+		res.Score = idx.score("text", f, terms, idfs, id)
+		r = append(r, res)
+	}
+	sortResults(r, ByScore{})
+	if len(r) > n {
+		r = r[:n]
+	}
+	return r
+}
+
+// TermCount is a term and the number of documents it occurs in.
+type TermCount struct {
+	Term  string
+	Count int
+}
+
+// ResultTerms returns the n terms occurring in the most of docIDs, which
+// surfaces the themes of a result set. Terms of the exclude queries, such as
+// the query that found the results, are left out.
+func (idx *Index) ResultTerms(docIDs []int, n int, exclude ...string) []TermCount {
+	skip := make(map[string]struct{})
+	for _, q := range exclude {
+		for _, term := range idx.analyzer.Analyze(q) {
+			skip[term] = struct{}{}
+		}
+	}
+	counts := make(map[string]int)
+	for _, id := range docIDs {
+		for term := range idx.termFreqs(id) {
+			if _, ok := skip[term]; !ok {
+				counts[term]++
+			}
+		}
+	}
+	r := make([]TermCount, 0, len(counts))
+	for term, c := range counts {
+		r = append(r, TermCount{Term: term, Count: c})
+	}
+	sort.Slice(r, func(i, j int) bool {
+		if r[i].Count != r[j].Count {
+			return r[i].Count > r[j].Count
+		}
+		return r[i].Term < r[j].Term
+	})
+	if len(r) > n {
+		r = r[:n]
+	}
+	return r
+}
@@ -0,0 +1,69 @@
+package fulltextsearch
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestMoreLikeThis(t *testing.T) {
+	docs := []Document{
+		{ID: 0, Text: "volcano eruption lava ash"},
+		{ID: 1, Text: "lava flows after a volcano eruption"},
+		{ID: 2, Text: "ash clouds from a volcano"},
+		{ID: 3, Text: "baking bread with yeast"},
+		{ID: 4, Text: "the volcano museum gift shop"},
+	}
+	tests := []struct {
+		name string
+		cfg  Config
+		n    int
+		want []int
+	}{
+		{"posting lists", Config{}, 10, []int{1, 2, 4}},
+		{"forward index", Config{ForwardIndex: true}, 10, []int{1, 2, 4}},
+		{"document store", Config{StoreDocuments: true}, 10, []int{1, 2, 4}},
+		{"first n", Config{}, 2, []int{1, 2}},
+		{"one term", Config{MoreLikeThisTerms: 1}, 10, []int{2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			idx := NewIndex(tt.cfg)
+			idx.Add(docs)
+			var got []int
+			for _, r := range idx.MoreLikeThis(0, tt.n) {
+				got = append(got, r.ID)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("MoreLikeThis(0, %d) = %v, want %v", tt.n, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResultTerms(t *testing.T) {
+	idx := NewIndex(Config{ForwardIndex: true})
+	idx.Add([]Document{
+		{ID: 0, Text: "volcano lava eruption"},
+		{ID: 1, Text: "volcano lava ash"},
+		{ID: 2, Text: "bread yeast oven"},
+		{ID: 3, Text: "bread yeast flour"},
+		{ID: 4, Text: "bread oven"},
+	})
+	tests := []struct {
+		name    string
+		ids     []int
+		exclude []string
+		want    []TermCount
+	}{
+		{"volcano results", []int{0, 1}, nil, []TermCount{{"lava", 2}, {"volcano", 2}, {"ash", 1}}},
+		{"bread results", []int{2, 3, 4}, nil, []TermCount{{"bread", 3}, {"oven", 2}, {"yeast", 2}}},
+		{"query terms left out", []int{2, 3, 4}, []string{"bread"}, []TermCount{{"oven", 2}, {"yeast", 2}, {"flour", 1}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := idx.ResultTerms(tt.ids, 3, tt.exclude...); !slices.Equal(got, tt.want) {
+				t.Errorf("ResultTerms(%v, 3) = %v, want %v", tt.ids, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,198 @@
+package fulltextsearch
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"sync"
+	"sync/atomic"
+)
+
+// LiveIndex lets searches run lock-free against an immutable snapshot while
+// updates are applied copy-on-write to a new version that is then swapped in
+// atomically. A snapshot is never modified once published, and old ones are
+// reclaimed by the garbage collector when the last reader drops them.
+//
+// Versions share whatever an update leaves alone, so an update copies only
+// the posting lists and per-document entries it touches, along with the
+// sorted list of document IDs.
+type LiveIndex struct {
+	mu      sync.Mutex // serializes writers
+	current atomic.Pointer[Index]
+
+	// path, if set, is where AddBatch and Update persist each committed
+	// version.
+	path string
+}
+
+func NewLiveIndex(idx *Index) *LiveIndex {
+	l := new(LiveIndex)
+	l.current.Store(idx)
+	return l
+}
+
+// NewPersistentLiveIndex is like NewLiveIndex, but AddBatch and Update
+// save each new version to path, as Index.Save does, before publishing it,
+// so a version that can't be saved is never searched either.
+func NewPersistentLiveIndex(idx *Index, path string) *LiveIndex {
+	l := NewLiveIndex(idx)
+	l.path = path
+	return l
+}
+
+// Snapshot returns the current version of the index. Callers must treat it
+// as read-only.
+func (l *LiveIndex) Snapshot() *Index {
+	return l.current.Load()
+}
+
+func (l *LiveIndex) Search(text string) []int {
+	return l.Snapshot().Search(text)
+}
+
+func (l *LiveIndex) Rank(query string, s Sorter) []Result {
+	return l.Snapshot().Rank(query, s)
+}
+
+func (l *LiveIndex) DocCount() int {
+	return l.Snapshot().DocCount()
+}
+
+// Add adds docs without the checks of AddBatch, and without persisting
+// them even if the index has a path.
+func (l *LiveIndex) Add(docs []Document) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	next := l.current.Load().clone()
+	next.Add(docs)
+	l.current.Store(next)
+}
+
+var (
+	ErrDuplicateID = errors.New("duplicate document ID")
+	ErrInvalidID   = errors.New("invalid document ID")
+	ErrUnknownID   = errors.New("unknown document ID")
+)
+
+// AddBatch adds docs as a single transaction: every document is checked,
+// indexed and persisted together, and if any step fails the live index is
+// left exactly as it was.
+func (l *LiveIndex) AddBatch(docs []Document) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	cur := l.current.Load()
+	seen := make(map[int]struct{}, len(docs))
+	for _, doc := range docs {
+		if doc.ID < 0 {
+			return fmt.Errorf("document %d: %w", doc.ID, ErrInvalidID)
+		}
+		_, dup := seen[doc.ID]
+		if _, ok := cur.Hashes.lookup(doc.ID); ok || dup {
+			return fmt.Errorf("document %d: %w", doc.ID, ErrDuplicateID)
+		}
+		seen[doc.ID] = struct{}{}
+	}
+
+	next := cur.clone()
+	next.Add(docs)
+	if l.path != "" {
+		if err := next.Save(l.path); err != nil {
+			return err
+		}
+	}
+	l.current.Store(next)
+	return nil
+}
+
+// Update replaces the document with doc's ID by doc. Searches see either
+// the old version or the new one, never neither or both.
+func (l *LiveIndex) Update(doc Document) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	next := l.current.Load().clone()
+	if err := next.Update(doc); err != nil {
+		return err
+	}
+	if l.path != "" {
+		if err := next.Save(l.path); err != nil {
+			return err
+		}
+	}
+	l.current.Store(next)
+	return nil
+}
+
+// Backup writes a snapshot of the current version of the index to w, as
+// Index.Snapshot does. Versions are never modified, so it needs no lock
+// and updates carry on while it runs.
+func (l *LiveIndex) Backup(w io.Writer) error {
+	return l.Snapshot().Snapshot(w)
+}
+
+// clone returns a copy of idx that can be modified without affecting idx,
+// nor idx without affecting it. The maps are cowMaps, which share their
+// entries until either side changes them, so an update only copies the
+// terms and documents it touches. The sorted ID lists are the exception:
+// the first insertion into one copies it whole, which is a quick copy of
+// memory.
+func (idx *Index) clone() *Index {
+	next := *idx
+	next.Fields = make(map[string]*field, len(idx.Fields))
+	for name, f := range idx.Fields {
+		next.Fields[name] = f.clone()
+	}
+	next.IDs = idx.IDs[:len(idx.IDs):len(idx.IDs)]
+	next.Deleted = slices.Clip(idx.Deleted)
+	next.Dates = idx.Dates.clone()
+	next.Present = clipSlices(idx.Present)
+	next.Hashes = idx.Hashes.clone()
+	next.Boosts = idx.Boosts.clone()
+	next.Titles = idx.Titles.clone()
+	next.URLs = idx.URLs.clone()
+	next.TitleWords = idx.TitleWords.clone()
+	next.Keys = idx.Keys.clone()
+	next.Forward = cloneCow(idx.Forward)
+	if idx.store != nil {
+		next.store = cloneCow(idx.store)
+		next.storeFile.pending = slices.Clip(idx.storeFile.pending)
+	}
+	next.positions = new(positionCache)
+	next.idfs = new(idfCache)
+	next.dict = new(termDict)
+	next.titleDict = new(termDict)
+	if idx.bitmaps != nil {
+		next.bitmaps = new(bitmapCache)
+	}
+	return &next
+}
+
+func (f *field) clone() *field {
+	return &field{
+		Postings:    f.Postings.clone(),
+		Freqs:       f.Freqs.clone(),
+		Positions:   cloneCow(f.Positions),
+		Lengths:     f.Lengths.clone(),
+		TotalLength: f.TotalLength,
+	}
+}
+
+// cloneCow clones the map m points to, if any.
+func cloneCow[K comparable, V any](m *cowMap[K, V]) *cowMap[K, V] {
+	if m == nil {
+		return nil
+	}
+	c := m.clone()
+	return &c
+}
+
+func clipSlices[K comparable, V any](m map[K][]V) map[K][]V {
+	r := make(map[K][]V, len(m))
+	for k, v := range m {
+		r[k] = v[:len(v):len(v)]
+	}
+	return r
+}
@@ -0,0 +1,155 @@
+package fulltextsearch
+
+import (
+	"errors"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+func TestLiveIndexAddBatchRollsBack(t *testing.T) {
+	tests := []struct {
+		name  string
+		path  func(dir string) string
+		batch []Document
+		want  error
+	}{
+		{
+			name:  "duplicate of an indexed document",
+			path:  func(string) string { return "" },
+			batch: []Document{{ID: 1, Text: "donut"}, {ID: 0, Text: "donut"}},
+			want:  ErrDuplicateID,
+		},
+		{
+			name:  "duplicate within the batch",
+			path:  func(string) string { return "" },
+			batch: []Document{{ID: 1, Text: "donut"}, {ID: 1, Text: "donut"}},
+			want:  ErrDuplicateID,
+		},
+		{
+			name:  "negative ID",
+			path:  func(string) string { return "" },
+			batch: []Document{{ID: 1, Text: "donut"}, {ID: -1, Text: "donut"}},
+			want:  ErrInvalidID,
+		},
+		{
+			name:  "failing save",
+			path:  func(dir string) string { return filepath.Join(dir, "missing", "idx") },
+			batch: []Document{{ID: 1, Text: "donut"}, {ID: 2, Text: "donut"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			idx := NewIndex(Config{})
+			idx.Add([]Document{{ID: 0, Text: "a glass plate"}})
+			l := NewPersistentLiveIndex(idx, tt.path(t.TempDir()))
+			before := l.Snapshot()
+
+			err := l.AddBatch(tt.batch)
+			if err == nil || tt.want != nil && !errors.Is(err, tt.want) {
+				t.Fatalf("AddBatch() = %v, want %v", err, tt.want)
+			}
+			if l.Snapshot() != before {
+				t.Error("AddBatch published a new snapshot")
+			}
+			if got := l.DocCount(); got != 1 {
+				t.Errorf("DocCount() = %d, want 1", got)
+			}
+			if got := l.Search("donut"); len(got) != 0 {
+				t.Errorf("Search(donut) = %v, want none", got)
+			}
+		})
+	}
+}
+
+func TestLiveIndexPersists(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "idx")
+	l := NewPersistentLiveIndex(NewIndex(Config{}), path)
+	if err := l.AddBatch([]Document{{ID: 0, Text: "donut"}, {ID: 1, Text: "glass plate"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Update(Document{ID: 1, Text: "donut plate"}); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded := NewIndex(Config{})
+	if err := loaded.Load(path); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := loaded.Search("donut"), []int{0, 1}; !slices.Equal(got, want) {
+		t.Errorf("Search(donut) after Load = %v, want %v", got, want)
+	}
+}
+
+func TestLiveIndexUpdateFailingSave(t *testing.T) {
+	idx := NewIndex(Config{})
+	idx.Add([]Document{{ID: 0, Text: "donut"}})
+	l := NewPersistentLiveIndex(idx, filepath.Join(t.TempDir(), "missing", "idx"))
+	before := l.Snapshot()
+	if err := l.Update(Document{ID: 0, Text: "plate"}); err == nil {
+		t.Fatal("Update() succeeded saving to a missing directory")
+	}
+	if l.Snapshot() != before {
+		t.Error("Update published a new snapshot")
+	}
+	if got := l.Search("donut"); !slices.Equal(got, []int{0}) {
+		t.Errorf("Search(donut) = %v, want [0]", got)
+	}
+}
+
+func TestLiveIndexConcurrentReadsAndWrites(t *testing.T) {
+	const writers, readers, batches = 2, 8, 50
+	l := NewLiveIndex(NewIndex(Config{StoreDocuments: true}))
+	var wg sync.WaitGroup
+	for w := range writers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for b := range batches {
+				id := w*batches + b
+				if err := l.AddBatch([]Document{{ID: id, Title: "glass", Text: "glass plate number " + strconv.Itoa(id)}}); err != nil {
+					t.Error(err)
+					return
+				}
+				if err := l.Update(Document{ID: id, Title: "glass", Text: "glass donut"}); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}()
+	}
+	for range readers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			last := 0
+			for last < writers*batches {
+				snap := l.Snapshot()
+				n := snap.DocCount()
+				if n < last {
+					t.Errorf("DocCount() went from %d back to %d", last, n)
+					return
+				}
+				last = n
+				// Every document has "glass" in one version or the other,
+				// so a snapshot must find them all.
+				if got := len(snap.Search("glass")); got != n {
+					t.Errorf("Search(glass) found %d of %d documents", got, n)
+					return
+				}
+				if got := len(snap.Rank("glass", nil)); got != n {
+					t.Errorf("Rank(glass) found %d of %d documents", got, n)
+					return
+				}
+				snap.Search(`"glass plate"`)
+				snap.Search("gla*")
+			}
+		}()
+	}
+	wg.Wait()
+	if got, want := len(l.Search("donut")), writers*batches; got != want {
+		t.Errorf("Search(donut) found %d documents, want %d", got, want)
+	}
+}
@@ -0,0 +1,172 @@
+package fulltextsearch
+
+import (
+	"sort"
+	"strings"
+)
+
+// span is a token together with its byte offsets in the source text.
+type span struct {
+	Text       string
+	Start, End int
+}
+
+// tokenizeSpans splits text exactly like tokenize but keeps each token's
+// position in the original text.
+func tokenizeSpans(text string) []span {
+	var r []span
+	start := -1
+	for i, c := range text {
+		if isSeparator(c) {
+			if start >= 0 {
+				r = append(r, span{Text: text[start:i], Start: start, End: i})
+				start = -1
+			}
+		} else if start < 0 {
+			start = i
+		}
+	}
+	if start >= 0 {
+		r = append(r, span{Text: text[start:], Start: start, End: len(text)})
+	}
+	return r
+}
+
+// matchingSpans returns the indexes of the spans in text whose analyzed form
+// is one of the analyzed query terms.
+func (a Analyzer) matchingSpans(spans []span, query string) []int {
+	terms := make(map[string]struct{})
+	for _, term := range a.AnalyzeQuery(query) {
+		terms[term] = struct{}{}
+	}
+	var r []int
+	for i, sp := range spans {
+		for _, term := range a.Analyze(sp.Text) {
+			if _, ok := terms[term]; ok {
+				r = append(r, i)
+				break
+			}
+		}
+	}
+	return r
+}
+
+type SnippetOptions struct {
+	// Words is the length of each snippet, in tokens.
+	Words int
+	// Count is the maximum number of snippets returned.
+	Count int
+	// Pre and Post, if set, are wrapped around each query term match,
+	// e.g. "<em>" and "</em>".
+	Pre, Post string
+	// Escape, if set, is applied to the text around the tags, e.g.
+	// html.EscapeString when the tags are HTML.
+	Escape func(string) string
+}
+
+var DefaultSnippetOptions = SnippetOptions{Words: 20, Count: 1}
+
+// Snippets returns up to opts.Count non-overlapping passages of text around
+// the places where query terms occur, best first by number of matches and
+// then reordered as they appear in the text.
+func (a Analyzer) Snippets(text, query string, opts SnippetOptions) []string {
+	if opts.Words <= 0 || opts.Count <= 0 {
+		return nil
+	}
+	spans := tokenizeSpans(text)
+	matches := a.matchingSpans(spans, query)
+
+	type window struct{ start, end, hits int }
+	windows := make([]window, 0, len(matches))
+	for _, m := range matches {
+		// Give each match a little leading context.
+		start := m - opts.Words/4
+		if start < 0 {
+			start = 0
+		}
+		end := start + opts.Words
+		if end > len(spans) {
+			end = len(spans)
+		}
+		w := window{start: start, end: end}
+		for _, n := range matches {
+			if n >= start && n < end {
+				w.hits++
+			}
+		}
+		windows = append(windows, w)
+	}
+	sort.SliceStable(windows, func(i, j int) bool {
+		return windows[i].hits > windows[j].hits
+	})
+
+	var picked []window
+	for _, w := range windows {
+		if len(picked) == opts.Count {
+			break
+		}
+		overlaps := false
+		for _, p := range picked {
+			if w.start < p.end && p.start < w.end {
+				overlaps = true
+				break
+			}
+		}
+		if !overlaps {
+			picked = append(picked, w)
+		}
+	}
+	sort.Slice(picked, func(i, j int) bool {
+		return picked[i].start < picked[j].start
+	})
+
+	r := make([]string, len(picked))
+	for i, w := range picked {
+		r[i] = passage(text, spans, w.start, w.end, matches, opts)
+	}
+	return r
+}
+
+// passage returns the text covered by spans[start:end], marking with an
+// ellipsis whichever ends were cut from the surrounding text and wrapping
+// the matching spans in opts' tags.
+func passage(text string, spans []span, start, end int, matches []int, opts SnippetOptions) string {
+	escape := opts.Escape
+	if escape == nil {
+		escape = func(s string) string { return s }
+	}
+	var b strings.Builder
+	if start > 0 {
+		b.WriteString("...")
+	}
+	last := spans[start].Start
+	if opts.Pre != "" || opts.Post != "" {
+		for _, m := range matches {
+			if m < start || m >= end {
+				continue
+			}
+			sp := spans[m]
+			b.WriteString(escape(text[last:sp.Start]))
+			b.WriteString(opts.Pre)
+			b.WriteString(escape(sp.Text))
+			b.WriteString(opts.Post)
+			last = sp.End
+		}
+	}
+	b.WriteString(escape(text[last:spans[end-1].End]))
+	if end < len(spans) {
+		b.WriteString("...")
+	}
+	return b.String()
+}
+
+// AddSnippets fills in Snippets for each result from its stored text.
+func (idx *Index) AddSnippets(results []Result, query string, opts SnippetOptions) error {
+	if idx.store == nil {
+		return ErrNoStore
+	}
+	for i := range results {
+		results[i].Snippets = idx.analyzer.Snippets(idx.store.get(results[i].ID).Text, query, opts)
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package fulltextsearch
+
+import (
+	"html"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestSnippets(t *testing.T) {
+	filler := strings.Repeat("filler ", 30)
+	text := "the cat sat down " + filler + "and the cat left"
+	tests := []struct {
+		name string
+		opts SnippetOptions
+		want []string
+	}{
+		{"one", SnippetOptions{Words: 4, Count: 1}, []string{"the cat sat down..."}},
+		{"two separated matches", SnippetOptions{Words: 4, Count: 2}, []string{
+			"the cat sat down...",
+			"...the cat left",
+		}},
+		{"more than there are", SnippetOptions{Words: 4, Count: 5}, []string{
+			"the cat sat down...",
+			"...the cat left",
+		}},
+		{"marked", SnippetOptions{Words: 3, Count: 1, Pre: "[", Post: "]"}, []string{"...[cat] sat down..."}},
+		{"no snippets", SnippetOptions{Words: 4}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Analyzer{}.Snippets(text, "cats", tt.opts)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("Snippets() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSnippetsEscape(t *testing.T) {
+	// Passages run from the first token to the last, so the markup here is
+	// between words.
+	text := `a <script>alert("cat")</script> & the cat's <b>bowl</b> b`
+	tests := []struct {
+		name, query string
+		want        string
+	}{
+		{"markup around matches", "cat",
+			`a &lt;script&gt;alert(&#34;<em>cat</em>&#34;)&lt;/script&gt; &amp; the <em>cat</em>&#39;s &lt;b&gt;bowl&lt;/b&gt; b`},
+		{"matching a tag name", "script",
+			`a &lt;<em>script</em>&gt;alert(&#34;cat&#34;)&lt;/<em>script</em>&gt; &amp; the cat&#39;s &lt;b&gt;bowl&lt;/b&gt; b`},
+		{"no match", "dog", ""},
+	}
+	opts := SnippetOptions{Words: 20, Count: 1, Pre: "<em>", Post: "</em>", Escape: html.EscapeString}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+			if s := (Analyzer{}).Snippets(text, tt.query, opts); len(s) > 0 {
+				got = s[0]
+			}
+			if got != tt.want {
+				t.Errorf("Snippets(%q) = %q, want %q", tt.query, got, tt.want)
+			}
+		})
+	}
+}
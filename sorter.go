@@ -0,0 +1,39 @@
+package fulltextsearch
+
+import "sort"
+
+// Sorter orders search results. Less reports whether a should come before b.
+type Sorter interface {
+	Less(a, b Result) bool
+}
+
+// ByID orders results by ascending document ID, the order postings use.
+type ByID struct{}
+
+func (ByID) Less(a, b Result) bool { return a.ID < b.ID }
+
+// ByScore orders results best first, breaking ties by ID.
+type ByScore struct{}
+
+func (ByScore) Less(a, b Result) bool {
+	if a.Score != b.Score {
+		return a.Score > b.Score
+	}
+	return a.ID < b.ID
+}
+
+// ByDate orders results newest first, breaking ties by score.
+type ByDate struct{}
+
+func (ByDate) Less(a, b Result) bool {
+	if !a.Date.Equal(b.Date) {
+		return a.Date.After(b.Date)
+	}
+	return ByScore{}.Less(a, b)
+}
+
+func sortResults(r []Result, s Sorter) {
+	sort.SliceStable(r, func(i, j int) bool {
+		return s.Less(r[i], r[j])
+	})
+}
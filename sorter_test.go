@@ -0,0 +1,38 @@
+package fulltextsearch
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestSorters(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2024, 1, d, 0, 0, 0, 0, time.UTC) }
+	idx := NewIndex(Config{})
+	idx.Add([]Document{
+		{ID: 0, Text: "cat", Date: day(2)},
+		{ID: 1, Text: "cat cat cat", Date: day(1)},
+		{ID: 2, Text: "a cat among many other animals in the garden", Date: day(3)},
+	})
+	tests := []struct {
+		name   string
+		sorter Sorter
+		want   []int
+	}{
+		{"nil", nil, []int{1, 0, 2}},
+		{"ByScore", ByScore{}, []int{1, 0, 2}},
+		{"ByID", ByID{}, []int{0, 1, 2}},
+		{"ByDate", ByDate{}, []int{2, 0, 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []int
+			for _, r := range idx.Rank("cat", tt.sorter) {
+				got = append(got, r.ID)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("Rank(cat, %T) = %v, want %v", tt.sorter, got, tt.want)
+			}
+		})
+	}
+}
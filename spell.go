@@ -0,0 +1,64 @@
+package fulltextsearch
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// SpellSuggestions returns up to n text field terms near word, nearest and
+// then most common first, if word's own term isn't in the index. Words are
+// corrected within the index's fuzziness, or defaultFuzziness edits if
+// fuzzy matching is off.
+func (idx *Index) SpellSuggestions(word string, n int) []Correction {
+	f, ok := idx.Fields["text"]
+	terms := idx.analyzer.AnalyzeQuery(word)
+	if !ok || len(terms) != 1 || len(f.Postings.get(terms[0])) > 0 {
+		return nil
+	}
+	maxDist := idx.fuzziness
+	if maxDist <= 0 {
+		maxDist = defaultFuzziness
+	}
+	r := idx.Corrections(word, maxDist)
+	return r[:min(n, len(r))]
+}
+
+// DidYouMean returns text with each plain word that isn't in the index
+// replaced by its best spelling suggestion, or "" if no word needed or
+// could be given one. Filters, wildcards and other query syntax are left
+// as they are.
+func (idx *Index) DidYouMean(text string) string {
+	words := strings.Fields(text)
+	changed := false
+	for i, w := range words {
+		if t := tokenize(w); len(t) != 1 || t[0] != w {
+			continue
+		}
+		if c := idx.SpellSuggestions(w, 1); len(c) > 0 {
+			words[i], changed = idx.spelling(c[0].Term), true
+		}
+	}
+	if !changed {
+		return ""
+	}
+	return strings.Join(words, " ")
+}
+
+// spelling returns a readable word for the analyzed term: the commonest
+// title word analyzing to it, since stems such as "anarch" make poor
+// suggestions, or else the term itself. Stems are nearly always prefixes of
+// the words they come from, bar a final letter changed as in "anarchi", so
+// only title words starting with the rest of term are tried.
+func (idx *Index) spelling(term string) string {
+	best, bestTitles := term, 0
+	_, size := utf8.DecodeLastRuneInString(term)
+	dict := idx.titleDict.sorted(idx.TitleWords.keys())
+	for _, w := range withPrefix(dict, term[:len(term)-size]) {
+		if n := idx.TitleWords.get(w); n > bestTitles {
+			if t := idx.analyzer.AnalyzeQuery(w); len(t) == 1 && t[0] == term {
+				best, bestTitles = w, n
+			}
+		}
+	}
+	return best
+}
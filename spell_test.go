@@ -0,0 +1,39 @@
+package fulltextsearch
+
+import "testing"
+
+func TestDidYouMean(t *testing.T) {
+	idx := NewIndex(Config{})
+	idx.Add([]Document{
+		{ID: 0, Title: "Anarchism", Text: "anarchism is a political philosophy"},
+		{ID: 1, Title: "Philosophy", Text: "philosophy is the study of general questions"},
+		{ID: 2, Title: "Politics", Text: "politics and political science"},
+	})
+	tests := []struct {
+		query, want string
+	}{
+		{"anarcism", "anarchism"},
+		{"anarcism philosphy", "anarchism philosophy"},
+		{"anarchism philosphy", "anarchism philosophy"},
+		{"title:anarcism philosphy", "title:anarcism philosophy"},
+		{"anarchism", ""},
+		{"xylophone", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			if got := idx.DidYouMean(tt.query); got != tt.want {
+				t.Errorf("DidYouMean(%q) = %q, want %q", tt.query, got, tt.want)
+			}
+		})
+	}
+
+	// Words in the index get no suggestions, misspelled ones the nearest
+	// terms.
+	if got := idx.SpellSuggestions("politics", 3); len(got) != 0 {
+		t.Errorf("SpellSuggestions(politics) = %v, want none", got)
+	}
+	if got := idx.SpellSuggestions("politcs", 3); len(got) == 0 || got[0].Term != "polit" {
+		t.Errorf("SpellSuggestions(politcs) = %v, want polit first", got)
+	}
+}
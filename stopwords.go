@@ -0,0 +1,73 @@
+package fulltextsearch
+
+import (
+	"bufio"
+	"os"
+	"strings"
+)
+
+// StopwordLists are standard stopword lists by ISO 639-1 language code, for
+// Analyzer.Stopwords. They hold only the commonest function words.
+var StopwordLists = map[string]map[string]struct{}{
+	"en": StopwordSet(strings.Fields(`a an and are as at be but by for if in
+		into is it no not of on or such that the their then there these they
+		this to was will with`)...),
+	"fr": StopwordSet(strings.Fields(`au aux avec ce ces dans de des du elle
+		en et eux il je la le les leur lui ma mais me même mes moi mon ne nos
+		notre nous on ou par pas pour qu que qui sa se ses son sur ta te tes
+		toi ton tu un une vos votre vous c d j l à m n s t y est sont été`)...),
+	"es": StopwordSet(strings.Fields(`a al algo como con de del el ella ellos
+		en entre era es esta este esto fue ha hay la las le les lo los más me
+		mi muy no nos o para pero por que quien se sin sobre su sus también
+		te todo un una uno unos y ya yo`)...),
+	"de": StopwordSet(strings.Fields(`aber als am an auch auf aus bei bin bis
+		da damit dann das dass dem den der des die dies doch du durch ein eine
+		einem einen einer eines er es für hat ich ihr im in ist ja kein man
+		mit nach nicht noch nur oder sein sich sie sind so über um und uns
+		von vor war was wenn wer wie wir wird zu zum zur`)...),
+	"it": StopwordSet(strings.Fields(`a ad al alla alle anche che chi ci con
+		da dal dalla degli dei del della delle di e è ed gli ha i il in io la
+		le lo ma mi ne nei nel nella non o per più se si sono su sua suo tra
+		un una uno`)...),
+	"pt": StopwordSet(strings.Fields(`a ao aos as até com como da das de do
+		dos e é ela ele em entre era foi há isso mais mas me na nas no nos o
+		os ou para pela pelo por que se sem ser seu sua também um uma`)...),
+	"nl": StopwordSet(strings.Fields(`aan al als bij dan dat de der die dit
+		door een en er had heb het hij hoe ik in is je kan maar met na naar
+		niet nog of om ook op over te tot uit van voor was wat we wel wij zal
+		ze zich zij zijn zo`)...),
+}
+
+// StopwordSet makes a stopword set from words, lowercasing them.
+func StopwordSet(words ...string) map[string]struct{} {
+	r := make(map[string]struct{}, len(words))
+	for _, w := range words {
+		r[strings.ToLower(w)] = struct{}{}
+	}
+	return r
+}
+
+// LoadStopwords reads a stopword list from a text file with any number of
+// words per line. Anything after a # or | is a comment, so the lists
+// distributed with Snowball and Lucene can be used as they are.
+func LoadStopwords(path string) (map[string]struct{}, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var words []string
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		line := sc.Text()
+		if i := strings.IndexAny(line, "#|"); i >= 0 {
+			line = line[:i]
+		}
+		words = append(words, strings.Fields(line)...)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	return StopwordSet(words...), nil
+}
@@ -0,0 +1,63 @@
+package fulltextsearch
+
+import (
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestLoadStopwords(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "stop.txt")
+	list := "| a Snowball-style list\nThe  of # articles and prepositions\n\nand | conjunction\n"
+	if err := os.WriteFile(path, []byte(list), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	words, err := LoadStopwords(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := slices.Sorted(maps.Keys(words)), []string{"and", "of", "the"}; !slices.Equal(got, want) {
+		t.Errorf("LoadStopwords() = %q, want %q", got, want)
+	}
+
+	if _, err := LoadStopwords(filepath.Join(t.TempDir(), "missing.txt")); err == nil {
+		t.Error("LoadStopwords(missing file) succeeded, want an error")
+	}
+}
+
+func TestStopwords(t *testing.T) {
+	tests := []struct {
+		name string
+		a    Analyzer
+		text string
+		want []string
+	}{
+		{"built-in", Analyzer{}, "the cat and the hat", []string{"cat", "hat"}},
+		{"own list", Analyzer{Stopwords: StopwordSet("Cat")}, "the cat and the hat", []string{"the", "and", "the", "hat"}},
+		{"none", Analyzer{Stopwords: map[string]struct{}{}}, "the cat and the hat", []string{"the", "cat", "and", "the", "hat"}},
+		{"english list", Analyzer{Stopwords: StopwordLists["en"]}, "this is the hat", []string{"hat"}},
+		{"by language", Analyzer{Language: "fr", StemStrength: StemNone}, "le chat et la souris", []string{"chat", "souris"}},
+		{"own list over language", Analyzer{Language: "fr", StemStrength: StemNone, Stopwords: StopwordSet("chat")},
+			"le chat et la souris", []string{"le", "et", "la", "souris"}},
+		{"language without a list", Analyzer{Language: "sv", StemStrength: StemNone}, "the cat", []string{"the", "cat"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.a.Analyze(tt.text); !slices.Equal(got, tt.want) {
+				t.Errorf("Analyze(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+
+	// Every word of every list is dropped by an analyzer for its language.
+	for lang, words := range StopwordLists {
+		a := Analyzer{Language: lang}
+		for w := range words {
+			if got := a.Analyze(w); len(got) != 0 {
+				t.Errorf("Analyzer{Language: %q}.Analyze(%q) = %q, want none", lang, w, got)
+			}
+		}
+	}
+}
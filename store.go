@@ -0,0 +1,251 @@
+package fulltextsearch
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"maps"
+	"os"
+	"slices"
+)
+
+// docStore keeps source documents by ID, so structures derived from their
+// text can be rebuilt without reloading the whole dump.
+type docStore map[int]Document
+
+// cow returns the documents as the index keeps them.
+func (s docStore) cow() *cowMap[int, Document] {
+	m := newCowMap(s)
+	return &m
+}
+
+const (
+	// storeSuffix is appended to an index's path to name its doc store
+	// file.
+	storeSuffix = ".docs"
+	// storeMagic starts every doc store file.
+	storeMagic = "FTSDOCS1"
+	// storeHeaderSize is the size of each batch's length and checksum.
+	storeHeaderSize = 8
+)
+
+// storeFile tracks what of the doc store is already on disk. The file is
+// storeMagic followed by a series of gob-encoded batches of documents, each
+// preceded by its length and CRC-32, so documents added since the last
+// save can be appended rather than rewriting every stored document, and a
+// batch cut short by a failed append is detected rather than misread.
+type storeFile struct {
+	// path is the index path the store was last loaded from or saved to.
+	path string
+	// size is how many bytes of the file there hold intact batches. Any
+	// more were left by an append that failed, and are overwritten by the
+	// next one.
+	size int64
+	// pending lists the documents added since then.
+	pending []int
+	// stale is set once a document has been removed since then, which
+	// only a rewrite can reflect.
+	stale bool
+}
+
+// saveStore writes the doc store, if there is one, beside the index at path,
+// appending just the new documents if the file there is otherwise up to
+// date.
+func (idx *Index) saveStore(path string) error {
+	if idx.store == nil {
+		return nil
+	}
+	var size int64
+	var err error
+	if idx.storeFile.path == path && !idx.storeFile.stale {
+		size, err = idx.appendStore(path + storeSuffix)
+	} else {
+		err = writeFileAtomic(path+storeSuffix, func(w io.Writer) error {
+			n, err := writeStoreBatch(w, idx.store.toMap(), true)
+			size = n
+			return err
+		})
+	}
+	if err != nil {
+		return err
+	}
+	idx.storeFile = storeFile{path: path, size: size}
+	return nil
+}
+
+// appendStore appends a batch of the pending documents to the store file,
+// over anything a failed append left after the intact batches, and returns
+// the file's new size.
+func (idx *Index) appendStore(name string) (int64, error) {
+	size := idx.storeFile.size
+	if len(idx.storeFile.pending) == 0 {
+		return size, nil
+	}
+	batch := make(docStore, len(idx.storeFile.pending))
+	for _, id := range idx.storeFile.pending {
+		batch[id] = idx.store.get(id)
+	}
+	f, err := os.OpenFile(name, os.O_WRONLY|os.O_CREATE, 0644)
+	if err != nil {
+		return 0, err
+	}
+	if err := f.Truncate(size); err != nil {
+		f.Close()
+		return 0, err
+	}
+	if _, err := f.Seek(size, io.SeekStart); err != nil {
+		f.Close()
+		return 0, err
+	}
+	n, err := writeStoreBatch(f, batch, size == 0)
+	if err != nil {
+		f.Close()
+		return 0, err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return 0, err
+	}
+	return size + n, f.Close()
+}
+
+// writeStoreBatch writes batch to w, after storeMagic if it starts the
+// file, and returns how many bytes it wrote.
+func writeStoreBatch(w io.Writer, batch docStore, first bool) (int64, error) {
+	var buf bytes.Buffer
+	if first {
+		buf.WriteString(storeMagic)
+	}
+	start := buf.Len()
+	buf.Write(make([]byte, storeHeaderSize))
+	if err := gob.NewEncoder(&buf).Encode(batch); err != nil {
+		return 0, err
+	}
+	b := buf.Bytes()
+	data := b[start+storeHeaderSize:]
+	binary.LittleEndian.PutUint32(b[start:], uint32(len(data)))
+	binary.LittleEndian.PutUint32(b[start+4:], crc32.Checksum(data, crcTable))
+	n, err := w.Write(b)
+	return int64(n), err
+}
+
+// loadStore reads the doc store beside the index at path, if the index
+// keeps one. A missing file leaves the store empty, as for an index saved
+// before the store was enabled.
+func (idx *Index) loadStore(path string) error {
+	if idx.store == nil {
+		return nil
+	}
+	f, err := os.Open(path + storeSuffix)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	r := bufio.NewReader(f)
+	store := make(docStore)
+	size := int64(0)
+	if info.Size() > 0 {
+		magic := make([]byte, len(storeMagic))
+		if _, err := io.ReadFull(r, magic); err != nil || string(magic) != storeMagic {
+			return fmt.Errorf("loading doc store: %w; rebuild the index", ErrFormatVersion)
+		}
+		size = int64(len(storeMagic))
+	}
+	for {
+		batch, n, err := readStoreBatch(r, info.Size()-size)
+		if err == io.EOF || n == 0 && err == nil {
+			// The end, or the remains of a failed append after it.
+			break
+		} else if err != nil {
+			return fmt.Errorf("loading doc store: %w", err)
+		}
+		maps.Copy(store, batch)
+		size += n
+	}
+	idx.store = store.cow()
+	idx.storeFile = storeFile{path: path, size: size}
+	idx.positions.reset()
+	return nil
+}
+
+// readStoreBatch reads the next batch of the doc store and its size on
+// disk, with left bytes left in the file. A size of zero means the rest of
+// the file is a batch cut short by a failed append; a batch that is whole
+// but garbled is an error.
+func readStoreBatch(r *bufio.Reader, left int64) (docStore, int64, error) {
+	var header [storeHeaderSize]byte
+	if _, err := io.ReadFull(r, header[:]); err == io.EOF {
+		return nil, 0, io.EOF
+	} else if err == io.ErrUnexpectedEOF {
+		return nil, 0, nil
+	} else if err != nil {
+		return nil, 0, err
+	}
+	size := int64(binary.LittleEndian.Uint32(header[:]))
+	if size > left-storeHeaderSize {
+		return nil, 0, nil
+	}
+	data := make([]byte, size)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return nil, 0, err
+	}
+	if crc32.Checksum(data, crcTable) != binary.LittleEndian.Uint32(header[4:]) {
+		if size == left-storeHeaderSize {
+			// The last batch, whose length was written but not all of
+			// its data.
+			return nil, 0, nil
+		}
+		return nil, 0, fmt.Errorf("batch checksum mismatch: %w", ErrCorruptIndex)
+	}
+	var batch docStore
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&batch); err != nil {
+		return nil, 0, fmt.Errorf("%w: %w", ErrCorruptIndex, err)
+	}
+	return batch, storeHeaderSize + size, nil
+}
+
+// storedFields are the fields Resolve can copy into results.
+var storedFields = []string{"title", "url", "text"}
+
+// Resolve projects results onto the named stored fields: those are filled
+// in from the doc store and the others cleared, even the title and URL that
+// results come with, so list views can leave out what they don't show. No
+// fields means all of them.
+func (idx *Index) Resolve(results []Result, fields ...string) error {
+	if idx.store == nil {
+		return ErrNoStore
+	}
+	if len(fields) == 0 {
+		fields = storedFields
+	}
+	for _, name := range fields {
+		if !slices.Contains(storedFields, name) {
+			return fmt.Errorf("unknown stored field %q", name)
+		}
+	}
+	for i := range results {
+		doc := idx.store.get(results[i].ID)
+		results[i].Title, results[i].URL, results[i].Text = "", "", ""
+		for _, name := range fields {
+			switch name {
+			case "title":
+				results[i].Title = doc.Title
+			case "url":
+				results[i].URL = doc.URL
+			case "text":
+				results[i].Text = doc.Text
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,172 @@
+package fulltextsearch
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestSaveFailureKeepsOldIndex(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "idx")
+	idx := NewIndex(Config{})
+	idx.Add([]Document{{ID: 0, Text: "donut"}})
+	if err := idx.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	old, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	failure := errors.New("disk full")
+	err = writeFileAtomic(path, func(w io.Writer) error {
+		w.Write([]byte("half an index"))
+		return failure
+	})
+	if !errors.Is(err, failure) {
+		t.Fatalf("writeFileAtomic() = %v, want %v", err, failure)
+	}
+	if got, _ := os.ReadFile(path); !slices.Equal(got, old) {
+		t.Error("failed save changed the index file")
+	}
+	if names, _ := filepath.Glob(path + ".tmp*"); len(names) > 0 {
+		t.Errorf("failed save left %v behind", names)
+	}
+	loaded := NewIndex(Config{})
+	if err := loaded.Load(path); err != nil {
+		t.Fatal(err)
+	}
+	if got := loaded.Search("donut"); !slices.Equal(got, []int{0}) {
+		t.Errorf("Search(donut) = %v, want [0]", got)
+	}
+}
+
+func TestStoreAppends(t *testing.T) {
+	tests := []struct {
+		name string
+		// damage changes the store file after the second save.
+		damage  func(t *testing.T, name string)
+		want    []int
+		wantErr error
+	}{
+		{"intact", func(*testing.T, string) {}, []int{0, 1, 2}, nil},
+		{"cut short", func(t *testing.T, name string) { truncateBy(t, name, 5) }, []int{0}, nil},
+		{"header cut short", func(t *testing.T, name string) { truncateBy(t, name, -3) }, []int{0}, nil},
+		{"garbled", func(t *testing.T, name string) { flipByte(t, name, len(storeMagic)+storeHeaderSize+1) }, nil, ErrCorruptIndex},
+		{"old format", func(t *testing.T, name string) { flipByte(t, name, 0) }, nil, ErrFormatVersion},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "idx")
+			idx := NewIndex(Config{StoreDocuments: true})
+			idx.Add([]Document{{ID: 0, Title: "Donut"}})
+			if err := idx.Save(path); err != nil {
+				t.Fatal(err)
+			}
+			idx.Add([]Document{{ID: 1, Title: "Plate"}, {ID: 2, Title: "Glass"}})
+			if err := idx.Save(path); err != nil {
+				t.Fatal(err)
+			}
+			tt.damage(t, path+storeSuffix)
+
+			loaded := NewIndex(Config{StoreDocuments: true})
+			err := loaded.loadStore(path)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("loadStore() = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if got := slices.Sorted(loaded.store.keys()); !slices.Equal(got, tt.want) {
+				t.Errorf("stored documents %v, want %v", got, tt.want)
+			}
+
+			// The next append replaces whatever was cut short.
+			loaded.Add([]Document{{ID: 3, Title: "Cat"}})
+			if err := loaded.saveStore(path); err != nil {
+				t.Fatal(err)
+			}
+			reloaded := NewIndex(Config{StoreDocuments: true})
+			if err := reloaded.loadStore(path); err != nil {
+				t.Fatal(err)
+			}
+			want := append(slices.Clone(tt.want), 3)
+			if got := slices.Sorted(reloaded.store.keys()); !slices.Equal(got, want) {
+				t.Errorf("stored documents after another append %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+// truncateBy cuts n bytes off the end of the named file, or if n is
+// negative, leaves only -n bytes of its last batch.
+func truncateBy(t *testing.T, name string, n int64) {
+	t.Helper()
+	info, err := os.Stat(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	size := info.Size() - n
+	if n < 0 {
+		// The second batch starts after the magic and the first batch.
+		first := NewIndex(Config{StoreDocuments: true})
+		first.Add([]Document{{ID: 0, Title: "Donut"}})
+		b, err := writeStoreBatch(io.Discard, first.store.toMap(), true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		size = b - n
+	}
+	if err := os.Truncate(name, size); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func flipByte(t *testing.T, name string, i int) {
+	t.Helper()
+	b, err := os.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b[i] ^= 0xff
+	if err := os.WriteFile(name, b, 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestResolveProjection(t *testing.T) {
+	doc := Document{ID: 0, Title: "Donut", URL: "https://example.com/donut", Text: "a ring of dough"}
+	tests := []struct {
+		name   string
+		fields []string
+		want   Result
+	}{
+		{"all", nil, Result{Title: doc.Title, URL: doc.URL, Text: doc.Text}},
+		{"title only", []string{"title"}, Result{Title: doc.Title}},
+		{"url and text", []string{"url", "text"}, Result{URL: doc.URL, Text: doc.Text}},
+	}
+	idx := NewIndex(Config{StoreDocuments: true})
+	idx.Add([]Document{doc})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := idx.Rank("donut", nil)
+			if err := idx.Resolve(r, tt.fields...); err != nil {
+				t.Fatal(err)
+			}
+			if len(r) != 1 {
+				t.Fatalf("Rank(donut) = %d results, want 1", len(r))
+			}
+			got := Result{Title: r[0].Title, URL: r[0].URL, Text: r[0].Text}
+			if got.Title != tt.want.Title || got.URL != tt.want.URL || got.Text != tt.want.Text {
+				t.Errorf("Resolve(%q) = title %q, url %q, text %q, want %q, %q, %q",
+					tt.fields, got.Title, got.URL, got.Text, tt.want.Title, tt.want.URL, tt.want.Text)
+			}
+		})
+	}
+	if err := idx.Resolve(nil, "body"); err == nil {
+		t.Error("Resolve(body) error = nil, want an unknown field error")
+	}
+}
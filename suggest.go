@@ -0,0 +1,62 @@
+package fulltextsearch
+
+import (
+	"slices"
+	"sort"
+	"strings"
+)
+
+// titleWords returns the distinct lowercased words of title, unanalyzed so
+// completions read as the user would type them.
+func titleWords(title string) []string {
+	words := lowercaseFilter(tokenize(title))
+	slices.Sort(words)
+	return slices.Compact(words)
+}
+
+func (idx *Index) addTitleWords(title string) {
+	for _, w := range titleWords(title) {
+		idx.TitleWords.set(w, idx.TitleWords.get(w)+1)
+	}
+}
+
+func (idx *Index) removeTitleWords(title string) {
+	for _, w := range titleWords(title) {
+		if n := idx.TitleWords.get(w) - 1; n > 0 {
+			idx.TitleWords.set(w, n)
+		} else {
+			idx.TitleWords.delete(w)
+		}
+	}
+}
+
+// Suggestion is one completion of a partly typed query.
+type Suggestion struct {
+	Text string `json:"text"`
+	// Titles is how many titles contain the completed word.
+	Titles int `json:"titles"`
+}
+
+// Suggest completes the last word of prefix from the words of document
+// titles, returning up to n completions, those found in the most titles
+// first. Earlier words of prefix are kept as typed. Nothing is suggested
+// once prefix ends in a separator, since there's no word to complete.
+func (idx *Index) Suggest(prefix string, n int) []Suggestion {
+	words := tokenize(prefix)
+	if len(words) == 0 || n <= 0 || !strings.HasSuffix(prefix, words[len(words)-1]) {
+		return nil
+	}
+	last := strings.ToLower(words[len(words)-1])
+	head := prefix[:len(prefix)-len(words[len(words)-1])]
+
+	dict := idx.titleDict.sorted(idx.TitleWords.keys())
+	matches := slices.Clone(withPrefix(dict, last))
+	sort.SliceStable(matches, func(i, j int) bool {
+		return idx.TitleWords.get(matches[i]) > idx.TitleWords.get(matches[j])
+	})
+	r := make([]Suggestion, 0, min(n, len(matches)))
+	for _, w := range matches[:min(n, len(matches))] {
+		r = append(r, Suggestion{Text: head + w, Titles: idx.TitleWords.get(w)})
+	}
+	return r
+}
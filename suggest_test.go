@@ -0,0 +1,44 @@
+package fulltextsearch
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestSuggest(t *testing.T) {
+	idx := NewIndex(Config{})
+	idx.Add([]Document{
+		{ID: 0, Title: "New York", Text: "a city"},
+		{ID: 1, Title: "New Jersey", Text: "a state"},
+		{ID: 2, Title: "Newark, New Jersey", Text: "a city"},
+		{ID: 3, Title: "York Minster", Text: "a cathedral"},
+	})
+	tests := []struct {
+		prefix string
+		n      int
+		want   []Suggestion
+	}{
+		{"ne", 5, []Suggestion{{"new", 3}, {"newark", 1}}},
+		{"ne", 1, []Suggestion{{"new", 3}}},
+		{"NEWA", 5, []Suggestion{{"newark", 1}}},
+		{"New Y", 5, []Suggestion{{"New york", 2}}},
+		{"j", 5, []Suggestion{{"jersey", 2}}},
+		{"new ", 5, nil},
+		{"", 5, nil},
+		{"ne", 0, nil},
+		{"zz", 5, []Suggestion{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.prefix, func(t *testing.T) {
+			if got := idx.Suggest(tt.prefix, tt.n); !slices.Equal(got, tt.want) {
+				t.Errorf("Suggest(%q, %d) = %v, want %v", tt.prefix, tt.n, got, tt.want)
+			}
+		})
+	}
+
+	// Removed titles no longer count.
+	idx.Remove(2)
+	if got, want := idx.Suggest("ne", 5), []Suggestion{{"new", 2}}; !slices.Equal(got, want) {
+		t.Errorf("after Remove(2), Suggest(ne, 5) = %v, want %v", got, want)
+	}
+}
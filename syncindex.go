@@ -0,0 +1,97 @@
+package fulltextsearch
+
+import (
+	"io"
+	"sync"
+)
+
+// SyncIndex guards an index with a read-write lock, so a long-running
+// process can search it while adding documents. Searches share the lock and
+// run concurrently; updates hold it exclusively and wait for searches in
+// flight. Unlike LiveIndex, updates modify the index in place rather than
+// a new version of it, which saves a little copying, at the price of
+// searches stalling while an update runs.
+type SyncIndex struct {
+	mu  sync.RWMutex
+	idx *Index
+}
+
+func NewSyncIndex(idx *Index) *SyncIndex {
+	return &SyncIndex{idx: idx}
+}
+
+// Read calls fn with the index under the read lock, for searches without a
+// method of their own here. fn must not modify the index or keep it.
+func (s *SyncIndex) Read(fn func(idx *Index)) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	fn(s.idx)
+}
+
+// Write calls fn with the index under the write lock. fn must not keep it.
+func (s *SyncIndex) Write(fn func(idx *Index)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fn(s.idx)
+}
+
+func (s *SyncIndex) Search(text string) []int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.idx.Search(text)
+}
+
+func (s *SyncIndex) Rank(query string, sorter Sorter) []Result {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.idx.Rank(query, sorter)
+}
+
+func (s *SyncIndex) RankTop(query string, k int) []Result {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.idx.RankTop(query, k)
+}
+
+func (s *SyncIndex) DocCount() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.idx.DocCount()
+}
+
+func (s *SyncIndex) Add(docs []Document) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.idx.Add(docs)
+}
+
+func (s *SyncIndex) Update(doc Document) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.idx.Update(doc)
+}
+
+func (s *SyncIndex) Delete(id int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.idx.Delete(id)
+}
+
+// Save holds the write lock, since saving records what of the doc store is
+// on disk.
+func (s *SyncIndex) Save(path string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.idx.Save(path)
+}
+
+// Backup writes a snapshot of the index to w, as Index.Snapshot does. It
+// holds the read lock only while cloning the index, which shares its maps
+// rather than copying them, and writes the clone out after releasing it,
+// so updates barely wait for it.
+func (s *SyncIndex) Backup(w io.Writer) error {
+	s.mu.RLock()
+	c := s.idx.clone()
+	s.mu.RUnlock()
+	return c.Snapshot(w)
+}
@@ -0,0 +1,166 @@
+package fulltextsearch
+
+import (
+	"bytes"
+	"io"
+	"math/rand/v2"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+func TestSyncIndexConcurrentReadsAndWrites(t *testing.T) {
+	const writers, readers, batches = 2, 8, 50
+	s := NewSyncIndex(NewIndex(Config{StoreDocuments: true}))
+	var wg sync.WaitGroup
+	for w := range writers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for b := range batches {
+				id := w*batches + b
+				s.Add([]Document{{ID: id, Title: "glass", Text: "glass plate number " + strconv.Itoa(id)}})
+				if err := s.Update(Document{ID: id, Title: "glass", Text: "glass donut"}); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}()
+	}
+	for range readers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			last := 0
+			for last < writers*batches {
+				s.Read(func(idx *Index) {
+					n := idx.DocCount()
+					if n < last {
+						t.Errorf("DocCount() went from %d back to %d", last, n)
+					}
+					last = n
+					// Every document has "glass" in one version or the
+					// other, so a search must find them all.
+					if got := len(idx.Search("glass")); got != n {
+						t.Errorf("Search(glass) found %d of %d documents", got, n)
+					}
+				})
+				if t.Failed() {
+					return
+				}
+				s.Rank("glass", nil)
+				s.RankTop("glass", 3)
+				s.Search(`"glass plate"`)
+				s.Search("gla*")
+			}
+		}()
+	}
+	wg.Wait()
+	if got, want := len(s.Search("donut")), writers*batches; got != want {
+		t.Errorf("Search(donut) found %d documents, want %d", got, want)
+	}
+	if got, want := s.DocCount(), writers*batches; got != want {
+		t.Errorf("DocCount() = %d, want %d", got, want)
+	}
+}
+
+func TestBackup(t *testing.T) {
+	r := rand.New(rand.NewPCG(3, 4))
+	docs := randomDocs(r, 0, 100)
+	cfg := Config{StoreDocuments: true}
+
+	tests := []struct {
+		name   string
+		backup func(w io.Writer) error
+	}{
+		{"Index.Snapshot", func(w io.Writer) error {
+			idx := NewIndex(cfg)
+			idx.Add(slices.Clone(docs))
+			return idx.Snapshot(w)
+		}},
+		{"SyncIndex.Backup", func(w io.Writer) error {
+			s := NewSyncIndex(NewIndex(cfg))
+			s.Add(slices.Clone(docs))
+			return s.Backup(w)
+		}},
+		{"LiveIndex.Backup", func(w io.Writer) error {
+			l := NewLiveIndex(NewIndex(cfg))
+			if err := l.AddBatch(slices.Clone(docs)); err != nil {
+				return err
+			}
+			return l.Backup(w)
+		}},
+	}
+	want := NewIndex(cfg)
+	want.Add(slices.Clone(docs))
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var b bytes.Buffer
+			if err := tt.backup(&b); err != nil {
+				t.Fatal(err)
+			}
+			got := NewIndex(cfg)
+			if err := got.RestoreSnapshot(&b); err != nil {
+				t.Fatalf("RestoreSnapshot() error = %v", err)
+			}
+			sameIndex(t, got, want)
+
+			// The doc store comes back too, and saves in full.
+			results := got.Rank("cat", nil)
+			if err := got.Resolve(results); err != nil {
+				t.Fatal(err)
+			}
+			for _, res := range results {
+				if doc := docs[slices.IndexFunc(docs, func(d Document) bool { return d.ID == res.ID })]; res.Text != doc.Text {
+					t.Errorf("document %d text = %q, want %q", res.ID, res.Text, doc.Text)
+				}
+			}
+			path := filepath.Join(t.TempDir(), "index")
+			if err := got.Save(path); err != nil {
+				t.Fatal(err)
+			}
+			loaded := NewIndex(cfg)
+			if err := loaded.Load(path); err != nil {
+				t.Fatal(err)
+			}
+			sameIndex(t, loaded, want)
+		})
+	}
+}
+
+func TestSyncIndexBackupDuringWrites(t *testing.T) {
+	s := NewSyncIndex(NewIndex(Config{StoreDocuments: true}))
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for id := range 200 {
+			s.Add([]Document{{ID: id, Text: "glass plate"}})
+		}
+	}()
+	for {
+		var b bytes.Buffer
+		if err := s.Backup(&b); err != nil {
+			t.Fatal(err)
+		}
+		idx := NewIndex(Config{StoreDocuments: true})
+		if err := idx.RestoreSnapshot(&b); err != nil {
+			t.Fatalf("RestoreSnapshot() error = %v", err)
+		}
+		// A backup is of one moment, holding every document added by then.
+		if n, found := idx.DocCount(), len(idx.Search("glass")); n != found {
+			t.Fatalf("backup of %d documents finds %d", n, found)
+		}
+		for i, id := range idx.IDs {
+			if id != i {
+				t.Fatalf("backup has IDs %v, want 0 to %d", idx.IDs, len(idx.IDs)-1)
+			}
+		}
+		select {
+		case <-done:
+			return
+		default:
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package fulltextsearch
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Synonyms maps each lowercased word to the set of words it is equivalent
+// to, itself included, in a fixed order.
+type Synonyms map[string][]string
+
+// SynonymSets makes Synonyms from sets of equivalent words, lowercasing
+// them. Only single words are supported; entries that tokenize to more
+// than one are dropped. A word in more than one set belongs to the last.
+func SynonymSets(sets ...[]string) Synonyms {
+	r := make(Synonyms)
+	for _, set := range sets {
+		var words []string
+		for _, w := range set {
+			if t := tokenize(strings.ToLower(w)); len(t) == 1 {
+				words = append(words, t[0])
+			}
+		}
+		slices.Sort(words)
+		words = slices.Compact(words)
+		if len(words) < 2 {
+			continue
+		}
+		for _, w := range words {
+			r[w] = words
+		}
+	}
+	return r
+}
+
+// LoadSynonyms reads synonym sets from a file. A .json file holds an array
+// of sets, each an array of words. Any other file is text with one set per
+// line, its words separated by commas, as in
+//
+//	car, automobile, auto
+//
+// where anything after a # is a comment.
+func LoadSynonyms(path string) (Synonyms, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var sets [][]string
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		if err := json.Unmarshal(data, &sets); err != nil {
+			return nil, err
+		}
+		return SynonymSets(sets...), nil
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if i := strings.IndexByte(line, '#'); i >= 0 {
+			line = line[:i]
+		}
+		var set []string
+		for _, w := range strings.Split(line, ",") {
+			if w = strings.TrimSpace(w); w != "" {
+				set = append(set, w)
+			}
+		}
+		sets = append(sets, set)
+	}
+	return SynonymSets(sets...), nil
+}
+
+// synonymFilter replaces each token that has synonyms with its whole set.
+// Documents and queries expand alike, so any word of a set matches the
+// others, and a set always comes out in the same order so phrases still
+// line up.
+func synonymFilter(tokens []string, synonyms Synonyms) []string {
+	r := make([]string, 0, len(tokens))
+	for _, token := range tokens {
+		if set, ok := synonyms[token]; ok {
+			r = append(r, set...)
+			continue
+		}
+		r = append(r, token)
+	}
+	return r
+}
+
+// SynonymFilter expands tokens to their sets of synonyms.
+func SynonymFilter(synonyms Synonyms) TokenFilter {
+	return TokenFilterFunc(func(tokens []string) []string {
+		return synonymFilter(tokens, synonyms)
+	})
+}
@@ -0,0 +1,79 @@
+package fulltextsearch
+
+import (
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestSynonymSets(t *testing.T) {
+	got := SynonymSets([]string{"Car", "automobile", "car"}, []string{"lone"}, []string{"New York", "NYC"})
+	want := Synonyms{"automobile": {"automobile", "car"}, "car": {"automobile", "car"}}
+	if !maps.EqualFunc(got, want, slices.Equal) {
+		t.Errorf("SynonymSets() = %q, want %q", got, want)
+	}
+}
+
+func TestLoadSynonyms(t *testing.T) {
+	want := Synonyms{
+		"auto": {"auto", "automobile", "car"}, "automobile": {"auto", "automobile", "car"}, "car": {"auto", "automobile", "car"},
+		"big": {"big", "large"}, "large": {"big", "large"},
+	}
+	files := map[string]string{
+		"synonyms.txt":  "# vehicles\ncar, automobile, auto\n\nbig,large # sizes\n",
+		"synonyms.json": `[["car", "automobile", "auto"], ["big", "large"]]`,
+	}
+	dir := t.TempDir()
+	for name, content := range files {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(dir, name)
+			if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			got, err := LoadSynonyms(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !maps.EqualFunc(got, want, slices.Equal) {
+				t.Errorf("LoadSynonyms() = %q, want %q", got, want)
+			}
+		})
+	}
+
+	bad := filepath.Join(dir, "bad.json")
+	if err := os.WriteFile(bad, []byte(`{"car": "auto"}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadSynonyms(bad); err == nil {
+		t.Error("LoadSynonyms(bad JSON) succeeded, want an error")
+	}
+}
+
+func TestSynonymSearch(t *testing.T) {
+	idx := NewIndex(Config{Analyzer: Analyzer{Synonyms: SynonymSets([]string{"car", "automobile"})}})
+	idx.Add([]Document{
+		{ID: 0, Text: "a red car"},
+		{ID: 1, Text: "the automobile industry"},
+		{ID: 2, Text: "red cars and automobiles"},
+		{ID: 3, Text: "a red bicycle"},
+	})
+	tests := []struct {
+		query string
+		want  []int
+	}{
+		{"car", []int{0, 1, 2}},
+		{"Automobile", []int{0, 1, 2}},
+		{`"red car"`, []int{0}},
+		{`"red automobile"`, []int{0}},
+		{"red", []int{0, 2, 3}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			if got := idx.Search(tt.query); !slices.Equal(got, tt.want) {
+				t.Errorf("Search(%s) = %v, want %v", tt.query, got, tt.want)
+			}
+		})
+	}
+}
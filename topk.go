@@ -0,0 +1,89 @@
+package fulltextsearch
+
+import (
+	"container/heap"
+	"time"
+)
+
+// resultHeap is a min-heap of results, worst by ByScore on top, so the
+// weakest of the best results kept so far is the one to evict.
+type resultHeap []Result
+
+func (h resultHeap) Len() int           { return len(h) }
+func (h resultHeap) Less(i, j int) bool { return ByScore{}.Less(h[j], h[i]) }
+func (h resultHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *resultHeap) Push(x any)        { *h = append(*h, x.(Result)) }
+func (h *resultHeap) Pop() any {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
+
+// RankTop returns the k best scoring documents for query, best first. Only
+// k results are held at a time, so a query matching much of the index
+// doesn't have to collect and sort every match. With composite scoring,
+// which normalizes scores over all matches, it is no cheaper than Rank.
+func (idx *Index) RankTop(query string, k int) []Result {
+	r, _ := idx.rankTop(query, k)
+	return r
+}
+
+// rankTop is RankTop that also returns the total number of matches.
+func (idx *Index) rankTop(query string, k int) ([]Result, int) {
+	if k <= 0 {
+		return nil, 0
+	}
+	if idx.scoring != nil {
+		r := idx.Rank(query, nil)
+		return r[:min(k, len(r))], len(r)
+	}
+
+	start := time.Now()
+	ids, sc, ok := idx.prepareRank(query, idx.DefaultOperator)
+	if !ok {
+		idx.queries.record(query, 0, time.Since(start))
+		return nil, 0
+	}
+	h := make(resultHeap, 0, min(k, len(ids)))
+	for _, id := range ids {
+		res := sc.result(id)
+		if len(h) < k {
+			heap.Push(&h, res)
+		} else if (ByScore{}).Less(res, h[0]) {
+			h[0] = res
+			heap.Fix(&h, 0)
+		}
+	}
+	r := make([]Result, len(h))
+	for i := len(r) - 1; i >= 0; i-- {
+		r[i] = heap.Pop(&h).(Result)
+	}
+	idx.queries.record(query, len(ids), time.Since(start))
+	return r, len(ids)
+}
+
+// Page is one page of search results.
+type Page[T any] struct {
+	Items []T
+	// Total is the number of matches across all pages.
+	Total int
+}
+
+// RankPage returns size results starting at offset from in score order,
+// for paging through the results of query. Results past the page are never
+// sorted, but every earlier page's are, so deep pages cost more.
+func (idx *Index) RankPage(query string, from, size int) Page[Result] {
+	from = max(from, 0)
+	r, total := idx.rankTop(query, from+size)
+	return Page[Result]{Items: r[min(from, len(r)):], Total: total}
+}
+
+// SearchPage returns size of the IDs matching text starting at offset from,
+// in ID order.
+func (idx *Index) SearchPage(text string, from, size int) Page[int] {
+	ids := idx.Search(text)
+	from = min(max(from, 0), len(ids))
+	end := min(from+max(size, 0), len(ids))
+	return Page[int]{Items: ids[from:end], Total: len(ids)}
+}
@@ -0,0 +1,250 @@
+package fulltextsearch
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"slices"
+)
+
+// LoggedIndex is an index saved at a path whose updates are made crash safe
+// by a write-ahead log beside it. Every update is appended to the log and
+// synced before it is applied, so it is never lost once its method
+// returns, yet the index file itself is only rewritten when the log is
+// compacted into it, every CompactEvery updates. Opening the index replays
+// the log over the index file, picking up where a crashed process left off
+// rather than starting from zero.
+//
+// Like Index, a LoggedIndex is not safe for concurrent use.
+type LoggedIndex struct {
+	// CompactEvery is how many logged updates build up before they are
+	// compacted into the index file, defaulting to defaultCompactEvery.
+	CompactEvery int
+
+	idx  *Index
+	path string
+	log  walFile
+	// size is how many bytes of the log hold whole records. A failed
+	// append is cut back to it, so later records aren't stranded behind
+	// a torn one that replay would stop at.
+	size    int64
+	entries int
+}
+
+// walFile is the log file, an *os.File outside tests.
+type walFile interface {
+	io.ReadWriteSeeker
+	io.Closer
+	Stat() (os.FileInfo, error)
+	Truncate(size int64) error
+	Sync() error
+}
+
+// walRecord is one logged update: documents added or replaced, or a
+// document deleted.
+type walRecord struct {
+	Op   walOp
+	Docs []Document
+	ID   int
+}
+
+type walOp int
+
+const (
+	walPut walOp = iota
+	walDelete
+)
+
+const (
+	// walSuffix is appended to an index's path to name its log.
+	walSuffix = ".wal"
+	// walHeaderSize is the size of each record's length and checksum.
+	walHeaderSize = 8
+
+	defaultCompactEvery = 1000
+)
+
+// OpenLogged loads the index at path, if there is one, replays its log and
+// opens the log for further updates. A record at the end of the log that a
+// crash cut short is dropped, as its update never returned.
+func OpenLogged(path string, cfg Config) (*LoggedIndex, error) {
+	idx := NewIndex(cfg)
+	if err := idx.Load(path); err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	log, err := os.OpenFile(path+walSuffix, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+	l := &LoggedIndex{idx: idx, path: path, log: log}
+	if err := l.replay(); err != nil {
+		log.Close()
+		return nil, fmt.Errorf("replaying %s: %w", log.Name(), err)
+	}
+	return l, nil
+}
+
+// replay applies the logged records, truncating the log after the last
+// whole one and leaving it positioned there for appending.
+func (l *LoggedIndex) replay() error {
+	info, err := l.log.Stat()
+	if err != nil {
+		return err
+	}
+	r := bufio.NewReader(l.log)
+	var end int64
+	for {
+		rec, n, err := readRecord(r, info.Size()-end)
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return err
+		}
+		if n == 0 {
+			// Cut short, or garbled by a crash mid-write.
+			break
+		}
+		l.idx.apply(rec)
+		l.entries++
+		end += n
+	}
+	l.size = end
+	return l.rewind()
+}
+
+// rewind truncates the log after its last whole record and positions it
+// there for appending.
+func (l *LoggedIndex) rewind() error {
+	if err := l.log.Truncate(l.size); err != nil {
+		return err
+	}
+	_, err := l.log.Seek(l.size, io.SeekStart)
+	return err
+}
+
+// readRecord reads the next record and its size on disk, with left bytes
+// left in the log. A size of zero means the rest of the log isn't a whole,
+// intact record.
+func readRecord(r *bufio.Reader, left int64) (walRecord, int64, error) {
+	var rec walRecord
+	var header [walHeaderSize]byte
+	if _, err := io.ReadFull(r, header[:]); err == io.EOF {
+		return rec, 0, io.EOF
+	} else if err == io.ErrUnexpectedEOF {
+		return rec, 0, nil
+	} else if err != nil {
+		return rec, 0, err
+	}
+	size := binary.LittleEndian.Uint32(header[:])
+	if int64(size) > left-walHeaderSize {
+		return rec, 0, nil
+	}
+	data := make([]byte, size)
+	if _, err := io.ReadFull(r, data); err == io.EOF || err == io.ErrUnexpectedEOF {
+		return rec, 0, nil
+	} else if err != nil {
+		return rec, 0, err
+	}
+	if crc32.Checksum(data, crcTable) != binary.LittleEndian.Uint32(header[4:]) {
+		return rec, 0, nil
+	}
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&rec); err != nil {
+		return rec, 0, nil
+	}
+	return rec, int64(walHeaderSize + len(data)), nil
+}
+
+// apply makes the update rec records. Added documents replace any with the
+// same ID, so replaying a record the index file already reflects, as after
+// a crash between saving it and emptying the log, changes nothing.
+func (idx *Index) apply(rec walRecord) {
+	switch rec.Op {
+	case walPut:
+		idx.Add(rec.Docs)
+	case walDelete:
+		idx.Delete(rec.ID)
+	}
+}
+
+// Index returns the index, for searching. It must not be modified other
+// than through l.
+func (l *LoggedIndex) Index() *Index {
+	return l.idx
+}
+
+// Add adds docs, replacing any documents with the same IDs.
+func (l *LoggedIndex) Add(docs []Document) error {
+	return l.do(walRecord{Op: walPut, Docs: docs})
+}
+
+// Update replaces the document with doc's ID by doc, failing if there is no
+// such document.
+func (l *LoggedIndex) Update(doc Document) error {
+	if _, ok := slices.BinarySearch(l.idx.IDs, doc.ID); !ok {
+		return fmt.Errorf("document %d: %w", doc.ID, ErrUnknownID)
+	}
+	return l.do(walRecord{Op: walPut, Docs: []Document{doc}})
+}
+
+func (l *LoggedIndex) Delete(id int) error {
+	return l.do(walRecord{Op: walDelete, ID: id})
+}
+
+// do logs rec, applies it and compacts the log if it has grown long enough.
+func (l *LoggedIndex) do(rec walRecord) error {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(rec); err != nil {
+		return err
+	}
+	header := binary.LittleEndian.AppendUint32(nil, uint32(buf.Len()))
+	header = binary.LittleEndian.AppendUint32(header, crc32.Checksum(buf.Bytes(), crcTable))
+	n, err := l.log.Write(append(header, buf.Bytes()...))
+	if err == nil {
+		err = l.log.Sync()
+	}
+	if err != nil {
+		if rerr := l.rewind(); rerr != nil {
+			return fmt.Errorf("%w; truncating log: %w", err, rerr)
+		}
+		return err
+	}
+	l.size += int64(n)
+	l.idx.apply(rec)
+	l.entries++
+
+	every := l.CompactEvery
+	if every <= 0 {
+		every = defaultCompactEvery
+	}
+	if l.entries < every {
+		return nil
+	}
+	return l.Compact()
+}
+
+// Compact saves the index and empties the log, whose updates it now holds.
+func (l *LoggedIndex) Compact() error {
+	if err := l.idx.Save(l.path); err != nil {
+		return err
+	}
+	l.size = 0
+	if err := l.rewind(); err != nil {
+		return err
+	}
+	l.entries = 0
+	return l.log.Sync()
+}
+
+// Close compacts the log into the index file and closes it.
+func (l *LoggedIndex) Close() error {
+	err := l.Compact()
+	if cerr := l.log.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
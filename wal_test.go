@@ -0,0 +1,59 @@
+package fulltextsearch
+
+import (
+	"errors"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// tornFile fails its first write after writing half of it, as a full disk
+// or a crash might.
+type tornFile struct {
+	walFile
+	torn bool
+}
+
+var errTorn = errors.New("disk full")
+
+func (f *tornFile) Write(p []byte) (int, error) {
+	if f.torn {
+		return f.walFile.Write(p)
+	}
+	f.torn = true
+	n, _ := f.walFile.Write(p[:len(p)/2])
+	return n, errTorn
+}
+
+func TestLoggedIndexTornWrite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "idx")
+	l, err := OpenLogged(path, Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.log.Close()
+	l.CompactEvery = 100
+	if err := l.Add([]Document{{ID: 0, Text: "donut"}}); err != nil {
+		t.Fatal(err)
+	}
+	l.log = &tornFile{walFile: l.log}
+	if err := l.Add([]Document{{ID: 1, Text: "donut"}}); !errors.Is(err, errTorn) {
+		t.Fatalf("Add() = %v, want %v", err, errTorn)
+	}
+	if err := l.Add([]Document{{ID: 2, Text: "donut"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Replay the log without compacting it, as after a crash.
+	reopened, err := OpenLogged(path, Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reopened.Close()
+	if got, want := reopened.Index().Search("donut"), []int{0, 2}; !slices.Equal(got, want) {
+		t.Errorf("Search() after replay = %v, want %v", got, want)
+	}
+	if got, want := l.Index().Search("donut"), []int{0, 2}; !slices.Equal(got, want) {
+		t.Errorf("Search() = %v, want %v", got, want)
+	}
+}
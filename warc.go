@@ -0,0 +1,177 @@
+package fulltextsearch
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha1"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/textproto"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/net/html/charset"
+)
+
+// LoadWARC reads the web pages captured in the WARC file at path as
+// documents; see StreamWARC.
+func LoadWARC(path string) ([]Document, error) {
+	var docs []Document
+	err := StreamWARC(path, func(doc Document) error {
+		docs = append(docs, doc)
+		return nil
+	})
+	return docs, err
+}
+
+// StreamWARC reads the WARC file at path, such as a crawler writes, like
+// StreamWARCReader.
+func StreamWARC(path string, fn func(Document) error) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := StreamWARCReader(f, fn); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
+
+// StreamWARCReader reads a web archive from r, calling fn with each
+// successful capture of an HTML or plain text page as a document, numbered
+// in order: the target URI is the URL and the capture time the date, and
+// HTML is read with ParseHTML, in the charset the response declares. Pages
+// without a title are titled by their URL. Other records, such as requests
+// and metadata, and other responses are skipped. Archives gzipped whole or
+// record by record, as .warc.gz files are, are detected and decompressed.
+// It stops at the first error fn returns and returns it.
+func StreamWARCReader(r io.Reader, fn func(Document) error) error {
+	br := bufio.NewReader(r)
+	if magic, _ := br.Peek(len(gzipMagic)); string(magic) == gzipMagic {
+		// gzip.Reader reads on through the members of a per-record file
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return err
+		}
+		defer gz.Close()
+		br = bufio.NewReader(gz)
+	}
+	id := 0
+	for n := 0; ; n++ {
+		header, block, err := readWARCRecord(br)
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("record %d: %w", n, err)
+		}
+		if header.Get("WARC-Type") != "response" {
+			continue
+		}
+		doc, ok, err := warcDocument(header, block)
+		if err != nil {
+			return fmt.Errorf("record %d: %w", n, err)
+		}
+		if !ok {
+			continue
+		}
+		doc.ID = id
+		id++
+		if err := fn(doc); err != nil {
+			return err
+		}
+	}
+}
+
+// readWARCRecord reads the next record of a web archive: its version line
+// and named fields, and its content block.
+func readWARCRecord(br *bufio.Reader) (textproto.MIMEHeader, []byte, error) {
+	// records are separated by two CRLFs, which are skipped along with any
+	// other blank lines
+	var version string
+	for version == "" {
+		line, err := br.ReadString('\n')
+		if version = strings.TrimSpace(line); version == "" && err != nil {
+			return nil, nil, err
+		}
+	}
+	if !strings.HasPrefix(version, "WARC/") {
+		return nil, nil, fmt.Errorf("not a WARC record: %q", version)
+	}
+	header, err := textproto.NewReader(br).ReadMIMEHeader()
+	if err == io.EOF {
+		// the record was cut off in its header, which isn't the end of
+		// the archive the caller takes io.EOF for
+		return nil, nil, io.ErrUnexpectedEOF
+	} else if err != nil {
+		return nil, nil, err
+	}
+	length, err := strconv.ParseInt(header.Get("Content-Length"), 10, 64)
+	if err != nil || length < 0 {
+		return nil, nil, fmt.Errorf("invalid Content-Length %q", header.Get("Content-Length"))
+	}
+	block := make([]byte, length)
+	if _, err := io.ReadFull(br, block); err != nil {
+		return nil, nil, err
+	}
+	return header, block, nil
+}
+
+// warcDocument reads the HTTP response captured in a response record,
+// reporting whether it is a page worth indexing.
+func warcDocument(header textproto.MIMEHeader, block []byte) (Document, bool, error) {
+	resp, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(block)), nil)
+	if err != nil {
+		// e.g. a dns: record, which holds no HTTP response
+		return Document{}, false, nil
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return Document{}, false, nil
+	}
+	contentType := resp.Header.Get("Content-Type")
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	if mediaType != "text/html" && mediaType != "application/xhtml+xml" && mediaType != "text/plain" {
+		return Document{}, false, nil
+	}
+
+	var body io.Reader = resp.Body
+	if strings.EqualFold(resp.Header.Get("Content-Encoding"), "gzip") {
+		gz, err := gzip.NewReader(body)
+		if err != nil {
+			return Document{}, false, err
+		}
+		defer gz.Close()
+		body = gz
+	}
+	if body, err = charset.NewReader(body, contentType); err != nil {
+		return Document{}, false, err
+	}
+
+	var doc Document
+	if mediaType == "text/plain" {
+		text, err := io.ReadAll(body)
+		if err != nil {
+			return Document{}, false, err
+		}
+		doc.Text = strings.TrimSpace(string(text))
+	} else if doc, err = ParseHTML(body); err != nil {
+		return Document{}, false, err
+	}
+	// WARC 1.0 puts the URI in angle brackets
+	doc.URL = strings.Trim(header.Get("WARC-Target-URI"), "<>")
+	if doc.Title == "" {
+		doc.Title = doc.URL
+	}
+	if doc.URL != "" {
+		sum := sha1.Sum([]byte(doc.URL))
+		doc.URLSHA1 = sum[:]
+	}
+	doc.Date, _ = time.Parse(time.RFC3339, header.Get("WARC-Date"))
+	return doc, true, nil
+}
@@ -0,0 +1,117 @@
+package fulltextsearch
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+// warcRecord returns a WARC record of type typ capturing uri, with block
+// as its content.
+func warcRecord(typ, uri, block string) string {
+	return fmt.Sprintf("WARC/1.1\r\nWARC-Type: %s\r\nWARC-Target-URI: %s\r\nWARC-Date: 2024-03-01T10:00:00Z\r\nContent-Length: %d\r\n\r\n%s\r\n\r\n",
+		typ, uri, len(block), block)
+}
+
+// httpResponse returns an HTTP response with the given status, content
+// type and body, as a response record holds it.
+func httpResponse(status, contentType, body string) string {
+	return fmt.Sprintf("HTTP/1.1 %s\r\nContent-Type: %s\r\nContent-Length: %d\r\n\r\n%s", status, contentType, len(body), body)
+}
+
+var testWARC = []string{
+	warcRecord("warcinfo", "", "software: test\r\n"),
+	warcRecord("request", "https://example.com/donuts", "GET /donuts HTTP/1.1\r\n\r\n"),
+	warcRecord("response", "https://example.com/donuts",
+		httpResponse("200 OK", "text/html; charset=iso-8859-1", "<title>Donuts</title><p>Caf\xe9 donuts</p>")),
+	warcRecord("response", "<https://example.com/missing>", httpResponse("404 Not Found", "text/html", "<p>Not found</p>")),
+	warcRecord("response", "https://example.com/logo.png", httpResponse("200 OK", "image/png", "\x89PNG")),
+	warcRecord("response", "<https://example.com/bagels.txt>", httpResponse("200 OK", "text/plain", "  Toasted bagels\n")),
+	warcRecord("response", "dns:example.com", "20240301100000\r\nexample.com. 300 IN A 192.0.2.1\r\n"),
+}
+
+func TestStreamWARC(t *testing.T) {
+	plain := strings.Join(testWARC, "")
+	var whole, perRecord bytes.Buffer
+	w := gzip.NewWriter(&whole)
+	io.WriteString(w, plain)
+	w.Close()
+	for _, rec := range testWARC {
+		w := gzip.NewWriter(&perRecord)
+		io.WriteString(w, rec)
+		w.Close()
+	}
+	tests := []struct {
+		name  string
+		input []byte
+	}{
+		{"plain", []byte(plain)},
+		{"gzipped whole", whole.Bytes()},
+		{"gzipped per record", perRecord.Bytes()},
+	}
+	want := []Document{
+		{ID: 0, Title: "Donuts", URL: "https://example.com/donuts", Text: "Café donuts"},
+		{ID: 1, Title: "https://example.com/bagels.txt", URL: "https://example.com/bagels.txt", Text: "Toasted bagels"},
+	}
+	date := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var docs []Document
+			err := StreamWARCReader(bytes.NewReader(tt.input), func(doc Document) error {
+				docs = append(docs, doc)
+				return nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(docs) != len(want) {
+				t.Fatalf("StreamWARCReader() = %d documents, want %d", len(docs), len(want))
+			}
+			for i, doc := range docs {
+				w := want[i]
+				if doc.ID != w.ID || doc.Title != w.Title || doc.URL != w.URL || doc.Text != w.Text || !doc.Date.Equal(date) {
+					t.Errorf("document %d = {%d %q %q %q %v}, want {%d %q %q %q %v}",
+						i, doc.ID, doc.Title, doc.URL, doc.Text, doc.Date, w.ID, w.Title, w.URL, w.Text, date)
+				}
+			}
+		})
+	}
+}
+
+func TestStreamWARCMalformed(t *testing.T) {
+	page := warcRecord("response", "https://example.com/donuts", httpResponse("200 OK", "text/plain", "Donuts"))
+	tests := []struct {
+		name  string
+		input string
+		// docs is how many documents are read before the error.
+		docs int
+		want error
+	}{
+		{"truncated record", page + page[:len(page)-20], 1, io.ErrUnexpectedEOF},
+		{"truncated header", page + "WARC/1.1\r\nWARC-Type: resp", 1, io.ErrUnexpectedEOF},
+		{"not a WARC record", page + "HTTP/1.1 200 OK\r\n\r\n", 1, nil},
+		{"invalid length", strings.Replace(page, "Content-Length: ", "Content-Length: -", 1), 0, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var docs int
+			err := StreamWARCReader(strings.NewReader(tt.input), func(Document) error {
+				docs++
+				return nil
+			})
+			if err == nil {
+				t.Error("StreamWARCReader() error = nil, want an error")
+			} else if tt.want != nil && !errors.Is(err, tt.want) {
+				t.Errorf("StreamWARCReader() error = %v, want %v", err, tt.want)
+			}
+			if docs != tt.docs {
+				t.Errorf("StreamWARCReader() read %d documents before failing, want %d", docs, tt.docs)
+			}
+		})
+	}
+}